@@ -0,0 +1,214 @@
+package emails
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// Error returns the status code and message of the provider.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API: %d %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Temporary returns true if the email may be accepted later: the provider is rate limiting or failing.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// apiMessage is an Email mapped to the fields of the HTTP APIs.
+type apiMessage struct {
+	from        *mail.Address
+	to, cc, bcc []*mail.Address
+	replyTo     []*mail.Address
+	subject     string
+	text, html  string
+	headers     []apiHeader // headers without a field of their own
+	attachments []apiAttachment
+}
+
+// apiHeader is a custom header of an apiMessage.
+type apiHeader struct {
+	name, value string
+}
+
+// apiAttachment is an attachment of an apiMessage.
+type apiAttachment struct {
+	filename    string
+	contentType string
+	contentID   string // without angle brackets
+	inline      bool   // referenced by the HTML body with cid:<contentID>
+	content     []byte
+}
+
+// apiHeadersFields are the headers mapped to dedicated fields by the HTTP APIs, or set by the providers.
+var apiHeadersFields = []string{
+	"From", "To", "Cc", "Bcc", "Reply-To", "Subject", "Date", "Message-Id", "Mime-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// apiMessage maps the email sent by emailAddress to the fields of the HTTP APIs.
+func (e *Email) apiMessage(emailAddress EmailAddress) (*apiMessage, error) {
+	if _, err := e.recipients(); err != nil {
+		return nil, err
+	}
+	if emailAddress.address == "" {
+		return nil, ErrSenderMustBeSpecified
+	}
+
+	var message = apiMessage{
+		from:    &mail.Address{Name: emailAddress.userName, Address: emailAddress.address},
+		subject: e.Subject,
+		text:    string(e.Text),
+		html:    string(e.HTML),
+	}
+	var err error
+	for _, list := range []struct {
+		addresses []string
+		parsed    *[]*mail.Address
+	}{{e.To, &message.to}, {e.Cc, &message.cc}, {e.Bcc, &message.bcc}, {e.ReplyTo, &message.replyTo}} {
+		if len(list.addresses) == 0 {
+			continue
+		}
+		if *list.parsed, err = mail.ParseAddressList(strings.Join(list.addresses, ", ")); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, values := range e.Headers {
+		if containsString(apiHeadersFields, textproto.CanonicalMIMEHeaderKey(name)) || len(values) == 0 {
+			continue
+		}
+		message.headers = append(message.headers, apiHeader{name: textproto.CanonicalMIMEHeaderKey(name), value: values[0]})
+	}
+	// Map iteration order is random, requests are kept deterministic.
+	sort.Slice(message.headers, func(i, j int) bool { return message.headers[i].name < message.headers[j].name })
+
+	for _, a := range e.Attachments {
+		var attachment = apiAttachment{filename: a.Filename, contentType: a.ContentType, inline: a.HTMLRelated, content: a.Content}
+		if attachment.contentType == "" {
+			attachment.contentType = "application/octet-stream"
+		}
+		if a.HTMLRelated {
+			// The default Content-ID of ToBytes.
+			attachment.contentID = a.Filename
+			if a.Header != nil && a.Header.Get("Content-ID") != "" {
+				attachment.contentID = strings.Trim(a.Header.Get("Content-ID"), "<>")
+			}
+		}
+		message.attachments = append(message.attachments, attachment)
+	}
+	return &message, nil
+}
+
+// recipients returns the addresses of every recipient of the message.
+func (m *apiMessage) recipients() []string {
+	var recipients []string
+	for _, list := range [][]*mail.Address{m.to, m.cc, m.bcc} {
+		for _, address := range list {
+			recipients = append(recipients, address.Address)
+		}
+	}
+	return recipients
+}
+
+// apiResult returns the SendResult of a message accepted or refused by an HTTP API, the APIs accept or refuse all the recipients.
+func apiResult(message *apiMessage, messageID string, statusCode int, err error) *SendResult {
+	var result = SendResult{MessageID: messageID}
+	for _, recipient := range message.recipients() {
+		result.Recipients = append(result.Recipients, RecipientResult{Address: recipient, Accepted: err == nil, Code: statusCode, Err: err})
+	}
+	return &result
+}
+
+// formatAddresses returns the addresses as a header value.
+func formatAddresses(addresses []*mail.Address) string {
+	var formatted = make([]string, len(addresses))
+	for i, address := range addresses {
+		formatted[i] = address.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// quoteEscaper escapes the quoted strings of headers, like mime/multipart.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes escapes a header quoted string.
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// httpClient returns the client of the options.
+func (o *HTTPSenderOptions) httpClient() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return http.DefaultClient
+}
+
+// endpoint returns the base URL of the API.
+func (o *HTTPSenderOptions) endpoint(defaultEndpoint string) string {
+	if o.Endpoint != "" {
+		return strings.TrimSuffix(o.Endpoint, "/")
+	}
+	return defaultEndpoint
+}
+
+// doAPIRequest sends a request to the API of provider and decodes its JSON response into out.
+// It returns the status code and headers of the response, and an *APIError if the provider refused the request.
+func doAPIRequest(ctx context.Context, client *http.Client, req *http.Request, provider string, out interface{}) (int, http.Header, error) {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s API: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, resp.Header, fmt.Errorf("%s API: %w", provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, resp.Header, &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: apiErrorMessage(body)}
+	}
+	if out != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil {
+			return resp.StatusCode, resp.Header, fmt.Errorf("%s API: invalid response: %w", provider, err)
+		}
+	}
+	return resp.StatusCode, resp.Header, nil
+}
+
+// apiErrorMessage returns the error message of an API response body.
+func apiErrorMessage(body []byte) string {
+	// Field names are matched case insensitively: "message" (Mailgun, SES), "Message" (Postmark) or "errors" (SendGrid).
+	var response struct {
+		Message string
+		Errors  []struct {
+			Field   string
+			Message string
+		}
+	}
+	if err := json.Unmarshal(body, &response); err == nil {
+		var messages []string
+		if response.Message != "" {
+			messages = append(messages, response.Message)
+		}
+		for _, e := range response.Errors {
+			if e.Field != "" {
+				messages = append(messages, e.Field+": "+e.Message)
+			} else {
+				messages = append(messages, e.Message)
+			}
+		}
+		if len(messages) > 0 {
+			return strings.Join(messages, "; ")
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
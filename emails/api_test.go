@@ -0,0 +1,260 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordedRequest is a request received by an httptest stand-in of a provider.
+type recordedRequest struct {
+	method, path string
+	header       http.Header
+	body         []byte
+}
+
+// newAPIServer starts a stand-in of a provider API answering every request with status and body.
+func newAPIServer(t *testing.T, status int, header http.Header, body string) (*httptest.Server, *recordedRequest) {
+	t.Helper()
+	var recorded recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		recorded = recordedRequest{method: r.Method, path: r.URL.Path, header: r.Header, body: data}
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &recorded
+}
+
+// newTestAPIEmail returns an email using every field mapped by the HTTP API senders.
+func newTestAPIEmail() *Email {
+	email := newTestEmail("Sam <sam@example.com>", "alex@example.com")
+	email.Cc = []string{"cc@example.com"}
+	email.Bcc = []string{"bcc@example.com"}
+	email.ReplyTo = []string{"support@example.com"}
+	email.HTML = []byte(`<p>Hello!</p><img src="cid:logo.png">`)
+	email.Headers.Set("X-Campaign", "approvals")
+	email.Attach(strings.NewReader("report"), "report.txt", "text/plain")
+	logo, _ := email.Attach(bytes.NewReader([]byte{0x89, 'P', 'N', 'G'}), "logo.png", "image/png")
+	logo.HTMLRelated = true
+	return email
+}
+
+var testAPIFrom = NewEmailAddress("noreply@example.com", "Tested4you", "", "", 0)
+
+// checkAcceptedResult checks the result of an email accepted by an API.
+func checkAcceptedResult(t *testing.T, result *SendResult, err error, messageID string) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MessageID != messageID {
+		t.Errorf("got message ID %q, want %q", result.MessageID, messageID)
+	}
+	if len(result.Recipients) != 4 || len(result.Failed()) != 0 {
+		t.Errorf("expected 4 accepted recipients, got %+v", result.Recipients)
+	}
+}
+
+func TestSendGridSender(t *testing.T) {
+	server, recorded := newAPIServer(t, http.StatusAccepted, http.Header{"X-Message-Id": {"sg-id"}}, "")
+	sender := NewSendGridSender(testAPIFrom, "sg-key", HTTPSenderOptions{Endpoint: server.URL})
+
+	result, err := sender.Send(context.Background(), newTestAPIEmail())
+	checkAcceptedResult(t, result, err, "sg-id")
+	if recorded.path != "/v3/mail/send" || recorded.header.Get("Authorization") != "Bearer sg-key" {
+		t.Errorf("unexpected request %s %v", recorded.path, recorded.header)
+	}
+
+	var request sendGridMessage
+	if err := json.Unmarshal(recorded.body, &request); err != nil {
+		t.Fatal(err)
+	}
+	personalization := request.Personalizations[0]
+	if len(personalization.To) != 2 || personalization.To[0] != (sendGridAddress{Email: "sam@example.com", Name: "Sam"}) ||
+		personalization.Cc[0].Email != "cc@example.com" || personalization.Bcc[0].Email != "bcc@example.com" {
+		t.Errorf("unexpected personalization %+v", personalization)
+	}
+	if request.From != (sendGridAddress{Email: "noreply@example.com", Name: "Tested4you"}) || request.ReplyTo.Email != "support@example.com" {
+		t.Errorf("unexpected from %+v and reply to %+v", request.From, request.ReplyTo)
+	}
+	if len(request.Content) != 2 || request.Content[0].Type != "text/plain" || request.Content[1].Value != `<p>Hello!</p><img src="cid:logo.png">` {
+		t.Errorf("unexpected content %+v", request.Content)
+	}
+	if request.Headers["X-Campaign"] != "approvals" {
+		t.Errorf("unexpected headers %v", request.Headers)
+	}
+	expected := []sendGridAttachment{
+		{Content: base64.StdEncoding.EncodeToString([]byte("report")), Type: "text/plain", Filename: "report.txt", Disposition: "attachment"},
+		{Content: "iVBORw==", Type: "image/png", Filename: "logo.png", Disposition: "inline", ContentID: "logo.png"},
+	}
+	if len(request.Attachments) != 2 || request.Attachments[0] != expected[0] || request.Attachments[1] != expected[1] {
+		t.Errorf("got attachments %+v, want %+v", request.Attachments, expected)
+	}
+}
+
+func TestSendGridSenderError(t *testing.T) {
+	server, _ := newAPIServer(t, http.StatusBadRequest, nil, `{"errors":[{"message":"Does not contain a valid address.","field":"from.email"}]}`)
+	sender := NewSendGridSender(testAPIFrom, "sg-key", HTTPSenderOptions{Endpoint: server.URL})
+
+	result, err := sender.Send(context.Background(), newTestAPIEmail())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 400 || apiErr.Message != "from.email: Does not contain a valid address." || apiErr.Temporary() {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(result.Failed()) != 4 || result.Recipients[0].Code != 400 {
+		t.Errorf("expected 4 rejected recipients, got %+v", result.Recipients)
+	}
+}
+
+func TestSendGridSenderBccOnly(t *testing.T) {
+	server, recorded := newAPIServer(t, http.StatusAccepted, nil, "")
+	sender := NewSendGridSender(testAPIFrom, "sg-key", HTTPSenderOptions{Endpoint: server.URL})
+
+	email := newTestEmail()
+	email.Bcc = []string{"bcc@example.com"}
+	result, err := sender.Send(context.Background(), email)
+	if !errors.Is(err, ErrToMustBeSpecified) || result != nil {
+		t.Errorf("expected ErrToMustBeSpecified, got %+v, %v", result, err)
+	}
+	if recorded.method != "" {
+		t.Errorf("expected no request, got %s %s", recorded.method, recorded.path)
+	}
+}
+
+func TestMailgunSender(t *testing.T) {
+	server, recorded := newAPIServer(t, http.StatusOK, nil, `{"id":"<mg-id@example.com>","message":"Queued. Thank you."}`)
+	sender := NewMailgunSender(testAPIFrom, "mg.example.com", "mg-key", HTTPSenderOptions{Endpoint: server.URL})
+
+	result, err := sender.Send(context.Background(), newTestAPIEmail())
+	checkAcceptedResult(t, result, err, "<mg-id@example.com>")
+	if recorded.path != "/v3/mg.example.com/messages" {
+		t.Errorf("unexpected path %s", recorded.path)
+	}
+	req := http.Request{Header: recorded.header}
+	if username, password, _ := req.BasicAuth(); username != "api" || password != "mg-key" {
+		t.Errorf("unexpected credentials %s:%s", username, password)
+	}
+
+	_, params, err := mime.ParseMediaType(recorded.header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	form, err := multipart.NewReader(bytes.NewReader(recorded.body), params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for field, value := range map[string]string{
+		"from":         `"Tested4you" <noreply@example.com>`,
+		"to":           `"Sam" <sam@example.com>, <alex@example.com>`,
+		"cc":           "<cc@example.com>",
+		"bcc":          "<bcc@example.com>",
+		"subject":      "Your video was approved",
+		"text":         "Hello!",
+		"h:Reply-To":   "<support@example.com>",
+		"h:X-Campaign": "approvals",
+	} {
+		if len(form.Value[field]) != 1 || form.Value[field][0] != value {
+			t.Errorf("got %s %q, want %q", field, form.Value[field], value)
+		}
+	}
+	if len(form.File["attachment"]) != 1 || form.File["attachment"][0].Filename != "report.txt" ||
+		len(form.File["inline"]) != 1 || form.File["inline"][0].Filename != "logo.png" {
+		t.Errorf("unexpected files %v", form.File)
+	}
+}
+
+func TestPostmarkSender(t *testing.T) {
+	server, recorded := newAPIServer(t, http.StatusOK, nil, `{"ErrorCode":0,"Message":"OK","MessageID":"pm-id"}`)
+	sender := NewPostmarkSender(testAPIFrom, "pm-token", "", HTTPSenderOptions{Endpoint: server.URL})
+
+	result, err := sender.Send(context.Background(), newTestAPIEmail())
+	checkAcceptedResult(t, result, err, "pm-id")
+	if recorded.path != "/email" || recorded.header.Get("X-Postmark-Server-Token") != "pm-token" {
+		t.Errorf("unexpected request %s %v", recorded.path, recorded.header)
+	}
+
+	var request postmarkMessage
+	if err := json.Unmarshal(recorded.body, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.To != `"Sam" <sam@example.com>, <alex@example.com>` || request.Bcc != "<bcc@example.com>" || request.ReplyTo != "<support@example.com>" ||
+		request.TextBody != "Hello!" || len(request.Headers) != 1 || request.Headers[0] != (postmarkHeader{Name: "X-Campaign", Value: "approvals"}) {
+		t.Errorf("unexpected request %+v", request)
+	}
+	if len(request.Attachments) != 2 || request.Attachments[1].ContentID != "cid:logo.png" || request.Attachments[0].ContentID != "" {
+		t.Errorf("unexpected attachments %+v", request.Attachments)
+	}
+}
+
+func TestPostmarkSenderError(t *testing.T) {
+	server, _ := newAPIServer(t, http.StatusUnprocessableEntity, nil, `{"ErrorCode":406,"Message":"You tried to send to a recipient that has been marked as inactive."}`)
+	sender := NewPostmarkSender(testAPIFrom, "pm-token", "", HTTPSenderOptions{Endpoint: server.URL})
+
+	_, err := sender.Send(context.Background(), newTestAPIEmail())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 422 || !strings.Contains(apiErr.Message, "inactive") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestSESSender(t *testing.T) {
+	server, recorded := newAPIServer(t, http.StatusOK, nil, `{"MessageId":"ses-id"}`)
+	sender := NewSESSender(testAPIFrom, "eu-west-1", SESCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, HTTPSenderOptions{Endpoint: server.URL})
+
+	result, err := sender.Send(context.Background(), newTestAPIEmail())
+	checkAcceptedResult(t, result, err, "ses-id")
+	if recorded.path != "/v2/email/outbound-emails" {
+		t.Errorf("unexpected path %s", recorded.path)
+	}
+	if authorization := recorded.header.Get("Authorization"); !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		!strings.Contains(authorization, "/eu-west-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=") {
+		t.Errorf("unexpected authorization %q", authorization)
+	}
+
+	var request sesMessage
+	if err := json.Unmarshal(recorded.body, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.Destination.ToAddresses) != 2 || request.Destination.BccAddresses[0] != "<bcc@example.com>" {
+		t.Errorf("unexpected destination %+v", request.Destination)
+	}
+	raw := string(request.Content.Raw.Data)
+	for _, expected := range []string{"X-Campaign: approvals", "Reply-To: support@example.com", `filename="report.txt"`, "Content-Id: <logo.png>"} {
+		if !strings.Contains(raw, expected) {
+			t.Errorf("expected %q in the raw message", expected)
+		}
+	}
+	if strings.Contains(raw, "bcc@example.com") {
+		t.Errorf("the raw message must not contain the Bcc recipients")
+	}
+}
+
+// TestSignAWSRequest checks the signature of the example of the AWS Signature Version 4 documentation.
+func TestSignAWSRequest(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	credentials := SESCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWSRequest(req, nil, "us-east-1", "iam", credentials, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if authorization := req.Header.Get("Authorization"); authorization != expected {
+		t.Errorf("got %q, want %q", authorization, expected)
+	}
+}
@@ -0,0 +1,78 @@
+package emails
+
+import (
+	"errors"
+	"net/smtp"
+	"strings"
+)
+
+// isLocalhost returns true if the SMTP server runs on the local machine, credentials may be sent without TLS.
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// loginAuth implements the LOGIN mechanism, still required by some Microsoft servers.
+type loginAuth struct {
+	username, password, host string
+}
+
+// LoginAuth returns an smtp.Auth implementing the LOGIN mechanism.
+// Like smtp.PlainAuth, it refuses to send the credentials over an unencrypted connection, except on localhost.
+func LoginAuth(username, password, host string) smtp.Auth {
+	return &loginAuth{username: username, password: password, host: host}
+}
+
+// Start begins the authentication.
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return string(AuthLogin), nil, nil
+}
+
+// Next answers the username and password prompts of the server.
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:", "user name":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, errors.New("unexpected LOGIN prompt " + string(fromServer))
+}
+
+// xoauth2Auth implements the XOAUTH2 mechanism of Gmail and Outlook.
+type xoauth2Auth struct {
+	username, token, host string
+}
+
+// XOAuth2Auth returns an smtp.Auth implementing the XOAUTH2 mechanism with an OAuth2 access token.
+// It refuses to send the token over an unencrypted connection, except on localhost.
+func XOAuth2Auth(username, token, host string) smtp.Auth {
+	return &xoauth2Auth{username: username, token: token, host: host}
+}
+
+// Start sends the access token.
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return string(AuthXOAUTH2), []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+// Next acknowledges the error details sent by the server when the token is rejected, the server then fails the authentication.
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
+}
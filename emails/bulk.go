@@ -0,0 +1,251 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/textproto"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/sabriboughanmi/go_utils/utils"
+)
+
+// BulkMailer sends an Email to many recipients through a Sender, personalized for each of them.
+// Every recipient receives its own email. The emails are sent by a pool of workers, rate limited by a utils.RateLimiter,
+// and the emails temporarily refused, by a 4xx SMTP reply or a temporary APIError, are sent again after a backoff.
+type BulkMailer struct {
+	sender  Sender
+	options BulkOptions
+}
+
+// bulkComposer personalizes the copy of an email sent to a recipient.
+type bulkComposer func(email *Email, recipient BulkRecipient) error
+
+// NewBulkMailer returns a BulkMailer sending the emails through sender.
+func NewBulkMailer(sender Sender, options BulkOptions) *BulkMailer {
+	if options.Workers <= 0 {
+		options.Workers = 4
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 3
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = 30 * time.Second
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = 10 * time.Minute
+	}
+	return &BulkMailer{sender: sender, options: options}
+}
+
+// Send sends email to every recipient, the recipient replaces the To, Cc and Bcc of email.
+// The Subject and Text of email are text/template templates and its HTML an html/template template, executed with the
+// Vars of the recipient, e.g. "Hello {{.FirstName}}". A variable missing from Vars fails the email of the recipient.
+//
+// An error is returned if the templates can't be parsed, the recipients the email was not delivered to are reported in
+// the BulkReport. If ctx is done, the remaining recipients are reported with the error of ctx.
+func (m *BulkMailer) Send(ctx context.Context, email *Email, recipients []BulkRecipient) (*BulkReport, error) {
+	var subject, text *texttemplate.Template
+	var html *template.Template
+	var err error
+	if email.Subject != "" {
+		if subject, err = texttemplate.New("subject").Option("missingkey=error").Parse(email.Subject); err != nil {
+			return nil, fmt.Errorf("emails.BulkMailer.Send: %w", err)
+		}
+	}
+	if len(email.Text) > 0 {
+		if text, err = texttemplate.New("text").Option("missingkey=error").Parse(string(email.Text)); err != nil {
+			return nil, fmt.Errorf("emails.BulkMailer.Send: %w", err)
+		}
+	}
+	if len(email.HTML) > 0 {
+		if html, err = template.New("html").Option("missingkey=error").Parse(string(email.HTML)); err != nil {
+			return nil, fmt.Errorf("emails.BulkMailer.Send: %w", err)
+		}
+	}
+
+	return m.send(ctx, email, recipients, func(email *Email, recipient BulkRecipient) error {
+		var buffer bytes.Buffer
+		if subject != nil {
+			if err := subject.Execute(&buffer, recipient.Vars); err != nil {
+				return err
+			}
+			email.Subject = buffer.String()
+		}
+		if text != nil {
+			buffer = bytes.Buffer{}
+			if err := text.Execute(&buffer, recipient.Vars); err != nil {
+				return err
+			}
+			email.Text = buffer.Bytes()
+		}
+		if html != nil {
+			buffer = bytes.Buffer{}
+			if err := html.Execute(&buffer, recipient.Vars); err != nil {
+				return err
+			}
+			email.HTML = buffer.Bytes()
+		}
+		return nil
+	}), nil
+}
+
+// SendTemplate sends the email name of templates to every recipient, rendered in its Language with its Vars, see
+// Templates.Render. email holds the other fields of the emails, e.g. their headers or attachments, the recipient
+// replaces its To, Cc and Bcc.
+func (m *BulkMailer) SendTemplate(ctx context.Context, templates *Templates, name string, email *Email, recipients []BulkRecipient) (*BulkReport, error) {
+	if templates.lookup(name, templates.options.FallbackLanguage) == nil {
+		return nil, fmt.Errorf("emails.BulkMailer.SendTemplate: %w: %s", ErrTemplateNotFound, name)
+	}
+	return m.send(ctx, email, recipients, func(email *Email, recipient BulkRecipient) error {
+		return templates.Render(email, name, recipient.Language, recipient.Vars)
+	}), nil
+}
+
+// send delivers a copy of email, personalized by compose, to every recipient.
+func (m *BulkMailer) send(ctx context.Context, email *Email, recipients []BulkRecipient, compose bulkComposer) *BulkReport {
+	var limiter *utils.RateLimiter
+	if m.options.Interval > 0 {
+		limiter = utils.CreateLimiter(m.options.Interval, m.options.Burst)
+		limiter.Start()
+		// Stopped once every worker is done waiting for it.
+		defer limiter.Stop()
+	}
+
+	var report = BulkReport{Results: make([]BulkResult, len(recipients))}
+	var jobs = make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < m.options.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				report.Results[i] = m.deliver(ctx, limiter, email, recipients[i], compose)
+			}
+		}()
+	}
+	for i := range recipients {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return &report
+}
+
+// deliver sends the email of a recipient until it is accepted, permanently refused or MaxAttempts is reached.
+func (m *BulkMailer) deliver(ctx context.Context, limiter *utils.RateLimiter, email *Email, recipient BulkRecipient, compose bulkComposer) BulkResult {
+	var result = BulkResult{Address: recipient.Address}
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	email = bulkEmail(email, recipient.Address)
+	if err := compose(email, recipient); err != nil {
+		result.Err = err
+		return result
+	}
+
+	backoff := m.options.Backoff
+	for {
+		if limiter != nil {
+			limiter.Wait()
+		}
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			return result
+		}
+		result.Attempts++
+		sent, err := m.sender.Send(ctx, email)
+		if sent != nil && len(sent.Recipients) > 0 {
+			r := sent.Recipients[0]
+			result.Accepted, result.Code, result.Err = r.Accepted && err == nil, r.Code, r.Err
+		} else {
+			result.Accepted, result.Code, result.Err = err == nil, replyCode(err), nil
+		}
+		if result.Accepted {
+			result.MessageID = sent.MessageID
+			return result
+		}
+		if result.Err == nil {
+			result.Err = err
+		}
+		if result.Attempts >= m.options.MaxAttempts || !temporaryFailure(result.Err) {
+			return result
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			result.Err = ctx.Err()
+			return result
+		}
+		if backoff *= 2; backoff > m.options.MaxBackoff {
+			backoff = m.options.MaxBackoff
+		}
+	}
+}
+
+// bulkEmail returns a copy of email sent to address only. The headers and attachments are copied, Email.ToBytes sets the
+// default headers of the attachments, the content of the attachments is shared.
+func bulkEmail(email *Email, address string) *Email {
+	var copied = *email
+	copied.To, copied.Cc, copied.Bcc = []string{address}, nil, nil
+	copied.Headers = make(textproto.MIMEHeader, len(email.Headers))
+	for name, values := range email.Headers {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if name == "To" || name == "Cc" || name == "Bcc" {
+			continue
+		}
+		copied.Headers[name] = append([]string(nil), values...)
+	}
+
+	if email.Attachments != nil {
+		copied.Attachments = make([]*Attachment, len(email.Attachments))
+	}
+	for i, a := range email.Attachments {
+		var attachment = *a
+		attachment.Header = make(textproto.MIMEHeader, len(a.Header))
+		for name, values := range a.Header {
+			attachment.Header[name] = append([]string(nil), values...)
+		}
+		copied.Attachments[i] = &attachment
+	}
+	return &copied
+}
+
+// temporaryFailure returns true if the email may be accepted later: a 4xx SMTP reply or a temporary APIError.
+func temporaryFailure(err error) bool {
+	if code := replyCode(err); code >= 400 && code < 500 {
+		return true
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Temporary()
+}
+
+// Sent returns the number of recipients the email was delivered to.
+func (r *BulkReport) Sent() int {
+	var sent int
+	for _, result := range r.Results {
+		if result.Accepted {
+			sent++
+		}
+	}
+	return sent
+}
+
+// Failed returns the results of the recipients the email was not delivered to.
+func (r *BulkReport) Failed() []BulkResult {
+	var failed []BulkResult
+	for _, result := range r.Results {
+		if !result.Accepted {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
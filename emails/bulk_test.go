@@ -0,0 +1,218 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sabriboughanmi/go_utils/i18n"
+)
+
+// senderFunc is a Sender calling a function.
+type senderFunc func(ctx context.Context, email *Email) (*SendResult, error)
+
+func (f senderFunc) Send(ctx context.Context, email *Email) (*SendResult, error) {
+	return f(ctx, email)
+}
+
+func TestBulkMailerSend(t *testing.T) {
+	server := (&fakeSMTPServer{}).start(t, nil)
+	sender := NewSMTPSender(server.emailAddress(), SMTPOptions{})
+	defer sender.Close()
+	mailer := NewBulkMailer(sender, BulkOptions{Workers: 2, Interval: time.Millisecond, Burst: 2, Backoff: time.Millisecond})
+
+	email := NewEmail()
+	email.Bcc = []string{"archive@example.com"}
+	email.Headers.Set("X-Campaign", "weekly")
+	email.Subject = "{{.Name}}, your weekly digest"
+	email.Text = []byte("Hello {{.Name}}, you have {{.Count}} new videos.")
+	email.HTML = []byte("<p>Hello {{.Name}}</p>")
+	report, err := mailer.Send(context.Background(), email, []BulkRecipient{
+		{Address: "Sam <sam@example.com>", Vars: map[string]interface{}{"Name": "Sam", "Count": 3}},
+		{Address: "busy@example.com", Vars: map[string]interface{}{"Name": "Busy", "Count": 1}},
+		{Address: "reject@example.com", Vars: map[string]interface{}{"Name": "Reject", "Count": 1}},
+		{Address: "alex@example.com", Vars: map[string]interface{}{"Name": "<Alex>"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		accepted bool
+		attempts int
+		code     int
+	}{{true, 1, 0}, {false, 3, 450}, {false, 1, 550}, {false, 0, 0}}
+	for i, r := range report.Results {
+		if r.Accepted != expected[i].accepted || r.Attempts != expected[i].attempts || r.Code != expected[i].code {
+			t.Errorf("%s: got %+v", r.Address, r)
+		}
+		if r.Accepted != (r.Err == nil) || r.Accepted != (r.MessageID != "") {
+			t.Errorf("%s: unexpected error %v or message ID %q", r.Address, r.Err, r.MessageID)
+		}
+	}
+	// The variable Count is missing.
+	if err := report.Results[3].Err; err == nil || !strings.Contains(err.Error(), "Count") {
+		t.Errorf("expected a missing variable error, got %v", err)
+	}
+	if report.Sent() != 1 || len(report.Failed()) != 3 {
+		t.Errorf("got %d sent and %d failed", report.Sent(), len(report.Failed()))
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(server.messages))
+	}
+	message := server.messages[0]
+	if strings.Join(message.to, ",") != "TO:<sam@example.com>" {
+		t.Errorf("expected the recipient only, got %q", message.to)
+	}
+	for _, s := range []string{"Subject: Sam, your weekly digest", "X-Campaign: weekly", "you have 3 new videos", "<p>Hello Sam</p>"} {
+		if !strings.Contains(message.data, s) {
+			t.Errorf("expected %q in %q", s, message.data)
+		}
+	}
+}
+
+func TestBulkMailerAttachments(t *testing.T) {
+	// The copies of the email are converted concurrently, run with -race.
+	sender := senderFunc(func(ctx context.Context, email *Email) (*SendResult, error) {
+		raw, err := email.ToBytes(testAPIFrom)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Contains(raw, []byte(`filename="report.txt"`)) {
+			return nil, fmt.Errorf("attachment missing from %q", raw)
+		}
+		return &SendResult{Recipients: []RecipientResult{{Address: email.To[0], Accepted: true}}}, nil
+	})
+
+	email := newTestEmail()
+	email.Attach(strings.NewReader("report"), "report.txt", "text/plain")
+	var recipients []BulkRecipient
+	for i := 0; i < 8; i++ {
+		recipients = append(recipients, BulkRecipient{Address: fmt.Sprintf("user%d@example.com", i)})
+	}
+	report, err := NewBulkMailer(sender, BulkOptions{Workers: 4}).Send(context.Background(), email, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Errorf("unexpected failures %+v", failed)
+	}
+	if len(email.Attachments[0].Header) != 0 {
+		t.Errorf("the attachment of the email was modified: %v", email.Attachments[0].Header)
+	}
+}
+
+func TestBulkMailerRetries(t *testing.T) {
+	var mu sync.Mutex
+	var attempts = make(map[string]int)
+	sender := senderFunc(func(ctx context.Context, email *Email) (*SendResult, error) {
+		mu.Lock()
+		attempts[email.To[0]]++
+		attempt := attempts[email.To[0]]
+		mu.Unlock()
+
+		switch {
+		case email.To[0] == "limited@example.com" && attempt < 3:
+			err := &APIError{Provider: "Test", StatusCode: http.StatusTooManyRequests}
+			return &SendResult{Recipients: []RecipientResult{{Address: email.To[0], Code: err.StatusCode, Err: err}}}, err
+		case email.To[0] == "invalid@example.com":
+			err := &APIError{Provider: "Test", StatusCode: http.StatusBadRequest}
+			return &SendResult{Recipients: []RecipientResult{{Address: email.To[0], Code: err.StatusCode, Err: err}}}, err
+		}
+		return &SendResult{MessageID: "id", Recipients: []RecipientResult{{Address: email.To[0], Accepted: true}}}, nil
+	})
+
+	mailer := NewBulkMailer(sender, BulkOptions{MaxAttempts: 5, Backoff: time.Millisecond})
+	report, err := mailer.Send(context.Background(), newTestEmail(), []BulkRecipient{{Address: "limited@example.com"}, {Address: "invalid@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := report.Results[0]; !r.Accepted || r.Attempts != 3 || r.MessageID != "id" {
+		t.Errorf("expected a delivery at the third attempt, got %+v", r)
+	}
+	// 400 is not temporary.
+	if r := report.Results[1]; r.Accepted || r.Attempts != 1 || r.Code != http.StatusBadRequest {
+		t.Errorf("expected a single attempt, got %+v", r)
+	}
+}
+
+func TestBulkMailerRateLimit(t *testing.T) {
+	var recipients []BulkRecipient
+	for i := 0; i < 5; i++ {
+		recipients = append(recipients, BulkRecipient{Address: "user@example.com"})
+	}
+	sender := senderFunc(func(ctx context.Context, email *Email) (*SendResult, error) {
+		return &SendResult{Recipients: []RecipientResult{{Address: email.To[0], Accepted: true}}}, nil
+	})
+
+	// The burst sends 2 emails immediately, the 3 others wait for an interval each.
+	mailer := NewBulkMailer(sender, BulkOptions{Workers: 5, Interval: 20 * time.Millisecond, Burst: 2})
+	start := time.Now()
+	report, err := mailer.Send(context.Background(), newTestEmail(), recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("expected the sends to take at least 60ms, took %v", elapsed)
+	}
+	if report.Sent() != 5 {
+		t.Errorf("expected 5 sent emails, got %d", report.Sent())
+	}
+}
+
+func TestBulkMailerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sender := senderFunc(func(ctx context.Context, email *Email) (*SendResult, error) {
+		cancel()
+		return &SendResult{Recipients: []RecipientResult{{Address: email.To[0], Accepted: true}}}, nil
+	})
+	mailer := NewBulkMailer(sender, BulkOptions{Workers: 1})
+	report, err := mailer.Send(ctx, newTestEmail(), []BulkRecipient{{Address: "sam@example.com"}, {Address: "alex@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Results[0].Accepted || report.Results[1].Err != context.Canceled || report.Results[1].Attempts != 0 {
+		t.Errorf("expected the second recipient to be canceled, got %+v", report.Results)
+	}
+}
+
+func TestBulkMailerSendTemplate(t *testing.T) {
+	templates, err := LoadTemplates(testTemplates, TemplateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var subjects = make(map[string]string)
+	sender := senderFunc(func(ctx context.Context, email *Email) (*SendResult, error) {
+		mu.Lock()
+		subjects[email.To[0]] = email.Subject
+		mu.Unlock()
+		return &SendResult{Recipients: []RecipientResult{{Address: email.To[0], Accepted: true}}}, nil
+	})
+
+	mailer := NewBulkMailer(sender, BulkOptions{})
+	vars := map[string]interface{}{"Name": "Sam", "Title": "Paris", "ID": 1}
+	report, err := mailer.SendTemplate(context.Background(), templates, "video_approved", NewEmail(), []BulkRecipient{
+		{Address: "sam@example.com", Vars: vars},
+		{Address: "camille@example.com", Language: i18n.LanguageCode_Fr_fr, Vars: vars},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sent() != 2 || subjects["sam@example.com"] != "Your video Paris was approved" ||
+		subjects["camille@example.com"] != "Votre vidéo Paris a été approuvée" {
+		t.Errorf("got %d sent emails and subjects %q", report.Sent(), subjects)
+	}
+
+	if _, err := mailer.SendTemplate(context.Background(), templates, "unknown", NewEmail(), nil); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
@@ -17,4 +17,86 @@ var ErrMissingContentType = errors.New("No Content-Type found for MIME entity")
 
 var ErrSenderMustBeSpecified = errors.New("sender Must be Specified")
 
-var ErrReceiversMustBeSpecified = errors.New("at least one receiver must be specified")
\ No newline at end of file
+var ErrReceiversMustBeSpecified = errors.New("at least one receiver must be specified")
+
+// ErrToMustBeSpecified is returned by the senders whose API refuses an email without To recipient, e.g. SendGrid
+var ErrToMustBeSpecified = errors.New("at least one To receiver must be specified")
+
+var ErrStartTLSUnsupported = errors.New("the SMTP server does not support STARTTLS")
+
+var ErrAuthUnsupported = errors.New("the SMTP server does not support authentication")
+
+// TLSMode defines how an SMTP connection is secured
+type TLSMode byte
+
+const (
+	TLSOpportunistic TLSMode = 0 // upgrades the connection with STARTTLS if the server supports it
+	TLSStartTLS      TLSMode = 1 // STARTTLS is required, usually on port 587
+	TLSImplicit      TLSMode = 2 // TLS from the first byte, usually on port 465
+	TLSNone          TLSMode = 3 // never encrypts the connection, only suitable for local relays
+)
+
+// AuthMechanism defines the SASL mechanism used to authenticate to an SMTP server
+type AuthMechanism string
+
+const (
+	AuthAuto    AuthMechanism = ""         // XOAUTH2 if SMTPOptions.OAuth2Token is set, otherwise the first of CRAM-MD5, PLAIN and LOGIN supported by the server
+	AuthNone    AuthMechanism = "NONE"     // no authentication, e.g for relays authorizing by IP
+	AuthPlain   AuthMechanism = "PLAIN"    // requires TLS, except on localhost
+	AuthLogin   AuthMechanism = "LOGIN"    // requires TLS, except on localhost
+	AuthCRAMMD5 AuthMechanism = "CRAM-MD5" // the password is never sent
+	AuthXOAUTH2 AuthMechanism = "XOAUTH2"  // Gmail and Outlook OAuth2 access tokens, requires TLS except on localhost
+)
+
+var ErrTemplateNotFound = errors.New("email template not found")
+
+// DKIMCanonicalization defines how a message is normalized before being signed, see RFC 6376 section 3.4
+type DKIMCanonicalization string
+
+const (
+	DKIMRelaxed DKIMCanonicalization = "relaxed" // tolerates whitespace changes and header folding by relays, the default
+	DKIMSimple  DKIMCanonicalization = "simple"  // tolerates almost no modification
+)
+
+// DefaultDKIMHeaders are the headers signed when DKIMOptions.Headers is empty.
+var DefaultDKIMHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-Id", "In-Reply-To", "References",
+	"Mime-Version", "Content-Type", "Content-Transfer-Encoding", "List-Unsubscribe",
+}
+
+var ErrInvalidAddress = errors.New("invalid email address")
+
+var ErrDisposableAddress = errors.New("the email address belongs to a disposable email provider")
+
+var ErrRoleAddress = errors.New("the email address is a role account")
+
+var ErrNoMXRecord = errors.New("the domain of the email address does not accept emails")
+
+// ValidationLevel defines the checks of an AddressValidator, every level includes the checks of the previous ones
+type ValidationLevel byte
+
+const (
+	ValidationSyntax     ValidationLevel = 0 // addr-spec of RFC 5322 with the UTF-8 characters of RFC 6531, internationalized domains
+	ValidationDisposable ValidationLevel = 1 // refuses the domains of disposable email providers
+	ValidationRole       ValidationLevel = 2 // refuses the role accounts, e.g. support@ or postmaster@
+	ValidationMX         ValidationLevel = 3 // requires an MX record for the domain, the only level querying the DNS
+)
+
+// DefaultDisposableDomains are the domains refused by ValidationDisposable when ValidationOptions.DisposableDomains is empty.
+var DefaultDisposableDomains = []string{
+	"10minutemail.com", "20minutemail.com", "33mail.com", "anonbox.net", "burnermail.io", "discard.email", "dispostable.com",
+	"emailondeck.com", "fakeinbox.com", "getairmail.com", "getnada.com", "guerrillamail.biz", "guerrillamail.com",
+	"guerrillamail.de", "guerrillamail.net", "guerrillamail.org", "guerrillamailblock.com", "harakirimail.com",
+	"incognitomail.org", "mailcatch.com", "maildrop.cc", "mailinator.com", "mailinator.net", "mailnesia.com", "mailsac.com",
+	"mintemail.com", "mohmal.com", "mytemp.email", "sharklasers.com", "spam4.me", "spamgourmet.com", "temp-mail.io",
+	"temp-mail.org", "tempmail.net", "tempmailo.com", "tempr.email", "throwawaymail.com", "trashmail.com", "trashmail.de",
+	"yopmail.com", "yopmail.fr", "yopmail.net",
+}
+
+// DefaultRoleAccounts are the local parts detected as role accounts when ValidationOptions.RoleAccounts is empty.
+var DefaultRoleAccounts = []string{
+	"abuse", "admin", "administrator", "billing", "compliance", "contact", "devnull", "dns", "ftp", "help", "hostmaster",
+	"info", "inoc", "ispfeedback", "ispsupport", "list", "list-request", "mailer-daemon", "marketing", "media", "news",
+	"no-reply", "noc", "noreply", "null", "office", "postmaster", "privacy", "root", "sales", "security", "spam", "support",
+	"sysadmin", "team", "usenet", "uucp", "webmaster", "www",
+}
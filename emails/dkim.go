@@ -0,0 +1,232 @@
+package emails
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dkimHeaderField is a header field of a message, as written in the message.
+type dkimHeaderField struct {
+	name string // lower case
+	raw  string // the whole field with its folding whitespaces, without the final CRLF
+}
+
+// SignDKIM signs a message built by Email.ToBytes with DKIM (RFC 6376) and returns it with its DKIM-Signature header.
+// Line endings are normalized to CRLF.
+func SignDKIM(message []byte, options DKIMOptions) ([]byte, error) {
+	return signDKIM(message, options, time.Now())
+}
+
+// signDKIM signs message at the time now.
+func signDKIM(message []byte, options DKIMOptions, now time.Time) ([]byte, error) {
+	if options.Domain == "" || options.Selector == "" || options.Signer == nil {
+		return nil, errors.New("emails.SignDKIM: the domain, selector and signer must be specified")
+	}
+	var algorithm string
+	switch options.Signer.Public().(type) {
+	case *rsa.PublicKey:
+		algorithm = "rsa-sha256"
+	case ed25519.PublicKey:
+		algorithm = "ed25519-sha256"
+	default:
+		return nil, fmt.Errorf("emails.SignDKIM: unsupported key type %T", options.Signer.Public())
+	}
+	headerCanonicalization, bodyCanonicalization := options.HeaderCanonicalization, options.BodyCanonicalization
+	if headerCanonicalization == "" {
+		headerCanonicalization = DKIMRelaxed
+	}
+	if bodyCanonicalization == "" {
+		bodyCanonicalization = DKIMRelaxed
+	}
+	if headerCanonicalization != DKIMRelaxed && headerCanonicalization != DKIMSimple ||
+		bodyCanonicalization != DKIMRelaxed && bodyCanonicalization != DKIMSimple {
+		return nil, fmt.Errorf("emails.SignDKIM: unknown canonicalization %s/%s", headerCanonicalization, bodyCanonicalization)
+	}
+
+	message = normalizeCRLF(message)
+	var header, body []byte
+	if i := bytes.Index(message, []byte("\r\n\r\n")); i >= 0 {
+		header, body = message[:i+2], message[i+4:]
+	} else {
+		header = message
+	}
+	fields := parseDKIMHeader(header)
+
+	bodyHash := sha256.Sum256(canonicalizeDKIMBody(body, bodyCanonicalization))
+
+	// Header fields are signed from the bottom of the header, a name listed twice signs two instances.
+	var signedNames []string
+	var signed []dkimHeaderField
+	var used = make(map[int]bool)
+	names := options.Headers
+	if len(names) == 0 {
+		names = DefaultDKIMHeaders
+	}
+	if !containsFold(names, "From") {
+		names = append([]string{"From"}, names...)
+	}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for i := len(fields) - 1; i >= 0; i-- {
+			if fields[i].name == name && !used[i] {
+				used[i] = true
+				signed = append(signed, fields[i])
+				signedNames = append(signedNames, name)
+				break
+			}
+		}
+	}
+
+	tags := []string{
+		"v=1",
+		"a=" + algorithm,
+		"c=" + string(headerCanonicalization) + "/" + string(bodyCanonicalization),
+		"d=" + options.Domain,
+		"s=" + options.Selector,
+		"t=" + strconv.FormatInt(now.Unix(), 10),
+	}
+	if options.Expiration > 0 {
+		tags = append(tags, "x="+strconv.FormatInt(now.Add(options.Expiration).Unix(), 10))
+	}
+	tags = append(tags,
+		"h="+strings.Join(signedNames, ":"),
+		"bh="+base64.StdEncoding.EncodeToString(bodyHash[:]),
+		"b=",
+	)
+	signature := dkimHeaderField{name: "dkim-signature", raw: "DKIM-Signature: " + strings.Join(tags, "; ")}
+
+	// The signature covers the signed fields and the DKIM-Signature field with an empty b= tag, without its final CRLF.
+	hash := sha256.New()
+	for _, field := range signed {
+		hash.Write([]byte(canonicalizeDKIMHeader(field, headerCanonicalization)))
+		hash.Write([]byte("\r\n"))
+	}
+	hash.Write([]byte(canonicalizeDKIMHeader(signature, headerCanonicalization)))
+
+	var b []byte
+	var err error
+	if algorithm == "rsa-sha256" {
+		b, err = options.Signer.Sign(rand.Reader, hash.Sum(nil), crypto.SHA256)
+	} else {
+		// RFC 8463: Ed25519 signs the SHA-256 hash of the data.
+		b, err = options.Signer.Sign(rand.Reader, hash.Sum(nil), crypto.Hash(0))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("emails.SignDKIM: %w", err)
+	}
+
+	var signedMessage = bytes.NewBuffer(make([]byte, 0, len(message)+len(signature.raw)+512))
+	signedMessage.WriteString(signature.raw)
+	signedMessage.WriteString(base64.StdEncoding.EncodeToString(b))
+	signedMessage.WriteString("\r\n")
+	signedMessage.Write(message)
+	return signedMessage.Bytes(), nil
+}
+
+// normalizeCRLF replaces the bare LF line endings with CRLF.
+func normalizeCRLF(message []byte) []byte {
+	if !bytes.Contains(bytes.Replace(message, []byte("\r\n"), nil, -1), []byte("\n")) {
+		return message
+	}
+	message = bytes.Replace(message, []byte("\r\n"), []byte("\n"), -1)
+	return bytes.Replace(message, []byte("\n"), []byte("\r\n"), -1)
+}
+
+// parseDKIMHeader splits a header into its fields, continuation lines are part of the field they continue.
+func parseDKIMHeader(header []byte) []dkimHeaderField {
+	var fields []dkimHeaderField
+	for _, line := range strings.SplitAfter(string(header), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].raw += line
+			continue
+		}
+		name := line
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			name = line[:i]
+		}
+		fields = append(fields, dkimHeaderField{name: strings.ToLower(strings.TrimSpace(name)), raw: line})
+	}
+	for i := range fields {
+		fields[i].raw = strings.TrimSuffix(fields[i].raw, "\r\n")
+	}
+	return fields
+}
+
+// canonicalizeDKIMHeader returns the canonical form of a header field, without its final CRLF.
+func canonicalizeDKIMHeader(field dkimHeaderField, canonicalization DKIMCanonicalization) string {
+	if canonicalization == DKIMSimple {
+		return field.raw
+	}
+	var value string
+	if i := strings.IndexByte(field.raw, ':'); i >= 0 {
+		value = field.raw[i+1:]
+	}
+	// Unfolds the value and reduces every sequence of whitespaces to a single space.
+	value = strings.Replace(value, "\r\n", "", -1)
+	return field.name + ":" + strings.TrimSpace(collapseWhitespaces(value))
+}
+
+// canonicalizeDKIMBody returns the canonical form of a CRLF terminated body.
+func canonicalizeDKIMBody(body []byte, canonicalization DKIMCanonicalization) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	if canonicalization == DKIMRelaxed {
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(collapseWhitespaces(line), " ")
+		}
+	}
+	// Empty lines at the end of the body are ignored.
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		if canonicalization == DKIMSimple {
+			return []byte("\r\n")
+		}
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// collapseWhitespaces replaces every sequence of spaces and tabs with a single space.
+func collapseWhitespaces(s string) string {
+	var b strings.Builder
+	var space bool
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' || s[i] == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteByte(s[i])
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// containsFold returns true if values contains value, ignoring case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
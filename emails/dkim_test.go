@@ -0,0 +1,217 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bTagValue matches the value of the b= tag of a DKIM-Signature, but not the one of bh=.
+var bTagValue = regexp.MustCompile(`(^|[;\s])b=[^;]*`)
+
+// verifyDKIM verifies the first DKIM-Signature of message with the public key of the signer.
+func verifyDKIM(message []byte, public crypto.PublicKey) error {
+	i := bytes.Index(message, []byte("\r\n\r\n"))
+	if i < 0 {
+		return errors.New("no body")
+	}
+	fields, body := parseDKIMHeader(message[:i+2]), message[i+4:]
+	if len(fields) == 0 || fields[0].name != "dkim-signature" {
+		return errors.New("no DKIM-Signature")
+	}
+	signature := fields[0]
+	fields = fields[1:]
+
+	var tags = make(map[string]string)
+	for _, tag := range strings.Split(signature.raw[strings.IndexByte(signature.raw, ':')+1:], ";") {
+		if kv := strings.SplitN(tag, "=", 2); len(kv) == 2 {
+			tags[strings.TrimSpace(kv[0])] = strings.Join(strings.Fields(kv[1]), "")
+		}
+	}
+	canonicalizations := strings.Split(tags["c"], "/")
+	headerCanonicalization, bodyCanonicalization := DKIMCanonicalization(canonicalizations[0]), DKIMSimple
+	if len(canonicalizations) > 1 {
+		bodyCanonicalization = DKIMCanonicalization(canonicalizations[1])
+	}
+
+	bodyHash := sha256.Sum256(canonicalizeDKIMBody(body, bodyCanonicalization))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != tags["bh"] {
+		return errors.New("body hash mismatch")
+	}
+
+	hash := sha256.New()
+	var used = make(map[int]bool)
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if fields[i].name == strings.ToLower(name) && !used[i] {
+				used[i] = true
+				hash.Write([]byte(canonicalizeDKIMHeader(fields[i], headerCanonicalization) + "\r\n"))
+				break
+			}
+		}
+	}
+	signature.raw = bTagValue.ReplaceAllString(signature.raw, "${1}b=")
+	hash.Write([]byte(canonicalizeDKIMHeader(signature, headerCanonicalization)))
+
+	b, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return err
+	}
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash.Sum(nil), b)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, hash.Sum(nil), b) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key %T", public)
+}
+
+// TestVerifyDKIMExample checks the canonicalization against the signed example of RFC 8463, appendix A.
+func TestVerifyDKIMExample(t *testing.T) {
+	message := strings.Replace(`DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;
+ d=football.example.com; i=@football.example.com;
+ q=dns/txt; s=brisbane; t=1528637909; h=from : to :
+ subject : date : message-id : from : subject : date;
+ bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;
+ b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus
+ Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==
+From: Joe SixPack <joe@football.example.com>
+To: Suzie Q <suzie@shopping.example.net>
+Subject: Is dinner ready?
+Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)
+Message-ID: <20030712040037.46341.5F8J@football.example.com>
+
+Hi.
+
+We lost the game.  Are you hungry yet?
+
+Joe.
+`, "\n", "\r\n", -1)
+	public, _ := base64.StdEncoding.DecodeString("11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=")
+	if err := verifyDKIM([]byte(message), ed25519.PublicKey(public)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignDKIM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email := newTestEmail("Sam <sam@example.com>")
+	email.HTML = []byte("<p>Hello!</p>")
+	raw, err := email.ToBytes(NewEmailAddress("noreply@example.com", "Tested4you", "", "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, signer := range []crypto.Signer{rsaKey, ed25519Key} {
+		for _, canonicalization := range []DKIMCanonicalization{DKIMRelaxed, DKIMSimple} {
+			options := DKIMOptions{
+				Domain: "example.com", Selector: "mail", Signer: signer,
+				HeaderCanonicalization: canonicalization, BodyCanonicalization: canonicalization,
+				Expiration: time.Hour,
+			}
+			signed, err := signDKIM(raw, options, time.Unix(1700000000, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := fmt.Sprintf("%T %s", signer, canonicalization)
+
+			signature := string(signed[:bytes.Index(signed, []byte("\r\n"))])
+			for _, tag := range []string{"c=" + string(canonicalization) + "/" + string(canonicalization), "d=example.com", "s=mail",
+				"t=1700000000", "x=1700003600", "h=from:subject:date:to:message-id:mime-version:content-type"} {
+				if !strings.Contains(signature, tag+";") {
+					t.Errorf("%s: expected %s in %q", name, tag, signature)
+				}
+			}
+			if err := verifyDKIM(signed, signer.Public()); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+
+			// Relays may refold headers and add trailing whitespaces, only the relaxed canonicalization tolerates it.
+			relayed := bytes.Replace(signed, []byte("Subject: Your video"), []byte("Subject:  Your\r\n\tvideo"), 1)
+			relayed = append(relayed, " \r\n\r\n"...)
+			if err := verifyDKIM(relayed, signer.Public()); (err == nil) != (canonicalization == DKIMRelaxed) {
+				t.Errorf("%s: unexpected verification of the relayed message: %v", name, err)
+			}
+
+			tampered := bytes.Replace(signed, []byte("approved"), []byte("rejected"), 1)
+			if err := verifyDKIM(tampered, signer.Public()); err == nil {
+				t.Errorf("%s: expected the verification of a tampered message to fail", name)
+			}
+		}
+	}
+}
+
+func TestSMTPSenderDKIM(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := (&fakeSMTPServer{}).start(t, nil)
+	sender := NewSMTPSender(server.emailAddress(), SMTPOptions{
+		DKIM: &DKIMOptions{Domain: "example.com", Selector: "mail", Signer: key},
+	})
+	defer sender.Close()
+
+	result, err := sender.Send(context.Background(), newTestEmail("user@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MessageID == "" {
+		t.Error("expected the message ID of the signed message")
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// textproto.Reader.ReadDotBytes converts the line endings to LF.
+	data := normalizeCRLF([]byte(server.messages[0].data))
+	if !bytes.HasPrefix(data, []byte("DKIM-Signature: v=1; a=ed25519-sha256;")) {
+		t.Fatalf("expected a DKIM-Signature, got %q", data)
+	}
+	if err := verifyDKIM(data, key.Public()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEmailSendDKIM(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := (&fakeSMTPServer{authMechanisms: []string{"PLAIN"}}).start(t, nil)
+	sender := server.emailAddress().WithDKIM(DKIMOptions{Domain: "example.com", Selector: "mail", Signer: key})
+	if err := newTestEmail("user@example.com").Send(sender); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	data := normalizeCRLF([]byte(server.messages[0].data))
+	if !bytes.HasPrefix(data, []byte("DKIM-Signature: v=1; a=ed25519-sha256;")) {
+		t.Fatalf("expected a DKIM-Signature, got %q", data)
+	}
+	if err := verifyDKIM(data, key.Public()); err != nil {
+		t.Error(err)
+	}
+}
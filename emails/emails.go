@@ -4,17 +4,14 @@ package emails
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"mime"
 	"mime/multipart"
-	"net"
-	"net/mail"
-	"net/smtp"
 	"net/textproto"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 //returns an EmailAddress to be used for sending Emails
@@ -28,6 +25,13 @@ func NewEmailAddress(address, userName, password, host string, port int) EmailAd
 	}
 }
 
+// WithDKIM returns a copy of the EmailAddress signing the emails sent by Email.Send with DKIM.
+// An SMTPSender signs its emails with SMTPOptions.DKIM instead.
+func (e EmailAddress) WithDKIM(options DKIMOptions) EmailAddress {
+	e.dkim = &options
+	return e
+}
+
 // NewEmail creates an Email, and returns the pointer to it.
 func NewEmail() *Email {
 	return &Email{Headers: textproto.MIMEHeader{}}
@@ -196,54 +200,35 @@ func (e *Email) ToBytes(emailAddress EmailAddress) ([]byte, error) {
 	return buff.Bytes(), nil
 }
 
-// Send an email using the given host and SMTP auth (optional), returns any error thrown by smtp.SendMail
-// This function merges the To, Cc, and Bcc fields and calls the smtp.SendMail function using the Email.ToBytes() output as the message
+// Send an email using the given host and SMTP PLAIN auth over a new connection, the connection is upgraded with STARTTLS if possible.
+// It returns an error if any recipient was rejected.
+// The email is signed with DKIM if senderAddress was returned by EmailAddress.WithDKIM.
+// Use an SMTPSender to choose the TLS mode and auth mechanism, or to send several emails through the same connection.
 func (e *Email) Send(senderAddress EmailAddress) error {
-	auth := smtp.PlainAuth("", senderAddress.address, senderAddress.password, senderAddress.host)
-	// Merge the To, Cc, and Bcc fields
-	to := make([]string, 0, len(e.To)+len(e.Cc)+len(e.Bcc))
-	to = append(append(append(to, e.To...), e.Cc...), e.Bcc...)
-	for i := 0; i < len(to); i++ {
-		addr, err := mail.ParseAddress(to[i])
-		if err != nil {
-			return err
-		}
-		to[i] = addr.Address
-	}
-	// Check to make sure there is a Sender
-	if senderAddress.from() == "" {
-		return ErrSenderMustBeSpecified
-	}
-
-	// Check to make sure there is at least one recipient
-	if len(to) == 0 {
-		return ErrReceiversMustBeSpecified
-	}
+	sender := NewSMTPSender(senderAddress, SMTPOptions{Auth: AuthPlain, MaxConnections: 1, DKIM: senderAddress.dkim})
+	defer sender.Close()
 
-	sender, err := senderAddress.parseSender()
-	if err != nil {
-		return err
-	}
-	raw, err := e.ToBytes(senderAddress)
+	result, err := sender.Send(context.Background(), e)
 	if err != nil {
 		return err
 	}
-	return smtp.SendMail(senderAddress.addr(), auth, sender, to, raw)
+	return result.Err()
 }
 
+// emailValidator looks up the MX records of IsEmailValid.
+var emailValidator = NewAddressValidator(ValidationOptions{Level: ValidationMX})
+
 // IsEmailValid checks if the email provided passes the required structure
 // and length test. It also checks the domain has a valid MX record.
+// Use an AddressValidator to validate addresses offline, or to refuse disposable addresses and role accounts.
 func IsEmailValid(e string) bool {
-	if len(e) < 3 && len(e) > 254 {
-		return false
-	}
-	if !emailRegex.MatchString(e) {
+	if len(e) < 3 || len(e) > 254 {
 		return false
 	}
-	parts := strings.Split(e, "@")
-	mx, err := net.LookupMX(parts[1])
-	if err != nil || len(mx) == 0 {
+	_, domain, err := parseAddressSpec(e)
+	if err != nil || domain[0] == '[' {
 		return false
 	}
-	return true
+	// Disposable addresses and role accounts are valid, only the MX records are checked.
+	return emailValidator.checkMX(context.Background(), domain) == nil
 }
@@ -1,3 +1,13 @@
 module github.com/sabriboughanmi/go_utils/emails
 
 go 1.16
+
+require (
+	github.com/sabriboughanmi/go_utils/i18n v0.0.0-00010101000000-000000000000
+	github.com/sabriboughanmi/go_utils/utils v0.0.0-20211113191522-1da606498426
+	golang.org/x/net v0.0.0-20210503060351-7fd8e65b6420
+)
+
+replace github.com/sabriboughanmi/go_utils/i18n => ./../i18n
+
+replace github.com/sabriboughanmi/go_utils/utils => ./../utils
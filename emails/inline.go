@@ -0,0 +1,426 @@
+package emails
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// cssDeclaration is a property of a CSS rule.
+type cssDeclaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+// cssCompound is a compound selector: an optional type with classes and an id, e.g. "td.cell#total".
+type cssCompound struct {
+	tag     string // empty for the universal selector
+	id      string
+	classes []string
+	child   bool // the compound is a child of the previous one ("a > b"), otherwise a descendant
+}
+
+// cssRule is a CSS rule with a single selector, ordered from the outermost compound to the matched element.
+type cssRule struct {
+	selector     []cssCompound
+	specificity  int
+	order        int
+	declarations []cssDeclaration
+}
+
+// cssElement is an open element of the document.
+type cssElement struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+// voidElements never have an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// InlineCSS moves the rules of the <style> elements of an HTML document to the style attributes of the elements they
+// match, as many email clients ignore <style> elements. Style attributes of the document take precedence over the rules,
+// except !important ones.
+//
+// Only type, class, id and universal selectors, combined with descendant and child combinators, are inlined. Other rules,
+// like @media queries or :hover, are kept in a <style> element.
+func InlineCSS(document []byte) ([]byte, error) {
+	var styleSheet strings.Builder
+	z := html.NewTokenizer(bytes.NewReader(document))
+	for inStyle := false; ; {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return nil, z.Err()
+			}
+			break
+		}
+		name, _ := z.TagName()
+		switch {
+		case tokenType == html.StartTagToken && string(name) == "style":
+			inStyle = true
+		case tokenType == html.EndTagToken && string(name) == "style":
+			inStyle = false
+		case tokenType == html.TextToken && inStyle:
+			styleSheet.Write(z.Text())
+			styleSheet.WriteByte('\n')
+		}
+	}
+	if styleSheet.Len() == 0 {
+		return document, nil
+	}
+	rules, kept := parseStyleSheet(styleSheet.String())
+
+	var out bytes.Buffer
+	var stack []cssElement
+	var styleWritten, inStyle bool
+	z = html.NewTokenizer(bytes.NewReader(document))
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		raw := append([]byte(nil), z.Raw()...)
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.Data == "style" {
+				// The remaining rules replace the first <style> element.
+				inStyle = tokenType == html.StartTagToken
+				if !styleWritten && kept != "" {
+					out.WriteString("<style>" + kept + "</style>")
+				}
+				styleWritten = true
+				continue
+			}
+
+			element := newCSSElement(token)
+			stack = append(stack, element)
+			if declarations := matchingDeclarations(rules, stack, styleAttribute(token)); len(declarations) > 0 {
+				setAttribute(&token, "style", formatDeclarations(declarations))
+				raw = []byte(token.String())
+			}
+			if tokenType == html.SelfClosingTagToken || voidElements[token.Data] {
+				stack = stack[:len(stack)-1]
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if string(name) == "style" {
+				inStyle = false
+				continue
+			}
+			// Unclosed elements are implicitly closed by their parent end tag.
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].tag == string(name) {
+					stack = stack[:i]
+					break
+				}
+			}
+		case html.TextToken:
+			if inStyle {
+				continue
+			}
+		}
+		out.Write(raw)
+	}
+	return out.Bytes(), nil
+}
+
+// newCSSElement returns the element of a start tag.
+func newCSSElement(token html.Token) cssElement {
+	var element = cssElement{tag: token.Data}
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "id":
+			element.id = attr.Val
+		case "class":
+			element.classes = strings.Fields(attr.Val)
+		}
+	}
+	return element
+}
+
+// styleAttribute returns the declarations of the style attribute of a start tag.
+func styleAttribute(token html.Token) []cssDeclaration {
+	return parseDeclarations(tokenAttribute(token, "style"))
+}
+
+// setAttribute sets the value of an attribute of a start tag.
+func setAttribute(token *html.Token, key, value string) {
+	for i, attr := range token.Attr {
+		if attr.Key == key {
+			token.Attr[i].Val = value
+			return
+		}
+	}
+	token.Attr = append(token.Attr, html.Attribute{Key: key, Val: value})
+}
+
+// matchingDeclarations returns the declarations applying to the last element of stack, in cascade order.
+func matchingDeclarations(rules []cssRule, stack []cssElement, inline []cssDeclaration) []cssDeclaration {
+	var matched []cssRule
+	for _, rule := range rules {
+		if matchSelector(rule.selector, len(rule.selector)-1, stack, len(stack)-1) {
+			matched = append(matched, rule)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].specificity != matched[j].specificity {
+			return matched[i].specificity < matched[j].specificity
+		}
+		return matched[i].order < matched[j].order
+	})
+
+	var normal, important []cssDeclaration
+	for _, rule := range matched {
+		for _, declaration := range rule.declarations {
+			if declaration.important {
+				important = append(important, declaration)
+			} else {
+				normal = append(normal, declaration)
+			}
+		}
+	}
+	return append(append(normal, inline...), important...)
+}
+
+// matchSelector returns true if selector[:i+1] matches stack[j] and its ancestors.
+func matchSelector(selector []cssCompound, i int, stack []cssElement, j int) bool {
+	if !selector[i].matches(stack[j]) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if selector[i].child {
+		return j > 0 && matchSelector(selector, i-1, stack, j-1)
+	}
+	for k := j - 1; k >= 0; k-- {
+		if matchSelector(selector, i-1, stack, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if the compound selector matches element.
+func (c *cssCompound) matches(element cssElement) bool {
+	if c.tag != "" && c.tag != element.tag {
+		return false
+	}
+	if c.id != "" && c.id != element.id {
+		return false
+	}
+	for _, class := range c.classes {
+		if !containsString(element.classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatDeclarations returns the style attribute of declarations, a property declared several times keeps its last value.
+func formatDeclarations(declarations []cssDeclaration) string {
+	var properties []string
+	var values = make(map[string]string)
+	for _, declaration := range declarations {
+		if _, ok := values[declaration.property]; !ok {
+			properties = append(properties, declaration.property)
+		}
+		values[declaration.property] = declaration.value
+	}
+
+	var style = make([]string, len(properties))
+	for i, property := range properties {
+		style[i] = property + ": " + values[property]
+	}
+	return strings.Join(style, "; ")
+}
+
+// parseStyleSheet returns the rules that can be inlined, and the text of the others.
+func parseStyleSheet(css string) ([]cssRule, string) {
+	// Removes the comments.
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			css = css[:start]
+			break
+		}
+		css = css[:start] + css[start+2+end+2:]
+	}
+
+	var rules []cssRule
+	var kept []string
+	for css = strings.TrimSpace(css); css != ""; css = strings.TrimSpace(css) {
+		open := strings.IndexByte(css, '{')
+		if strings.HasPrefix(css, "@") {
+			// At-rules are statements (@import) or contain blocks (@media).
+			if semicolon := strings.IndexByte(css, ';'); semicolon >= 0 && (open < 0 || semicolon < open) {
+				kept = append(kept, css[:semicolon+1])
+				css = css[semicolon+1:]
+				continue
+			}
+		}
+		if open < 0 {
+			break
+		}
+		end := matchingBrace(css, open)
+		if end < 0 {
+			end = len(css) - 1
+		}
+		text := css[:end+1]
+		prelude, block := strings.TrimSpace(css[:open]), css[open+1:end]
+		css = css[end+1:]
+
+		if strings.HasPrefix(prelude, "@") {
+			kept = append(kept, text)
+			continue
+		}
+		declarations := parseDeclarations(block)
+		for _, s := range strings.Split(prelude, ",") {
+			selector, specificity, ok := parseSelector(s)
+			if !ok {
+				kept = append(kept, strings.TrimSpace(s)+" {"+block+"}")
+				continue
+			}
+			rules = append(rules, cssRule{selector: selector, specificity: specificity, order: len(rules), declarations: declarations})
+		}
+	}
+	return rules, strings.Join(kept, "\n")
+}
+
+// matchingBrace returns the index of the brace closing the one at open, -1 if it is not closed.
+func matchingBrace(css string, open int) int {
+	var depth int
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseSelector parses a selector, ok is false if it is not supported.
+// The specificity counts the ids, classes and types as base 100 digits.
+func parseSelector(s string) (selector []cssCompound, specificity int, ok bool) {
+	var child bool
+	for _, field := range strings.Fields(strings.Replace(s, ">", " > ", -1)) {
+		if field == ">" {
+			if len(selector) == 0 || child {
+				return nil, 0, false
+			}
+			child = true
+			continue
+		}
+
+		var compound = cssCompound{child: child}
+		child = false
+		for i := 0; i < len(field); {
+			kind := field[i]
+			if kind == '*' && i == 0 {
+				i++
+				continue
+			}
+			start := i
+			if kind == '.' || kind == '#' {
+				start++
+			}
+			end := start
+			for end < len(field) && isIdentifierByte(field[end]) {
+				end++
+			}
+			if end == start {
+				return nil, 0, false
+			}
+			switch kind {
+			case '.':
+				compound.classes = append(compound.classes, field[start:end])
+				specificity += 100
+			case '#':
+				compound.id = field[start:end]
+				specificity += 10000
+			default:
+				if i != 0 {
+					return nil, 0, false
+				}
+				compound.tag = strings.ToLower(field[start:end])
+				specificity++
+			}
+			i = end
+		}
+		selector = append(selector, compound)
+	}
+	return selector, specificity, len(selector) > 0 && !child
+}
+
+// isIdentifierByte returns true if b can be part of a CSS identifier.
+func isIdentifierByte(b byte) bool {
+	return b == '-' || b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// parseDeclarations parses the declarations of a rule or a style attribute.
+func parseDeclarations(block string) []cssDeclaration {
+	var declarations []cssDeclaration
+	for _, text := range splitDeclarations(block) {
+		colon := strings.IndexByte(text, ':')
+		if colon < 0 {
+			continue
+		}
+		var declaration = cssDeclaration{
+			property: strings.ToLower(strings.TrimSpace(text[:colon])),
+			value:    strings.TrimSpace(text[colon+1:]),
+		}
+		if i := strings.LastIndex(strings.ToLower(declaration.value), "!important"); i >= 0 {
+			declaration.value, declaration.important = strings.TrimSpace(declaration.value[:i]), true
+		}
+		if declaration.property != "" && declaration.value != "" {
+			declarations = append(declarations, declaration)
+		}
+	}
+	return declarations
+}
+
+// splitDeclarations splits a declaration block on the semicolons outside of quotes and parentheses, e.g. data URLs.
+func splitDeclarations(block string) []string {
+	var declarations []string
+	var quote byte
+	var depth, start int
+	for i := 0; i < len(block); i++ {
+		switch c := block[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ';' && depth == 0:
+			declarations = append(declarations, block[start:i])
+			start = i + 1
+		}
+	}
+	return append(declarations, block[start:])
+}
@@ -0,0 +1,94 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+)
+
+// MailgunSender is a Sender delivering emails through the Messages API of Mailgun.
+type MailgunSender struct {
+	from    EmailAddress
+	domain  string
+	apiKey  string
+	options HTTPSenderOptions
+}
+
+// NewMailgunSender returns a Sender using the sending domain and the API key of a Mailgun account, emailAddress is the
+// sender of the emails, only its address and user name are used.
+// Accounts of the EU region must set HTTPSenderOptions.Endpoint to https://api.eu.mailgun.net.
+func NewMailgunSender(emailAddress EmailAddress, domain, apiKey string, options HTTPSenderOptions) *MailgunSender {
+	return &MailgunSender{from: emailAddress, domain: domain, apiKey: apiKey, options: options}
+}
+
+// Send delivers the email.
+func (s *MailgunSender) Send(ctx context.Context, email *Email) (*SendResult, error) {
+	message, err := email.apiMessage(s.from)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fields := []struct{ name, value string }{
+		{"from", message.from.String()},
+		{"to", formatAddresses(message.to)},
+		{"cc", formatAddresses(message.cc)},
+		{"bcc", formatAddresses(message.bcc)},
+		{"subject", message.subject},
+		{"text", message.text},
+		{"html", message.html},
+		{"h:Reply-To", formatAddresses(message.replyTo)},
+	}
+	for _, header := range message.headers {
+		fields = append(fields, struct{ name, value string }{"h:" + header.name, header.value})
+	}
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		if err := w.WriteField(field.name, field.value); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range message.attachments {
+		// Inline attachments are referenced by their file name: cid:<filename>.
+		name, filename := "attachment", a.filename
+		if a.inline {
+			name, filename = "inline", a.contentID
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Disposition": {`form-data; name="` + name + `"; filename="` + escapeQuotes(filename) + `"`},
+			"Content-Type":        {a.contentType},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(a.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	endpoint := s.options.endpoint("https://api.mailgun.net") + "/v3/" + url.PathEscape(s.domain) + "/messages"
+	req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth("api", s.apiKey)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	var response struct {
+		ID string `json:"id"`
+	}
+	status, _, err := doAPIRequest(ctx, s.options.httpClient(), req, "Mailgun", &response)
+	if err != nil {
+		return apiResult(message, "", status, err), err
+	}
+	return apiResult(message, response.ID, status, nil), nil
+}
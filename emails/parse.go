@@ -0,0 +1,192 @@
+package emails
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse reads a raw RFC 5322 message, e.g. a reply or a bounce received by an inbound mailbox, into an Email.
+//
+// The first text/plain and text/html parts are the Text and HTML of the email, the other parts are Attachments.
+// Inline parts and the parts of a multipart/related entity are HTMLRelated. Quoted-printable and base64 contents
+// and RFC 2047 encoded words of the headers are decoded. Headers without an Email field, like From or Message-Id,
+// are kept in Headers.
+func Parse(r io.Reader) (*Email, error) {
+	e := NewEmail()
+	tp := textproto.NewReader(bufio.NewReader(&trimReader{rd: r}))
+	headers, err := tp.ReadMIMEHeader()
+	if err != nil && !(err == io.EOF && len(headers) > 0) {
+		return nil, err
+	}
+
+	parts, err := parseMIMEParts(headers, tp.R, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parts {
+		e.addPart(p)
+	}
+
+	for field, values := range headers {
+		switch field {
+		case "Subject":
+			e.Subject = decodeHeader(values[0])
+		case "To":
+			e.To = handleAddressList(values)
+		case "Cc":
+			e.Cc = handleAddressList(values)
+		case "Bcc":
+			e.Bcc = handleAddressList(values)
+		case "Reply-To":
+			e.ReplyTo = handleAddressList(values)
+		case "Content-Type", "Content-Transfer-Encoding":
+			// Describes the MIME structure, which is parsed into the Email.
+		default:
+			for _, value := range values {
+				e.Headers.Add(field, decodeHeader(value))
+			}
+		}
+	}
+	return e, nil
+}
+
+// parseMIMEParts returns the leaf parts of a MIME entity, multipart entities are parsed recursively.
+func parseMIMEParts(header textproto.MIMEHeader, body io.Reader, related bool) ([]*part, error) {
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", defaultContentType)
+	}
+	contentType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil && !strings.HasPrefix(contentType, "multipart/") {
+		// A malformed leaf content type is kept as is, its content can still be read.
+		contentType = "application/octet-stream"
+	}
+
+	if !strings.HasPrefix(contentType, "multipart/") {
+		switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+		case "quoted-printable":
+			body = quotedprintable.NewReader(body)
+		case "base64":
+			body = base64.NewDecoder(base64.StdEncoding, &base64Cleaner{rd: body})
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, body); err != nil {
+			return nil, err
+		}
+		return []*part{{header: header, body: buf.Bytes(), related: related}}, nil
+	}
+
+	if params["boundary"] == "" {
+		return nil, ErrMissingBoundary
+	}
+	var parts []*part
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// NextPart decodes quoted-printable parts and removes their Content-Transfer-Encoding.
+		subParts, err := parseMIMEParts(p.Header, p, related || contentType == "multipart/related")
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, subParts...)
+	}
+	return parts, nil
+}
+
+// addPart adds a leaf part to the email: as its Text or HTML if it is the first text/plain or text/html part, as an
+// Attachment otherwise.
+func (e *Email) addPart(p *part) {
+	contentType, params, err := mime.ParseMediaType(p.header.Get("Content-Type"))
+	if err != nil {
+		contentType = "application/octet-stream"
+	}
+	disposition, dispositionParams, _ := mime.ParseMediaType(p.header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	filename = decodeHeader(filename)
+
+	if disposition != "attachment" && filename == "" {
+		switch {
+		case contentType == "text/plain" && e.Text == nil:
+			e.Text = toUTF8(p.body, params["charset"])
+			return
+		case contentType == "text/html" && e.HTML == nil:
+			e.HTML = toUTF8(p.body, params["charset"])
+			return
+		}
+	}
+
+	var attachment = &Attachment{
+		Filename:    filename,
+		ContentType: p.header.Get("Content-Type"),
+		Header:      textproto.MIMEHeader{},
+		Content:     p.body,
+		HTMLRelated: disposition == "inline" || p.related && disposition != "attachment",
+	}
+	// The Content-ID references the part from the HTML body, the other headers are generated by ToBytes.
+	if id := p.header.Get("Content-ID"); id != "" {
+		attachment.Header.Set("Content-ID", id)
+	}
+	e.Attachments = append(e.Attachments, attachment)
+}
+
+// decodeHeader decodes the RFC 2047 encoded words of a header value. The value is kept as is if it is malformed or
+// uses a charset other than UTF-8, US-ASCII and ISO-8859-1.
+func decodeHeader(value string) string {
+	decoded, err := (&mime.WordDecoder{}).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// toUTF8 converts a Latin-1 text to UTF-8, texts in other charsets are returned as is.
+func toUTF8(text []byte, charset string) []byte {
+	if charset = strings.ToLower(charset); charset != "iso-8859-1" && charset != "latin1" {
+		return text
+	}
+	var converted = make([]byte, 0, len(text)*2)
+	var buf [utf8.UTFMax]byte
+	for _, b := range text {
+		n := utf8.EncodeRune(buf[:], rune(b))
+		converted = append(converted, buf[:n]...)
+	}
+	return converted
+}
+
+// base64Cleaner drops the characters that are not part of the base64 alphabet, like the spaces added by some mailers.
+type base64Cleaner struct {
+	rd io.Reader
+}
+
+// Read reads the base64 characters of the originating reader.
+func (c *base64Cleaner) Read(buf []byte) (int, error) {
+	for {
+		n, err := c.rd.Read(buf)
+		var kept int
+		for _, b := range buf[:n] {
+			if b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '+' || b == '/' || b == '=' {
+				buf[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
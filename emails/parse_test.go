@@ -0,0 +1,122 @@
+package emails
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseToBytes(t *testing.T) {
+	email := newTestEmail("Sam <sam@example.com>", "alex@example.com")
+	email.Cc = []string{"cc@example.com"}
+	email.ReplyTo = []string{"support@example.com"}
+	email.Subject = "Votre vidéo a été approuvée"
+	email.Text = []byte("Bonjour,\r\nVotre vidéo a été approuvée. Une très longue ligne qui doit être coupée par l'encodage quoted-printable.")
+	email.HTML = []byte(`<p>Votre vidéo a été approuvée.</p><img src="cid:logo.png">`)
+	email.Headers.Set("X-Campaign", "approvals")
+	email.Attach(strings.NewReader("report"), "report.txt", "text/plain")
+	logo, _ := email.Attach(bytes.NewReader([]byte{0x89, 'P', 'N', 'G', 0, 0xff}), "logo.png", "image/png")
+	logo.HTMLRelated = true
+
+	raw, err := email.ToBytes(NewEmailAddress("noreply@example.com", "Tested4you", "", "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Subject != email.Subject || !bytes.Equal(parsed.Text, email.Text) || !bytes.Equal(parsed.HTML, email.HTML) {
+		t.Errorf("got subject %q, text %q and HTML %q", parsed.Subject, parsed.Text, parsed.HTML)
+	}
+	if strings.Join(parsed.To, ",") != `"Sam" <sam@example.com>,<alex@example.com>` || strings.Join(parsed.Cc, ",") != "<cc@example.com>" ||
+		strings.Join(parsed.ReplyTo, ",") != "support@example.com" {
+		t.Errorf("got to %q, cc %q and reply to %q", parsed.To, parsed.Cc, parsed.ReplyTo)
+	}
+	if parsed.Headers.Get("X-Campaign") != "approvals" || parsed.Headers.Get("From") != `"Tested4you" <noreply@example.com>` ||
+		parsed.Headers.Get("Message-Id") == "" || parsed.Headers.Get("Content-Type") != "" {
+		t.Errorf("unexpected headers %v", parsed.Headers)
+	}
+
+	if len(parsed.Attachments) != 2 {
+		t.Fatalf("expected 2 attachments, got %d", len(parsed.Attachments))
+	}
+	// The related parts precede the other attachments in the message.
+	image, report := parsed.Attachments[0], parsed.Attachments[1]
+	if image.Filename != "logo.png" || !image.HTMLRelated || image.Header.Get("Content-ID") != "<logo.png>" ||
+		!bytes.Equal(image.Content, logo.Content) || !strings.HasPrefix(image.ContentType, "image/png") {
+		t.Errorf("unexpected inline attachment %+v", image)
+	}
+	if report.Filename != "report.txt" || report.HTMLRelated || string(report.Content) != "report" {
+		t.Errorf("unexpected attachment %+v", report)
+	}
+}
+
+func TestParseBounce(t *testing.T) {
+	message := strings.Replace(`
+From: Mail Delivery System <MAILER-DAEMON@mx.example.com>
+To: noreply@example.com
+Subject: =?ISO-8859-1?Q?Non_remis_=E0?= sam@example.com
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=delivery-status;
+	boundary="report"
+
+--report
+Content-Type: text/plain; charset=ISO-8859-1
+Content-Transfer-Encoding: base64
+
+TGUgbWVzc2FnZSBuJ2EgcGFzIOl06SByZW1pcy4=
+
+--report
+Content-Type: message/delivery-status
+
+Final-Recipient: rfc822; sam@example.com
+Action: failed
+Status: 5.1.1
+
+--report
+Content-Type: application/pdf; name="=?UTF-8?B?cmFwcG9ydC3DqXTDqS5wZGY=?="
+Content-Disposition: attachment
+Content-Transfer-Encoding: base64
+
+JVBERi0x
+LjQK
+--report--
+`, "\n", "\r\n", -1)
+
+	parsed, err := Parse(strings.NewReader(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Subject != "Non remis à sam@example.com" || parsed.Headers.Get("From") != "Mail Delivery System <MAILER-DAEMON@mx.example.com>" {
+		t.Errorf("got subject %q and from %q", parsed.Subject, parsed.Headers.Get("From"))
+	}
+	if string(parsed.Text) != "Le message n'a pas été remis." {
+		t.Errorf("got text %q", parsed.Text)
+	}
+	if len(parsed.Attachments) != 2 {
+		t.Fatalf("expected 2 attachments, got %d", len(parsed.Attachments))
+	}
+	status, pdf := parsed.Attachments[0], parsed.Attachments[1]
+	if status.ContentType != "message/delivery-status" || !strings.Contains(string(status.Content), "Status: 5.1.1") || status.HTMLRelated {
+		t.Errorf("unexpected delivery status %+v", status)
+	}
+	if pdf.Filename != "rapport-été.pdf" || string(pdf.Content) != "%PDF-1.4\n" {
+		t.Errorf("unexpected attachment %q %q", pdf.Filename, pdf.Content)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, message := range map[string]string{
+		"missing boundary": "Content-Type: multipart/mixed\r\n\r\nbody",
+		"no header":        "",
+	} {
+		if _, err := Parse(strings.NewReader(message)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := Parse(strings.NewReader("Content-Type: multipart/mixed\r\n\r\nbody")); err != ErrMissingBoundary {
+		t.Errorf("expected ErrMissingBoundary, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// PostmarkSender is a Sender delivering emails through the Email API of Postmark.
+type PostmarkSender struct {
+	from          EmailAddress
+	serverToken   string
+	messageStream string
+	options       HTTPSenderOptions
+}
+
+// postmarkMessage is the body of a Postmark Email request.
+type postmarkMessage struct {
+	From          string
+	To            string
+	Cc            string               `json:",omitempty"`
+	Bcc           string               `json:",omitempty"`
+	ReplyTo       string               `json:",omitempty"`
+	Subject       string               `json:",omitempty"`
+	TextBody      string               `json:",omitempty"`
+	HtmlBody      string               `json:",omitempty"`
+	Headers       []postmarkHeader     `json:",omitempty"`
+	Attachments   []postmarkAttachment `json:",omitempty"`
+	MessageStream string               `json:",omitempty"`
+}
+
+type postmarkHeader struct {
+	Name  string
+	Value string
+}
+
+type postmarkAttachment struct {
+	Name        string
+	Content     string
+	ContentType string
+	ContentID   string `json:",omitempty"`
+}
+
+// NewPostmarkSender returns a Sender using the token of a Postmark server, emailAddress is the sender of the emails,
+// only its address and user name are used. messageStream is the ID of the message stream, "outbound" if empty.
+func NewPostmarkSender(emailAddress EmailAddress, serverToken, messageStream string, options HTTPSenderOptions) *PostmarkSender {
+	return &PostmarkSender{from: emailAddress, serverToken: serverToken, messageStream: messageStream, options: options}
+}
+
+// Send delivers the email.
+func (s *PostmarkSender) Send(ctx context.Context, email *Email) (*SendResult, error) {
+	message, err := email.apiMessage(s.from)
+	if err != nil {
+		return nil, err
+	}
+
+	var request = postmarkMessage{
+		From:          message.from.String(),
+		To:            formatAddresses(message.to),
+		Cc:            formatAddresses(message.cc),
+		Bcc:           formatAddresses(message.bcc),
+		ReplyTo:       formatAddresses(message.replyTo),
+		Subject:       message.subject,
+		TextBody:      message.text,
+		HtmlBody:      message.html,
+		MessageStream: s.messageStream,
+	}
+	for _, header := range message.headers {
+		request.Headers = append(request.Headers, postmarkHeader{Name: header.name, Value: header.value})
+	}
+	for _, a := range message.attachments {
+		var attachment = postmarkAttachment{Name: a.filename, Content: base64.StdEncoding.EncodeToString(a.content), ContentType: a.contentType}
+		if a.inline {
+			attachment.ContentID = "cid:" + a.contentID
+		}
+		request.Attachments = append(request.Attachments, attachment)
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.options.endpoint("https://api.postmarkapp.com")+"/email", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Postmark-Server-Token", s.serverToken)
+
+	var response struct {
+		ErrorCode int
+		Message   string
+		MessageID string
+	}
+	status, _, err := doAPIRequest(ctx, s.options.httpClient(), req, "Postmark", &response)
+	if err == nil && response.ErrorCode != 0 {
+		err = &APIError{Provider: "Postmark", StatusCode: status, Message: fmt.Sprintf("%s (error code %d)", response.Message, response.ErrorCode)}
+	}
+	if err != nil {
+		return apiResult(message, "", status, err), err
+	}
+	return apiResult(message, response.MessageID, status, nil), nil
+}
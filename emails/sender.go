@@ -0,0 +1,89 @@
+package emails
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/mail"
+	"net/textproto"
+)
+
+// Sender delivers Emails, e.g through SMTP or the HTTP API of an email provider.
+// Implementations must be safe for concurrent use.
+type Sender interface {
+	// Send delivers the email to its To, Cc and Bcc recipients.
+	// An error is returned if the email was not sent at all, recipients rejected individually are reported in the SendResult.
+	Send(ctx context.Context, email *Email) (*SendResult, error)
+}
+
+// Failed returns the results of the recipients the email was not delivered to.
+func (r *SendResult) Failed() []RecipientResult {
+	var failed []RecipientResult
+	for _, recipient := range r.Recipients {
+		if !recipient.Accepted {
+			failed = append(failed, recipient)
+		}
+	}
+	return failed
+}
+
+// Err returns the error of the first rejected recipient, nil if every recipient accepted the email.
+func (r *SendResult) Err() error {
+	for _, recipient := range r.Recipients {
+		if !recipient.Accepted {
+			return recipient.Err
+		}
+	}
+	return nil
+}
+
+// recipients returns the addresses of the To, Cc and Bcc recipients.
+func (e *Email) recipients() ([]string, error) {
+	to := make([]string, 0, len(e.To)+len(e.Cc)+len(e.Bcc))
+	to = append(append(append(to, e.To...), e.Cc...), e.Bcc...)
+	for i := 0; i < len(to); i++ {
+		addr, err := mail.ParseAddress(to[i])
+		if err != nil {
+			return nil, err
+		}
+		to[i] = addr.Address
+	}
+	if len(to) == 0 {
+		return nil, ErrReceiversMustBeSpecified
+	}
+	return to, nil
+}
+
+// envelopeSender returns the SMTP envelope sender: Email.Sender if set, or the sending address.
+func (e *Email) envelopeSender(emailAddress EmailAddress) (string, error) {
+	if e.Sender != "" {
+		sender, err := mail.ParseAddress(e.Sender)
+		if err != nil {
+			return "", err
+		}
+		return sender.Address, nil
+	}
+	if emailAddress.from() == "" {
+		return "", ErrSenderMustBeSpecified
+	}
+	return emailAddress.parseSender()
+}
+
+// messageID returns the Message-Id header of a message built by ToBytes.
+func messageID(raw []byte) string {
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw))).ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return ""
+	}
+	return header.Get("Message-Id")
+}
+
+// replyCode returns the SMTP reply code of err, 0 if err is not an SMTP reply.
+func replyCode(err error) int {
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) {
+		return protocolErr.Code
+	}
+	return 0
+}
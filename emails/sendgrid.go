@@ -0,0 +1,142 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+)
+
+// SendGridSender is a Sender delivering emails through the v3 Mail Send API of SendGrid.
+type SendGridSender struct {
+	from    EmailAddress
+	apiKey  string
+	options HTTPSenderOptions
+}
+
+// sendGridAddress is an address of a SendGrid request.
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// sendGridMessage is the body of a SendGrid Mail Send request.
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	ReplyToList      []sendGridAddress         `json:"reply_to_list,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	Cc  []sendGridAddress `json:"cc,omitempty"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+// NewSendGridSender returns a Sender using a SendGrid API key with the "Mail Send" permission, emailAddress is the sender
+// of the emails, only its address and user name are used.
+func NewSendGridSender(emailAddress EmailAddress, apiKey string, options HTTPSenderOptions) *SendGridSender {
+	return &SendGridSender{from: emailAddress, apiKey: apiKey, options: options}
+}
+
+// Send delivers the email. SendGrid requires a To recipient, an email only sent to Cc or Bcc recipients returns
+// ErrToMustBeSpecified: moving one of them to To would disclose it to the others.
+func (s *SendGridSender) Send(ctx context.Context, email *Email) (*SendResult, error) {
+	message, err := email.apiMessage(s.from)
+	if err != nil {
+		return nil, err
+	}
+	if len(message.to) == 0 {
+		return nil, fmt.Errorf("emails.SendGridSender.Send: %w", ErrToMustBeSpecified)
+	}
+
+	var request = sendGridMessage{
+		Personalizations: []sendGridPersonalization{{
+			To:  sendGridAddresses(message.to),
+			Cc:  sendGridAddresses(message.cc),
+			Bcc: sendGridAddresses(message.bcc),
+		}},
+		From:    sendGridAddresses([]*mail.Address{message.from})[0],
+		Subject: message.subject,
+	}
+	if replyTo := sendGridAddresses(message.replyTo); len(replyTo) == 1 {
+		request.ReplyTo = &replyTo[0]
+	} else {
+		request.ReplyToList = replyTo
+	}
+	// SendGrid requires the plaintext content to be first.
+	if message.text != "" {
+		request.Content = append(request.Content, sendGridContent{Type: "text/plain", Value: message.text})
+	}
+	if message.html != "" {
+		request.Content = append(request.Content, sendGridContent{Type: "text/html", Value: message.html})
+	}
+	for _, a := range message.attachments {
+		var attachment = sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(a.content),
+			Type:        a.contentType,
+			Filename:    a.filename,
+			Disposition: "attachment",
+		}
+		if a.inline {
+			attachment.Disposition, attachment.ContentID = "inline", a.contentID
+		}
+		request.Attachments = append(request.Attachments, attachment)
+	}
+	if len(message.headers) > 0 {
+		request.Headers = make(map[string]string, len(message.headers))
+		for _, header := range message.headers {
+			request.Headers[header.name] = header.value
+		}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.options.endpoint("https://api.sendgrid.com")+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	status, header, err := doAPIRequest(ctx, s.options.httpClient(), req, "SendGrid", nil)
+	if err != nil {
+		return apiResult(message, "", status, err), err
+	}
+	return apiResult(message, header.Get("X-Message-Id"), status, nil), nil
+}
+
+// sendGridAddresses returns the addresses of a SendGrid request.
+func sendGridAddresses(addresses []*mail.Address) []sendGridAddress {
+	if len(addresses) == 0 {
+		return nil
+	}
+	var converted = make([]sendGridAddress, len(addresses))
+	for i, address := range addresses {
+		converted[i] = sendGridAddress{Email: address.Address, Name: address.Name}
+	}
+	return converted
+}
@@ -0,0 +1,181 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SESSender is a Sender delivering emails through the SendEmail action of the Amazon SES v2 API.
+// Emails are sent as raw MIME messages built by Email.ToBytes, so they keep every header and attachment.
+type SESSender struct {
+	from        EmailAddress
+	region      string
+	credentials SESCredentials
+	options     HTTPSenderOptions
+}
+
+// sesMessage is the body of an SES SendEmail request.
+type sesMessage struct {
+	FromEmailAddress string
+	Destination      sesDestination
+	Content          sesContent
+}
+
+type sesDestination struct {
+	ToAddresses  []string `json:",omitempty"`
+	CcAddresses  []string `json:",omitempty"`
+	BccAddresses []string `json:",omitempty"`
+}
+
+type sesContent struct {
+	Raw struct {
+		Data []byte // encoded in base64 by encoding/json
+	}
+}
+
+// NewSESSender returns a Sender using the SES API of an AWS region, e.g. "eu-west-1". The credentials must allow
+// ses:SendEmail, emailAddress is the sender of the emails, only its address and user name are used.
+func NewSESSender(emailAddress EmailAddress, region string, credentials SESCredentials, options HTTPSenderOptions) *SESSender {
+	return &SESSender{from: emailAddress, region: region, credentials: credentials, options: options}
+}
+
+// Send delivers the email.
+func (s *SESSender) Send(ctx context.Context, email *Email) (*SendResult, error) {
+	message, err := email.apiMessage(s.from)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := email.ToBytes(s.from)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bcc recipients are only in the destination, ToBytes does not write them in the headers.
+	var request = sesMessage{
+		FromEmailAddress: message.from.String(),
+		Destination: sesDestination{
+			ToAddresses:  sesAddresses(message.to),
+			CcAddresses:  sesAddresses(message.cc),
+			BccAddresses: sesAddresses(message.bcc),
+		},
+	}
+	request.Content.Raw.Data = raw
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := s.options.endpoint("https://email." + s.region + ".amazonaws.com")
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signAWSRequest(req, body, s.region, "ses", s.credentials, time.Now())
+
+	var response struct {
+		MessageId string
+	}
+	status, _, err := doAPIRequest(ctx, s.options.httpClient(), req, "SES", &response)
+	if err != nil {
+		return apiResult(message, "", status, err), err
+	}
+	return apiResult(message, response.MessageId, status, nil), nil
+}
+
+// sesAddresses returns the addresses of an SES request.
+func sesAddresses(addresses []*mail.Address) []string {
+	var converted []string
+	for _, address := range addresses {
+		converted = append(converted, address.String())
+	}
+	return converted
+}
+
+// signAWSRequest signs req with AWS Signature Version 4, body is the payload of req.
+// Every header set on req is signed, they must not be modified afterwards.
+func signAWSRequest(req *http.Request, body []byte, region, service string, credentials SESCredentials, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	var headers = map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	var names = make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.Join(strings.Fields(headers[name]), " ") + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalAWSQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := amzDate[:8] + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{amzDate[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(hmacSHA256(key, stringToSign)))
+}
+
+// canonicalAWSQuery returns the query parameters sorted and encoded as AWS Signature Version 4 expects.
+func canonicalAWSQuery(query url.Values) string {
+	var parameters []string
+	for name, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, awsEscape(name)+"="+awsEscape(value))
+		}
+	}
+	sort.Strings(parameters)
+	return strings.Join(parameters, "&")
+}
+
+// awsEscape percent-encodes every byte but the unreserved characters of RFC 3986.
+func awsEscape(s string) string {
+	return strings.Replace(strings.Replace(url.QueryEscape(s), "+", "%20", -1), "%7E", "~", -1)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
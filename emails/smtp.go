@@ -0,0 +1,409 @@
+package emails
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPSender is a Sender delivering emails through an SMTP server.
+// Authenticated connections are pooled and reused between messages, call Close to release them.
+type SMTPSender struct {
+	from    EmailAddress
+	options SMTPOptions
+	slots   chan struct{} // holds a value for every open connection
+
+	mu     sync.Mutex
+	idle   []*smtpConn
+	closed bool
+}
+
+// smtpConn is a pooled SMTP connection.
+type smtpConn struct {
+	conn     net.Conn // underlying connection, used to apply the context deadlines
+	client   *smtp.Client
+	messages int
+	lastUsed time.Time
+}
+
+// NewSMTPSender returns a Sender using the server and credentials of emailAddress, the address is the sender of the emails.
+func NewSMTPSender(emailAddress EmailAddress, options SMTPOptions) *SMTPSender {
+	if options.LocalName == "" {
+		options.LocalName = "localhost"
+	}
+	if options.MaxConnections <= 0 {
+		options.MaxConnections = 4
+	}
+	if options.MaxMessagesPerConnection <= 0 {
+		options.MaxMessagesPerConnection = 100
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = 30 * time.Second
+	}
+	if options.Username == "" {
+		options.Username = emailAddress.address
+	}
+	return &SMTPSender{
+		from:    emailAddress,
+		options: options,
+		slots:   make(chan struct{}, options.MaxConnections),
+	}
+}
+
+// Send delivers the email. Recipients are sent one by one to the server, rejected recipients are reported in the
+// SendResult and the email is delivered to the others. An error is returned if no recipient accepted the email.
+//
+// ctx deadline applies to the whole exchange with the server, including waiting for a free connection.
+func (s *SMTPSender) Send(ctx context.Context, email *Email) (*SendResult, error) {
+	recipients, err := email.recipients()
+	if err != nil {
+		return nil, err
+	}
+	envelopeSender, err := email.envelopeSender(s.from)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := email.ToBytes(s.from)
+	if err != nil {
+		return nil, err
+	}
+	if s.options.DKIM != nil {
+		if raw, err = SignDKIM(raw, *s.options.DKIM); err != nil {
+			return nil, err
+		}
+	}
+
+	var result = SendResult{MessageID: messageID(raw)}
+	c, err := s.acquire(ctx)
+	if err != nil {
+		return &result, err
+	}
+
+	stop := watchContext(ctx, c.conn)
+	err = c.send(envelopeSender, recipients, raw, &result)
+	stop()
+	// Rejections are replies of the server, the connection can still be used.
+	s.release(c, err == nil || replyCode(err) != 0)
+
+	if err != nil {
+		return &result, fmt.Errorf("emails.SMTPSender.Send: %w", contextError(ctx, err))
+	}
+	return &result, nil
+}
+
+// Close closes the idle connections, connections in use are closed once their message is sent.
+func (s *SMTPSender) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	idle := s.idle
+	s.idle = nil
+	s.mu.Unlock()
+
+	for _, c := range idle {
+		c.quit()
+	}
+	return nil
+}
+
+// send delivers raw to the recipients accepted by the server and records their results.
+func (c *smtpConn) send(envelopeSender string, recipients []string, raw []byte, result *SendResult) error {
+	fail := func(err error) error {
+		for _, recipient := range recipients {
+			result.Recipients = append(result.Recipients, RecipientResult{Address: recipient, Code: replyCode(err), Err: err})
+		}
+		return err
+	}
+	if err := c.client.Mail(envelopeSender); err != nil {
+		return fail(err)
+	}
+
+	var accepted int
+	var firstErr error
+	for _, recipient := range recipients {
+		var r = RecipientResult{Address: recipient, Accepted: true}
+		if err := c.client.Rcpt(recipient); err != nil {
+			r = RecipientResult{Address: recipient, Code: replyCode(err), Err: err}
+			if replyCode(err) == 0 {
+				// The connection is broken, the email is not sent to any recipient.
+				result.Recipients = result.Recipients[:0]
+				return fail(err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			accepted++
+		}
+		result.Recipients = append(result.Recipients, r)
+	}
+	if accepted == 0 {
+		return fmt.Errorf("all recipients were rejected: %w", firstErr)
+	}
+
+	err := c.writeData(raw)
+	if err != nil {
+		for i := range result.Recipients {
+			if result.Recipients[i].Accepted {
+				result.Recipients[i] = RecipientResult{Address: result.Recipients[i].Address, Code: replyCode(err), Err: err}
+			}
+		}
+		return err
+	}
+	c.messages++
+	return nil
+}
+
+// writeData sends the message content.
+func (c *smtpConn) writeData(raw []byte) error {
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// quit closes the connection politely.
+func (c *smtpConn) quit() {
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := c.client.Quit(); err != nil {
+		c.client.Close()
+	}
+}
+
+// acquire returns an idle connection, or opens a new one if none can be reused.
+func (s *SMTPSender) acquire(ctx context.Context) (*smtpConn, error) {
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("emails.SMTPSender.Send: waiting for a connection: %w", ctx.Err())
+	}
+
+	for {
+		s.mu.Lock()
+		if len(s.idle) == 0 {
+			s.mu.Unlock()
+			break
+		}
+		c := s.idle[len(s.idle)-1]
+		s.idle = s.idle[:len(s.idle)-1]
+		s.mu.Unlock()
+
+		if time.Since(c.lastUsed) > s.options.IdleTimeout {
+			c.quit()
+			continue
+		}
+		// The server may have closed the connection in the meantime.
+		stop := watchContext(ctx, c.conn)
+		err := c.client.Noop()
+		stop()
+		if err == nil {
+			return c, nil
+		}
+		c.client.Close()
+	}
+
+	c, err := s.dial(ctx)
+	if err != nil {
+		<-s.slots
+		return nil, fmt.Errorf("emails.SMTPSender.Send: %w", err)
+	}
+	return c, nil
+}
+
+// release returns the connection to the pool if it can be reused, or closes it.
+func (s *SMTPSender) release(c *smtpConn, reusable bool) {
+	defer func() { <-s.slots }()
+
+	if reusable && c.messages < s.options.MaxMessagesPerConnection {
+		// Clears the transaction of a failed message.
+		c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+		err := c.client.Reset()
+		c.conn.SetDeadline(time.Time{})
+		if err == nil {
+			s.mu.Lock()
+			if !s.closed {
+				c.lastUsed = time.Now()
+				s.idle = append(s.idle, c)
+				s.mu.Unlock()
+				return
+			}
+			s.mu.Unlock()
+		}
+	}
+	c.quit()
+}
+
+// dial opens a new connection, secures it and authenticates.
+func (s *SMTPSender) dial(ctx context.Context) (*smtpConn, error) {
+	var dialer net.Dialer
+	var conn net.Conn
+	var err error
+	if s.options.TLSMode == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: &dialer, Config: s.tlsConfig()}).DialContext(ctx, "tcp", s.from.addr())
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.from.addr())
+	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	stop := watchContext(ctx, conn)
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.from.host)
+	if err != nil {
+		conn.Close()
+		return nil, contextError(ctx, err)
+	}
+	if err := s.handshake(ctx, client); err != nil {
+		client.Close()
+		return nil, contextError(ctx, err)
+	}
+	return &smtpConn{conn: conn, client: client}, nil
+}
+
+// handshake greets the server, upgrades the connection to TLS and authenticates according to the options.
+func (s *SMTPSender) handshake(ctx context.Context, client *smtp.Client) error {
+	if err := client.Hello(s.options.LocalName); err != nil {
+		return err
+	}
+
+	if s.options.TLSMode == TLSOpportunistic || s.options.TLSMode == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(s.tlsConfig()); err != nil {
+				return err
+			}
+		} else if s.options.TLSMode == TLSStartTLS {
+			return ErrStartTLSUnsupported
+		}
+	}
+
+	auth, err := s.auth(ctx, client)
+	if err != nil || auth == nil {
+		return err
+	}
+	return client.Auth(auth)
+}
+
+// auth returns the smtp.Auth of the configured mechanism, nil if no authentication is needed.
+func (s *SMTPSender) auth(ctx context.Context, client *smtp.Client) (smtp.Auth, error) {
+	mechanism := s.options.Auth
+	if mechanism == AuthNone {
+		return nil, nil
+	}
+	ok, advertised := client.Extension("AUTH")
+	if !ok {
+		if mechanism == AuthAuto {
+			return nil, nil
+		}
+		return nil, ErrAuthUnsupported
+	}
+
+	if mechanism == AuthAuto {
+		if s.options.OAuth2Token != nil {
+			mechanism = AuthXOAUTH2
+		} else if s.from.password == "" {
+			return nil, nil
+		} else {
+			supported := strings.Fields(strings.ToUpper(advertised))
+			for _, candidate := range []AuthMechanism{AuthCRAMMD5, AuthPlain, AuthLogin} {
+				if containsString(supported, string(candidate)) {
+					mechanism = candidate
+					break
+				}
+			}
+			if mechanism == AuthAuto {
+				return nil, fmt.Errorf("%w: no supported mechanism in %q", ErrAuthUnsupported, advertised)
+			}
+		}
+	}
+
+	host, username, password := s.from.host, s.options.Username, s.from.password
+	switch mechanism {
+	case AuthPlain:
+		return smtp.PlainAuth("", username, password, host), nil
+	case AuthLogin:
+		return LoginAuth(username, password, host), nil
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(username, password), nil
+	case AuthXOAUTH2:
+		if s.options.OAuth2Token == nil {
+			return nil, fmt.Errorf("XOAUTH2 requires SMTPOptions.OAuth2Token")
+		}
+		token, err := s.options.OAuth2Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("OAuth2 token: %w", err)
+		}
+		return XOAuth2Auth(username, token, host), nil
+	}
+	return nil, fmt.Errorf("unknown auth mechanism %q", mechanism)
+}
+
+// tlsConfig returns the TLS configuration of the connections.
+func (s *SMTPSender) tlsConfig() *tls.Config {
+	var config = &tls.Config{}
+	if s.options.TLSConfig != nil {
+		config = s.options.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = s.from.host
+	}
+	return config
+}
+
+// watchContext applies the deadline of ctx to conn and interrupts it as soon as ctx is done.
+// The returned function must be called once the exchange is over, it removes the deadline.
+func watchContext(ctx context.Context, conn net.Conn) (stop func()) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			// A deadline in the past unblocks pending reads and writes.
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+		conn.SetDeadline(time.Time{})
+	}
+}
+
+// contextError returns the error of ctx if err was caused by ctx being done, err otherwise.
+// Connections deadlines are those of ctx, they can expire before ctx reports it.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	var netErr net.Error
+	if _, ok := ctx.Deadline(); ok && errors.As(err, &netErr) && netErr.Timeout() {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// containsString returns true if values contains value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,392 @@
+package emails
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPMessage is a message received by a fakeSMTPServer.
+type fakeSMTPMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer is a minimal SMTP server accepting every recipient except those containing "reject" (550) or "busy" (450).
+// The password of every user is "secret".
+type fakeSMTPServer struct {
+	listener       net.Listener
+	startTLS       *tls.Config // advertises STARTTLS if set
+	authMechanisms []string
+	stall          bool // never answers
+
+	mu          sync.Mutex
+	connections int
+	auths       []string
+	messages    []fakeSMTPMessage
+}
+
+// start listens on a random local port, with TLS from the first byte if implicitTLS is set.
+func (s *fakeSMTPServer) start(t *testing.T, implicitTLS *tls.Config) *fakeSMTPServer {
+	t.Helper()
+	var listener net.Listener
+	var err error
+	if implicitTLS != nil {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", implicitTLS)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.listener = listener
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.connections++
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// emailAddress returns the address of the user "me@example.com" on the server.
+func (s *fakeSMTPServer) emailAddress() EmailAddress {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return NewEmailAddress("me@example.com", "Me", "secret", "127.0.0.1", addr.Port)
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	if s.stall {
+		conn.Read(make([]byte, 1))
+		return
+	}
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	var message fakeSMTPMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.Fields(line + " ")[0])
+		args := strings.TrimSpace(line[len(verb):])
+		switch verb {
+		case "EHLO":
+			lines := []string{"localhost"}
+			if s.startTLS != nil {
+				lines = append(lines, "STARTTLS")
+			}
+			if len(s.authMechanisms) > 0 {
+				lines = append(lines, "AUTH "+strings.Join(s.authMechanisms, " "))
+			}
+			lines = append(lines, "8BITMIME")
+			for i, l := range lines {
+				if i < len(lines)-1 {
+					tp.PrintfLine("250-%s", l)
+				} else {
+					tp.PrintfLine("250 %s", l)
+				}
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.startTLS)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			if !s.authenticate(tp, args) {
+				tp.PrintfLine("535 5.7.8 authentication failed")
+				continue
+			}
+			tp.PrintfLine("235 2.7.0 accepted")
+		case "MAIL":
+			message = fakeSMTPMessage{from: args}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			switch {
+			case strings.Contains(args, "reject"):
+				tp.PrintfLine("550 5.1.1 no such user")
+			case strings.Contains(args, "busy"):
+				tp.PrintfLine("450 4.2.1 mailbox busy")
+			default:
+				message.to = append(message.to, args)
+				tp.PrintfLine("250 OK")
+			}
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			message.data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			tp.PrintfLine("250 OK queued")
+		case "RSET":
+			message = fakeSMTPMessage{}
+			tp.PrintfLine("250 OK")
+		case "NOOP":
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// authenticate runs the AUTH exchange and records the credentials.
+func (s *fakeSMTPServer) authenticate(tp *textproto.Conn, args string) bool {
+	fields := strings.Fields(args)
+	decode := func(value string) string {
+		b, _ := base64.StdEncoding.DecodeString(value)
+		return string(b)
+	}
+	prompt := func(challenge string) string {
+		tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+		line, _ := tp.ReadLine()
+		return decode(line)
+	}
+
+	var record string
+	var ok bool
+	switch fields[0] {
+	case "PLAIN":
+		parts := strings.Split(decode(fields[1]), "\x00")
+		record, ok = "PLAIN "+parts[1], parts[2] == "secret"
+	case "LOGIN":
+		username := prompt("Username:")
+		password := prompt("Password:")
+		record, ok = "LOGIN "+username, password == "secret"
+	case "CRAM-MD5":
+		challenge := "<1896.697170952@localhost>"
+		parts := strings.Fields(prompt(challenge))
+		mac := hmac.New(md5.New, []byte("secret"))
+		mac.Write([]byte(challenge))
+		record, ok = "CRAM-MD5 "+parts[0], parts[1] == hex.EncodeToString(mac.Sum(nil))
+	case "XOAUTH2":
+		record = "XOAUTH2 " + decode(fields[1])
+		ok = strings.Contains(record, "auth=Bearer valid-token")
+		if !ok {
+			prompt(`{"status":"401"}`)
+		}
+	}
+	s.mu.Lock()
+	s.auths = append(s.auths, record)
+	s.mu.Unlock()
+	return ok
+}
+
+// newTestTLSConfigs returns a server certificate for 127.0.0.1 and a client configuration trusting it.
+func newTestTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate)
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	return server, &tls.Config{RootCAs: roots}
+}
+
+func newTestEmail(to ...string) *Email {
+	email := NewEmail()
+	email.To = to
+	email.Subject = "Your video was approved"
+	email.Text = []byte("Hello!")
+	return email
+}
+
+func TestSMTPSenderReusesConnections(t *testing.T) {
+	server := (&fakeSMTPServer{authMechanisms: []string{"LOGIN"}}).start(t, nil)
+	sender := NewSMTPSender(server.emailAddress(), SMTPOptions{Auth: AuthLogin, MaxConnections: 1})
+	defer sender.Close()
+
+	for i := 0; i < 3; i++ {
+		result, err := sender.Send(context.Background(), newTestEmail("user@example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.MessageID == "" || len(result.Recipients) != 1 || !result.Recipients[0].Accepted {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.connections != 1 || len(server.messages) != 3 {
+		t.Errorf("got %d connections and %d messages, want 1 and 3", server.connections, len(server.messages))
+	}
+	if len(server.auths) != 1 || server.auths[0] != "LOGIN me@example.com" {
+		t.Errorf("unexpected authentications %q", server.auths)
+	}
+	if !strings.Contains(server.messages[0].data, "Subject: Your video was approved") {
+		t.Errorf("unexpected message %s", server.messages[0].data)
+	}
+}
+
+func TestSMTPSenderRecipientResults(t *testing.T) {
+	server := (&fakeSMTPServer{}).start(t, nil)
+	sender := NewSMTPSender(server.emailAddress(), SMTPOptions{})
+	defer sender.Close()
+
+	email := newTestEmail("ok@example.com", "reject@example.com")
+	email.Bcc = []string{"busy@example.com"}
+	result, err := sender.Send(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		accepted bool
+		code     int
+	}{{true, 0}, {false, 550}, {false, 450}}
+	for i, r := range result.Recipients {
+		if r.Accepted != expected[i].accepted || r.Code != expected[i].code {
+			t.Errorf("recipient %s: got accepted=%v code=%d", r.Address, r.Accepted, r.Code)
+		}
+	}
+	if len(result.Failed()) != 2 || replyCode(result.Err()) != 550 {
+		t.Errorf("unexpected failures %+v", result.Failed())
+	}
+
+	// Nothing is sent if every recipient is rejected, the connection is still usable.
+	result, err = sender.Send(context.Background(), newTestEmail("reject@example.com"))
+	if err == nil || replyCode(err) != 550 {
+		t.Errorf("expected the 550 rejection, got %v", err)
+	}
+	if _, err := sender.Send(context.Background(), newTestEmail("ok@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.messages) != 2 || len(server.messages[0].to) != 1 || server.connections != 1 {
+		t.Errorf("unexpected messages %+v over %d connections", server.messages, server.connections)
+	}
+}
+
+func TestSMTPSenderAuthMechanisms(t *testing.T) {
+	server := (&fakeSMTPServer{authMechanisms: []string{"PLAIN", "LOGIN", "CRAM-MD5", "XOAUTH2"}}).start(t, nil)
+
+	auto := NewSMTPSender(server.emailAddress(), SMTPOptions{})
+	defer auto.Close()
+	if _, err := auto.Send(context.Background(), newTestEmail("user@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	token := "valid-token"
+	oauth := NewSMTPSender(server.emailAddress(), SMTPOptions{
+		Username:    "oauth@example.com",
+		OAuth2Token: func(ctx context.Context) (string, error) { return token, nil },
+	})
+	defer oauth.Close()
+	if _, err := oauth.Send(context.Background(), newTestEmail("user@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	token = "expired-token"
+	rejected := NewSMTPSender(server.emailAddress(), SMTPOptions{Auth: AuthXOAUTH2, OAuth2Token: func(ctx context.Context) (string, error) { return token, nil }})
+	defer rejected.Close()
+	if _, err := rejected.Send(context.Background(), newTestEmail("user@example.com")); replyCode(err) != 535 {
+		t.Errorf("expected the 535 authentication failure, got %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	expected := []string{
+		"CRAM-MD5 me@example.com",
+		"XOAUTH2 user=oauth@example.com\x01auth=Bearer valid-token\x01\x01",
+		"XOAUTH2 user=me@example.com\x01auth=Bearer expired-token\x01\x01",
+	}
+	if strings.Join(server.auths, "|") != strings.Join(expected, "|") {
+		t.Errorf("got  %q\nwant %q", server.auths, expected)
+	}
+}
+
+func TestSMTPSenderTLSModes(t *testing.T) {
+	serverTLS, clientTLS := newTestTLSConfigs(t)
+
+	implicit := (&fakeSMTPServer{authMechanisms: []string{"PLAIN"}}).start(t, serverTLS)
+	sender := NewSMTPSender(implicit.emailAddress(), SMTPOptions{TLSMode: TLSImplicit, TLSConfig: clientTLS, Auth: AuthPlain})
+	defer sender.Close()
+	if _, err := sender.Send(context.Background(), newTestEmail("user@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	startTLS := (&fakeSMTPServer{startTLS: serverTLS}).start(t, nil)
+	sender = NewSMTPSender(startTLS.emailAddress(), SMTPOptions{TLSMode: TLSStartTLS, TLSConfig: clientTLS})
+	defer sender.Close()
+	if _, err := sender.Send(context.Background(), newTestEmail("user@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := (&fakeSMTPServer{}).start(t, nil)
+	sender = NewSMTPSender(plain.emailAddress(), SMTPOptions{TLSMode: TLSStartTLS})
+	defer sender.Close()
+	if _, err := sender.Send(context.Background(), newTestEmail("user@example.com")); !errors.Is(err, ErrStartTLSUnsupported) {
+		t.Errorf("expected ErrStartTLSUnsupported, got %v", err)
+	}
+}
+
+func TestSMTPSenderContextDeadline(t *testing.T) {
+	server := (&fakeSMTPServer{stall: true}).start(t, nil)
+	sender := NewSMTPSender(server.emailAddress(), SMTPOptions{})
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := sender.Send(ctx, newTestEmail("user@example.com")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send returned after %v", elapsed)
+	}
+}
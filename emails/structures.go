@@ -1,7 +1,17 @@
 package emails
 
 
-import "net/textproto"
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"html/template"
+	"net/http"
+	"net/textproto"
+	"time"
+
+	"github.com/sabriboughanmi/go_utils/i18n"
+)
 
 
 //EmailAddress contains all data related to an Email address for Server Side Usage
@@ -11,6 +21,7 @@ type EmailAddress struct {
 	port     int
 	password string
 	host     string
+	dkim     *DKIMOptions // signs the emails sent by Email.Send (optional)
 }
 
 
@@ -31,8 +42,9 @@ type Email struct {
 
 // part is a copyable representation of a multipart.Part
 type part struct {
-	header textproto.MIMEHeader
-	body   []byte
+	header  textproto.MIMEHeader
+	body    []byte // decoded content
+	related bool   // the part belongs to a multipart/related entity, e.g. an image of the HTML body
 }
 
 // Attachment is a struct representing an email attachment.
@@ -45,3 +57,124 @@ type Attachment struct {
 	HTMLRelated bool
 }
 
+
+// SMTPOptions configures an SMTPSender.
+type SMTPOptions struct {
+	TLSMode   TLSMode
+	TLSConfig *tls.Config // optional, the ServerName defaults to the EmailAddress host
+
+	Auth        AuthMechanism
+	Username    string                                   // defaults to the EmailAddress address
+	OAuth2Token func(ctx context.Context) (string, error) // returns a valid access token, called for every new XOAUTH2 connection
+
+	LocalName                string        // host name sent with EHLO, defaults to "localhost"
+	MaxConnections           int           // maximum number of open connections, defaults to 4
+	MaxMessagesPerConnection int           // the connection is closed after sending this many messages, defaults to 100
+	IdleTimeout              time.Duration // idle connections older than this are closed instead of reused, defaults to 30 seconds
+
+	DKIM *DKIMOptions // signs the messages with DKIM (optional)
+}
+
+// RecipientResult is the delivery result of a single recipient.
+type RecipientResult struct {
+	Address  string
+	Accepted bool
+	Code     int   // SMTP reply code, or HTTP status code for HTTP API senders. 0 if unknown.
+	Err      error // why the recipient was rejected
+}
+
+// SendResult describes the delivery of an Email by a Sender.
+type SendResult struct {
+	MessageID  string // Message-Id header of the sent message, or the identifier returned by the provider
+	Recipients []RecipientResult
+}
+
+// TemplateOptions configures the Templates loaded by LoadTemplates.
+type TemplateOptions struct {
+	FallbackLanguage i18n.ELanguageCode // variant used when the recipient language has none, defaults to i18n.LanguageCode_En_Us
+	Layout           string             // name of the template wrapping the emails, defaults to "layout"
+	Funcs            template.FuncMap   // functions available to every template
+	KeepStyles       bool               // keeps the <style> elements instead of inlining them in style attributes
+}
+
+// HTTPSenderOptions configures the senders using the HTTP API of an email provider.
+type HTTPSenderOptions struct {
+	Client   *http.Client // defaults to http.DefaultClient
+	Endpoint string       // base URL of the API, defaults to the provider one. e.g for an EU region or tests
+}
+
+// SESCredentials are the AWS credentials of an SESSender.
+type SESCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // optional, for temporary credentials
+}
+
+// APIError is returned by the HTTP API senders when the provider refuses an email.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+// DKIMOptions configures the DKIM signature of a message, see SignDKIM.
+type DKIMOptions struct {
+	Domain   string        // d= tag, the domain of the sending address or one of its parents
+	Selector string        // s= tag, the public key is published in the TXT record <Selector>._domainkey.<Domain>
+	Signer   crypto.Signer // *rsa.PrivateKey for rsa-sha256 or ed25519.PrivateKey for ed25519-sha256
+
+	Headers                []string             // signed headers, defaults to DefaultDKIMHeaders. From is always signed
+	HeaderCanonicalization DKIMCanonicalization // defaults to DKIMRelaxed
+	BodyCanonicalization   DKIMCanonicalization // defaults to DKIMRelaxed
+	Expiration             time.Duration        // x= tag, the signature expires this long after signing if set
+}
+
+// BulkRecipient is a recipient of a BulkMailer and the data its email is personalized with.
+type BulkRecipient struct {
+	Address  string                 // e.g "Sam <sam@example.com>"
+	Language i18n.ELanguageCode     // variant of the template sent by BulkMailer.SendTemplate (optional)
+	Vars     map[string]interface{} // merge variables, the data of the templates
+}
+
+// BulkOptions configures a BulkMailer.
+type BulkOptions struct {
+	Workers     int           // emails sent concurrently, defaults to 4
+	Interval    time.Duration // minimum interval between two sends, the sends are not rate limited if 0
+	Burst       int           // sends allowed without waiting for Interval when the mailing starts
+	MaxAttempts int           // sends of an email temporarily refused, defaults to 3
+	Backoff     time.Duration // wait before the first retry, doubled after every retry, defaults to 30 seconds
+	MaxBackoff  time.Duration // maximum wait between two retries, defaults to 10 minutes
+}
+
+// BulkResult is the delivery result of a BulkRecipient.
+type BulkResult struct {
+	Address   string
+	Accepted  bool
+	Attempts  int    // number of sends, 0 if the email could not be personalized
+	Code      int    // reply code of the last attempt, see RecipientResult
+	MessageID string // set if the email was accepted
+	Err       error  // why the email was not delivered
+}
+
+// BulkReport is the result of a mailing, the results are in the order of the recipients.
+type BulkReport struct {
+	Results []BulkResult
+}
+
+// ValidationOptions configures an AddressValidator.
+type ValidationOptions struct {
+	Level             ValidationLevel
+	DisposableDomains []string   // domains of disposable email providers, subdomains included. defaults to DefaultDisposableDomains
+	RoleAccounts      []string   // local parts of role accounts, defaults to DefaultRoleAccounts
+	Resolver          MXResolver // looks up the MX records of ValidationMX, defaults to net.DefaultResolver
+}
+
+// AddressValidation describes an email address checked by an AddressValidator.
+type AddressValidation struct {
+	Address    string
+	LocalPart  string
+	Domain     string // in lower case, internationalized domains in ASCII, e.g. xn--bcher-kva.example for bücher.example
+	Normalized string // the same for every variant of an address delivered to the same mailbox, e.g. without the dots and tag of a Gmail address
+	Disposable bool   // the domain belongs to a disposable email provider
+	Role       bool   // the local part is a role account, e.g. support or postmaster
+}
@@ -0,0 +1,166 @@
+package emails
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sabriboughanmi/go_utils/i18n"
+)
+
+// Templates renders Emails from html/template files, in the language of their recipient.
+//
+// The files are organised as follows:
+//
+//	layouts/*.html               layouts, the one named TemplateOptions.Layout wraps every email
+//	partials/*.html              templates shared by every email
+//	<name>.html                  default variant of the email <name>, written in TemplateOptions.FallbackLanguage
+//	<name>.<language code>.html  variant of the email <name> for a language, e.g. video_approved.fr-FR.html
+//
+// An email defines its "subject" and its "content", which the layout includes with {{template "content" .}}.
+// Without layout, the email file itself is the body. The function lang returns the language of the rendered variant,
+// e.g. for <html lang="{{lang}}">.
+type Templates struct {
+	options TemplateOptions
+	emails  map[string]map[i18n.ELanguageCode]*emailTemplate // variants by email name and language
+}
+
+// emailTemplate is a variant of an email, parsed with the layouts and partials.
+type emailTemplate struct {
+	template *template.Template
+	file     string
+	language i18n.ELanguageCode
+}
+
+// LoadTemplates parses the layouts, partials and emails of fsys, e.g. an embed.FS or os.DirFS.
+func LoadTemplates(fsys fs.FS, options TemplateOptions) (*Templates, error) {
+	if options.FallbackLanguage == "" {
+		options.FallbackLanguage = i18n.LanguageCode_En_Us
+	}
+	if options.Layout == "" {
+		options.Layout = "layout"
+	}
+
+	// lang is replaced when rendering, it must exist while parsing.
+	var base = template.New("").Funcs(template.FuncMap{"lang": func() string { return "" }}).Funcs(options.Funcs)
+	for _, pattern := range []string{"layouts/*.html", "partials/*.html"} {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+		if _, err := base.ParseFS(fsys, files...); err != nil {
+			return nil, fmt.Errorf("emails.LoadTemplates: %w", err)
+		}
+	}
+
+	files, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	var templates = Templates{options: options, emails: make(map[string]map[i18n.ELanguageCode]*emailTemplate)}
+	for _, file := range files {
+		name, language, isDefault := strings.TrimSuffix(file, ".html"), options.FallbackLanguage, true
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name, language, isDefault = name[:i], i18n.ELanguageCode(name[i+1:]), false
+		}
+
+		t, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.ParseFS(fsys, file); err != nil {
+			return nil, fmt.Errorf("emails.LoadTemplates: %w", err)
+		}
+		if templates.emails[name] == nil {
+			templates.emails[name] = make(map[i18n.ELanguageCode]*emailTemplate)
+		}
+		// A language specific file takes precedence over the default variant.
+		if _, exists := templates.emails[name][language]; exists && isDefault {
+			continue
+		}
+		templates.emails[name][language] = &emailTemplate{template: t, file: path.Base(file), language: language}
+	}
+	return &templates, nil
+}
+
+// Render renders the email name in language into email: its Subject if the template defines one, its HTML with the CSS
+// inlined and its Text, generated from the HTML.
+//
+// If the email has no variant for language, a variant of the same language in another region is used (fr-FR for fr-CA),
+// then the variant of TemplateOptions.FallbackLanguage.
+func (t *Templates) Render(email *Email, name string, language i18n.ELanguageCode, data interface{}) error {
+	variant := t.lookup(name, language)
+	if variant == nil {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	// Templates can't be cloned once executed, only the clone is.
+	tmpl, err := variant.template.Clone()
+	if err != nil {
+		return err
+	}
+	tmpl.Funcs(template.FuncMap{"lang": func() string { return string(variant.language) }})
+
+	root := tmpl.Lookup(t.options.Layout)
+	if root == nil {
+		root = tmpl.Lookup(variant.file)
+	}
+	var body bytes.Buffer
+	if err := root.Execute(&body, data); err != nil {
+		return fmt.Errorf("emails.Templates.Render %s: %w", variant.file, err)
+	}
+
+	if subject := tmpl.Lookup("subject"); subject != nil {
+		var buffer bytes.Buffer
+		if err := subject.Execute(&buffer, data); err != nil {
+			return fmt.Errorf("emails.Templates.Render %s: %w", variant.file, err)
+		}
+		// The subject is a header, not HTML.
+		email.Subject = strings.Join(strings.Fields(html.UnescapeString(buffer.String())), " ")
+	}
+
+	email.HTML = body.Bytes()
+	if !t.options.KeepStyles {
+		if email.HTML, err = InlineCSS(email.HTML); err != nil {
+			return fmt.Errorf("emails.Templates.Render %s: %w", variant.file, err)
+		}
+	}
+	email.Text = HTMLToText(email.HTML)
+	return nil
+}
+
+// lookup returns the variant of the email name to use for language, nil if the email does not exist.
+func (t *Templates) lookup(name string, language i18n.ELanguageCode) *emailTemplate {
+	variants := t.emails[name]
+	if variant, ok := variants[language]; ok {
+		return variant
+	}
+
+	var languages = make([]string, 0, len(variants))
+	for l := range variants {
+		languages = append(languages, string(l))
+	}
+	sort.Strings(languages)
+	for _, l := range languages {
+		if strings.EqualFold(primaryLanguage(l), primaryLanguage(string(language))) {
+			return variants[i18n.ELanguageCode(l)]
+		}
+	}
+	return variants[t.options.FallbackLanguage]
+}
+
+// primaryLanguage returns the language of a language code without its region: "fr" for "fr-CA".
+func primaryLanguage(code string) string {
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		return code[:i]
+	}
+	return code
+}
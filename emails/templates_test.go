@@ -0,0 +1,130 @@
+package emails
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sabriboughanmi/go_utils/i18n"
+)
+
+var testTemplates = fstest.MapFS{
+	"layouts/base.html": {Data: []byte(`{{define "layout"}}<html lang="{{lang}}"><head><style>
+p { color: #333333; margin: 0 }
+.button { background: #e00000; color: #ffffff }
+.footer { color: #999999 }
+@media (max-width: 600px) { p { font-size: 18px } }
+a:hover { color: #000000 }
+</style></head><body>{{template "content" .}}{{template "footer" .}}</body></html>{{end}}`)},
+	"partials/footer.html": {Data: []byte(`{{define "footer"}}<p class="footer">tested4you</p>{{end}}`)},
+	"video_approved.html": {Data: []byte(`{{define "subject"}}Your video {{.Title}} was approved{{end}}
+{{define "content"}}<p>Hello {{.Name}},</p><p>Your video <b>{{.Title}}</b> was approved.</p>
+<a class="button" href="https://example.com/v/{{.ID}}">Watch it</a>{{end}}`)},
+	"video_approved.fr-FR.html": {Data: []byte(`{{define "subject"}}Votre vidéo {{.Title}} a été approuvée{{end}}
+{{define "content"}}<p>Bonjour {{.Name}},</p><p>Votre vidéo <b>{{.Title}}</b> a été approuvée.</p>
+<a class="button" href="https://example.com/v/{{.ID}}">La regarder</a>{{end}}`)},
+}
+
+type testTemplateData struct {
+	Name, Title, ID string
+}
+
+func TestTemplatesRender(t *testing.T) {
+	templates, err := LoadTemplates(testTemplates, TemplateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := testTemplateData{Name: "Sam", Title: "Tom & Jerry", ID: "42"}
+
+	tests := []struct {
+		language i18n.ELanguageCode
+		subject  string
+		lang     string
+	}{
+		{i18n.LanguageCode_Fr_fr, "Votre vidéo Tom & Jerry a été approuvée", "fr-FR"},
+		{i18n.LanguageCode_Fr_Cn, "Votre vidéo Tom & Jerry a été approuvée", "fr-FR"},
+		{i18n.LanguageCode_Gr_Gr, "Your video Tom & Jerry was approved", "en-US"},
+		{i18n.LanguageCode_En_Gb, "Your video Tom & Jerry was approved", "en-US"},
+	}
+	for _, test := range tests {
+		email := NewEmail()
+		if err := templates.Render(email, "video_approved", test.language, data); err != nil {
+			t.Fatal(err)
+		}
+		if email.Subject != test.subject {
+			t.Errorf("%s: got subject %q, want %q", test.language, email.Subject, test.subject)
+		}
+		if !strings.Contains(string(email.HTML), `<html lang="`+test.lang+`">`) {
+			t.Errorf("%s: expected the %s variant, got %s", test.language, test.lang, email.HTML)
+		}
+	}
+
+	email := NewEmail()
+	if err := templates.Render(email, "video_approved", i18n.LanguageCode_En_Us, data); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<p style="color: #333333; margin: 0">Hello Sam,</p>`,
+		`<b>Tom &amp; Jerry</b>`,
+		`<a class="button" href="https://example.com/v/42" style="background: #e00000; color: #ffffff">`,
+		`<p class="footer" style="color: #999999; margin: 0">`,
+		`@media (max-width: 600px) { p { font-size: 18px } }`,
+		`a:hover {`,
+	} {
+		if !strings.Contains(string(email.HTML), expected) {
+			t.Errorf("expected %q in %s", expected, email.HTML)
+		}
+	}
+	if text := "Hello Sam,\n\nYour video Tom & Jerry was approved.\n\nWatch it (https://example.com/v/42)\n\ntested4you"; string(email.Text) != text {
+		t.Errorf("got text %q, want %q", email.Text, text)
+	}
+
+	if err := templates.Render(NewEmail(), "video_banned", i18n.LanguageCode_En_Us, data); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestInlineCSS(t *testing.T) {
+	document := `<html><head><style>
+/* comment */
+td { padding: 4px }
+table.grid > tr > td.total { font-weight: bold }
+#summary td { color: red }
+.grid td { color: blue !important; padding: 8px }
+</style></head><body><table class="grid" id="summary"><tr><td class="total" style="padding: 2px">1</td><td>2</td></tr></table>
+<img src="logo.png"><p>text</p></body></html>`
+
+	inlined, err := InlineCSS([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<td class="total" style="padding: 2px; font-weight: bold; color: blue">1</td>`,
+		`<td style="padding: 8px; color: blue">2</td>`,
+		`<img src="logo.png"><p>text</p>`,
+	} {
+		if !strings.Contains(string(inlined), expected) {
+			t.Errorf("expected %q in %s", expected, inlined)
+		}
+	}
+	if strings.Contains(string(inlined), "<style>") {
+		t.Errorf("expected the <style> element to be removed, got %s", inlined)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	document := `<html><head><title>Title</title><style>p { color: red }</style></head><body>
+<h1>Weekly   digest</h1>
+<p>Your videos:<br>this week</p>
+<ul><li>First &amp; best</li><li>Second<ol><li>part one</li><li>part two</li></ol></li></ul>
+<p>Contact <a href="mailto:support@example.com">support@example.com</a> or <a href="https://example.com/help">our help center</a>.</p>
+<img src="logo.png" alt="tested4you"><script>alert(1)</script>
+</body></html>`
+
+	expected := "Weekly digest\n\nYour videos:\nthis week\n\n- First & best\n- Second\n  1. part one\n  2. part two\n\n" +
+		"Contact support@example.com or our help center (https://example.com/help).\n\ntested4you"
+	if text := string(HTMLToText([]byte(document))); text != expected {
+		t.Errorf("got %q, want %q", text, expected)
+	}
+}
@@ -0,0 +1,196 @@
+package emails
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// textWriter builds a plaintext document, collapsing whitespaces like a browser.
+type textWriter struct {
+	buffer   bytes.Buffer
+	newlines int  // number of newlines ending the buffer
+	space    bool // a space is pending before the next word
+}
+
+// write appends text, its whitespaces are collapsed.
+func (w *textWriter) write(text string) {
+	if text == "" {
+		return
+	}
+	if strings.TrimLeft(text, " \t\r\n\f") != text {
+		w.space = true
+	}
+	for _, word := range strings.Fields(text) {
+		w.writeRaw(word)
+		w.space = true
+	}
+	if strings.TrimRight(text, " \t\r\n\f") == text {
+		w.space = false
+	}
+}
+
+// writeRaw appends text as is, after the pending space.
+func (w *textWriter) writeRaw(text string) {
+	if text == "" {
+		return
+	}
+	if w.space && w.newlines == 0 && w.buffer.Len() > 0 {
+		w.buffer.WriteByte(' ')
+	}
+	w.buffer.WriteString(text)
+	if trailing := len(text) - len(strings.TrimRight(text, "\n")); trailing == len(text) {
+		w.newlines += trailing
+	} else {
+		w.newlines = trailing
+	}
+	w.space = false
+}
+
+// breakLine ends the current line, with n-1 empty lines after it. Nothing is written at the beginning of the document.
+func (w *textWriter) breakLine(n int) {
+	w.space = false
+	if w.buffer.Len() == 0 {
+		return
+	}
+	for ; w.newlines < n; w.newlines++ {
+		w.buffer.WriteByte('\n')
+	}
+}
+
+// textBlocks are the elements separated from their siblings by an empty line, other block elements are on their own line.
+var textBlocks = map[string]int{
+	"p": 2, "h1": 2, "h2": 2, "h3": 2, "h4": 2, "h5": 2, "h6": 2, "table": 2, "ul": 2, "ol": 2, "blockquote": 2, "pre": 2,
+	"div": 1, "tr": 1, "li": 1, "section": 1, "article": 1, "header": 1, "footer": 1, "center": 1, "dl": 1, "dt": 1, "dd": 1,
+}
+
+// blockLines returns the number of lines ending a block, blocks inside lists are not separated by empty lines.
+func blockLines(n int, lists []int) int {
+	if len(lists) > 0 {
+		return 1
+	}
+	return n
+}
+
+// HTMLToText returns the plaintext version of an HTML document, e.g. the alternative of an HTML email.
+// Paragraphs are separated by empty lines, list items are prefixed by a dash or their number and links are followed
+// by their URL.
+func HTMLToText(document []byte) []byte {
+	var w textWriter
+	var skip, pre int
+	var lists []int // item counters of the open lists, -1 for unordered lists
+	var href string
+	var linkStart int
+
+	z := html.NewTokenizer(bytes.NewReader(document))
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		token := z.Token()
+		switch tokenType {
+		case html.TextToken:
+			switch {
+			case skip > 0:
+			case pre > 0:
+				w.writeRaw(token.Data)
+			default:
+				w.write(token.Data)
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch token.Data {
+			case "head", "style", "script", "title", "noscript":
+				if tokenType == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			if n, ok := textBlocks[token.Data]; ok {
+				w.breakLine(blockLines(n, lists))
+			}
+			switch token.Data {
+			case "pre":
+				pre++
+			case "br":
+				w.space = false
+				w.writeRaw("\n")
+			case "hr":
+				w.breakLine(2)
+				w.writeRaw("----------")
+				w.breakLine(2)
+			case "ul":
+				lists = append(lists, -1)
+			case "ol":
+				lists = append(lists, 0)
+			case "li":
+				if len(lists) == 0 {
+					w.writeRaw("- ")
+				} else if indent := strings.Repeat("  ", len(lists)-1); lists[len(lists)-1] < 0 {
+					w.writeRaw(indent + "- ")
+				} else {
+					lists[len(lists)-1]++
+					w.writeRaw(indent + strconv.Itoa(lists[len(lists)-1]) + ". ")
+				}
+			case "td", "th":
+				w.space = true
+			case "img":
+				w.write(tokenAttribute(token, "alt"))
+			case "a":
+				href = strings.TrimSpace(tokenAttribute(token, "href"))
+				linkStart = w.buffer.Len()
+			}
+
+		case html.EndTagToken:
+			switch token.Data {
+			case "head", "style", "script", "title", "noscript":
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			switch token.Data {
+			case "pre":
+				if pre > 0 {
+					pre--
+				}
+			case "ul", "ol":
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+				}
+			case "a":
+				// Links are followed by their URL, unless it is their text.
+				text := strings.TrimSpace(w.buffer.String()[linkStart:])
+				url := strings.TrimPrefix(href, "mailto:")
+				if href != "" && !strings.HasPrefix(href, "#") && text != url {
+					w.write(" ")
+					w.writeRaw("(" + url + ")")
+				}
+				href = ""
+			}
+			if n, ok := textBlocks[token.Data]; ok {
+				w.breakLine(blockLines(n, lists))
+			}
+		}
+	}
+	return bytes.TrimSpace(w.buffer.Bytes())
+}
+
+// tokenAttribute returns the value of an attribute of a start tag, an empty string if it is not set.
+func tokenAttribute(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
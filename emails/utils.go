@@ -15,7 +15,6 @@ import (
 	"net/mail"
 	"net/textproto"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 	"unicode"
@@ -278,8 +277,3 @@ func IsValidEmail(email string) error {
 	}
 	return nil
 }
-
-
-
-var emailRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
-
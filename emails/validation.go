@@ -0,0 +1,268 @@
+package emails
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// MXResolver looks up the MX records of a domain, *net.Resolver implements it.
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// AddressValidator validates and normalizes email addresses, e.g. the address of a user signing up.
+// Every level but ValidationMX works offline, the MX records are looked up with ValidationOptions.Resolver.
+type AddressValidator struct {
+	options    ValidationOptions
+	disposable map[string]bool
+	roles      map[string]bool
+}
+
+// addressProvider describes an email provider delivering several variants of an address to the same mailbox.
+// The local parts of its addresses are case insensitive, and everything after a '+' is a tag.
+type addressProvider struct {
+	domain     string // the domain of the mailbox
+	ignoreDots bool   // the dots of the local part are ignored
+}
+
+// addressProviders are the providers normalized by AddressValidator, by domain.
+var addressProviders = map[string]addressProvider{
+	"gmail.com":      {domain: "gmail.com", ignoreDots: true},
+	"googlemail.com": {domain: "gmail.com", ignoreDots: true},
+	"outlook.com":    {domain: "outlook.com"},
+	"hotmail.com":    {domain: "hotmail.com"},
+	"live.com":       {domain: "live.com"},
+	"icloud.com":     {domain: "icloud.com"},
+	"fastmail.com":   {domain: "fastmail.com"},
+	"protonmail.com": {domain: "protonmail.com"},
+	"proton.me":      {domain: "proton.me"},
+}
+
+// idnaProfile converts domains to their ASCII form, as they are looked up (RFC 5891).
+var idnaProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true))
+
+// NewAddressValidator returns an AddressValidator checking the addresses up to options.Level.
+func NewAddressValidator(options ValidationOptions) *AddressValidator {
+	if options.Resolver == nil {
+		options.Resolver = net.DefaultResolver
+	}
+	disposableDomains, roleAccounts := options.DisposableDomains, options.RoleAccounts
+	if len(disposableDomains) == 0 {
+		disposableDomains = DefaultDisposableDomains
+	}
+	if len(roleAccounts) == 0 {
+		roleAccounts = DefaultRoleAccounts
+	}
+
+	var validator = AddressValidator{
+		options:    options,
+		disposable: make(map[string]bool, len(disposableDomains)),
+		roles:      make(map[string]bool, len(roleAccounts)),
+	}
+	for _, domain := range disposableDomains {
+		validator.disposable[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+	for _, role := range roleAccounts {
+		validator.roles[strings.ToLower(strings.TrimSpace(role))] = true
+	}
+	return &validator
+}
+
+// Validate checks address, an addr-spec such as "sam@example.com" without display name, up to the level of the validator.
+// The AddressValidation is nil if the syntax of address is invalid, the returned error wraps ErrInvalidAddress,
+// ErrDisposableAddress, ErrRoleAddress or ErrNoMXRecord. Other errors come from the resolver, e.g. a DNS timeout.
+func (v *AddressValidator) Validate(ctx context.Context, address string) (*AddressValidation, error) {
+	local, domain, err := parseAddressSpec(address)
+	if err != nil {
+		return nil, err
+	}
+	mailbox, normalizedDomain := normalizeAddress(local, domain)
+	var validation = AddressValidation{
+		Address:    address,
+		LocalPart:  local,
+		Domain:     domain,
+		Normalized: mailbox + "@" + normalizedDomain,
+		Disposable: v.isDisposable(domain),
+		Role:       v.roles[roleName(mailbox)],
+	}
+
+	switch {
+	case v.options.Level >= ValidationDisposable && validation.Disposable:
+		return &validation, fmt.Errorf("%w: %s", ErrDisposableAddress, domain)
+	case v.options.Level >= ValidationRole && validation.Role:
+		return &validation, fmt.Errorf("%w: %s", ErrRoleAddress, local)
+	case v.options.Level >= ValidationMX && domain[0] != '[':
+		return &validation, v.checkMX(ctx, domain)
+	}
+	return &validation, nil
+}
+
+// isDisposable returns true if domain or one of its parents is a disposable domain.
+func (v *AddressValidator) isDisposable(domain string) bool {
+	for {
+		if v.disposable[domain] {
+			return true
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			return false
+		}
+		domain = domain[i+1:]
+	}
+}
+
+// checkMX returns ErrNoMXRecord if domain has no MX record, or a null MX record (RFC 7505).
+func (v *AddressValidator) checkMX(ctx context.Context, domain string) error {
+	records, err := v.options.Resolver.LookupMX(ctx, domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return fmt.Errorf("%w: %s", ErrNoMXRecord, domain)
+		}
+		return fmt.Errorf("emails.AddressValidator.Validate: %w", err)
+	}
+	if len(records) == 0 || len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+		return fmt.Errorf("%w: %s", ErrNoMXRecord, domain)
+	}
+	return nil
+}
+
+// parseAddressSpec splits an addr-spec of RFC 5322, with the UTF-8 characters of RFC 6531, into its local part and domain.
+// The domain is returned in lower case ASCII, or as an address literal, e.g. [192.0.2.1].
+func parseAddressSpec(address string) (string, string, error) {
+	if !utf8.ValidString(address) {
+		return "", "", fmt.Errorf("%w: not UTF-8", ErrInvalidAddress)
+	}
+	// The local part may contain '@' if it is quoted, the domain never does.
+	at := strings.LastIndexByte(address, '@')
+	if at < 0 {
+		return "", "", fmt.Errorf("%w: missing @", ErrInvalidAddress)
+	}
+	local, domain := address[:at], address[at+1:]
+	if err := checkLocalPart(local); err != nil {
+		return "", "", err
+	}
+
+	if strings.HasPrefix(domain, "[") {
+		if err := checkAddressLiteral(domain); err != nil {
+			return "", "", err
+		}
+	} else {
+		ascii, err := idnaProfile.ToASCII(domain)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+		}
+		// Single label domains and numeric top level domains are not reachable on the Internet.
+		i := strings.LastIndexByte(ascii, '.')
+		if i < 0 || strings.Trim(ascii[i+1:], "0123456789") == "" {
+			return "", "", fmt.Errorf("%w: invalid domain %s", ErrInvalidAddress, domain)
+		}
+		domain = ascii
+	}
+
+	// RFC 5321 limits a path to 256 octets, angle brackets included.
+	if len(address) > 254 || len(local)+1+len(domain) > 254 {
+		return "", "", fmt.Errorf("%w: longer than 254 octets", ErrInvalidAddress)
+	}
+	return local, domain, nil
+}
+
+// checkLocalPart checks a dot-atom or quoted-string local part.
+func checkLocalPart(local string) error {
+	if local == "" {
+		return fmt.Errorf("%w: empty local part", ErrInvalidAddress)
+	}
+	if len(local) > 64 {
+		return fmt.Errorf("%w: local part longer than 64 octets", ErrInvalidAddress)
+	}
+
+	if local[0] == '"' {
+		if len(local) < 2 || local[len(local)-1] != '"' {
+			return fmt.Errorf("%w: unterminated quoted local part", ErrInvalidAddress)
+		}
+		for i := 1; i < len(local)-1; i++ {
+			c := local[i]
+			if c == '\\' {
+				// The closing quote can't be escaped.
+				if i++; i == len(local)-1 {
+					return fmt.Errorf("%w: unterminated quoted local part", ErrInvalidAddress)
+				}
+				c = local[i]
+			} else if c == '"' {
+				return fmt.Errorf("%w: unescaped quote in local part", ErrInvalidAddress)
+			}
+			if c < ' ' && c != '\t' || c == 0x7f {
+				return fmt.Errorf("%w: control character in local part", ErrInvalidAddress)
+			}
+		}
+		return nil
+	}
+
+	for _, atom := range strings.Split(local, ".") {
+		if atom == "" {
+			return fmt.Errorf("%w: misplaced dot in local part", ErrInvalidAddress)
+		}
+		for i := 0; i < len(atom); i++ {
+			if !isAtext(atom[i]) {
+				return fmt.Errorf("%w: invalid character %q in local part", ErrInvalidAddress, atom[i])
+			}
+		}
+	}
+	return nil
+}
+
+// isAtext returns true if c is an atext character of RFC 5322, or a byte of a UTF-8 encoded character (RFC 6531).
+func isAtext(c byte) bool {
+	return c >= 0x80 || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+// checkAddressLiteral checks an IPv4 or IPv6 address literal of RFC 5321, e.g. [192.0.2.1] or [IPv6:2001:db8::1].
+func checkAddressLiteral(domain string) error {
+	if !strings.HasSuffix(domain, "]") {
+		return fmt.Errorf("%w: unterminated address literal", ErrInvalidAddress)
+	}
+	literal := domain[1 : len(domain)-1]
+	if strings.HasPrefix(literal, "IPv6:") {
+		if ip := net.ParseIP(literal[5:]); ip == nil || !strings.Contains(literal[5:], ":") {
+			return fmt.Errorf("%w: invalid IPv6 address literal %s", ErrInvalidAddress, domain)
+		}
+		return nil
+	}
+	if ip := net.ParseIP(literal); ip == nil || strings.Contains(literal, ":") {
+		return fmt.Errorf("%w: invalid address literal %s", ErrInvalidAddress, domain)
+	}
+	return nil
+}
+
+// normalizeAddress returns the local part and domain of the mailbox an address is delivered to.
+// Local parts are case sensitive, except for the known providers.
+func normalizeAddress(local, domain string) (string, string) {
+	provider, ok := addressProviders[domain]
+	if !ok || local[0] == '"' {
+		return local, domain
+	}
+	local = strings.ToLower(local)
+	if i := strings.IndexByte(local, '+'); i > 0 {
+		local = local[:i]
+	}
+	if provider.ignoreDots {
+		local = strings.Replace(local, ".", "", -1)
+	}
+	return local, provider.domain
+}
+
+// roleName returns the name of the role a local part may be, without its tag, e.g. "support" for "Support+billing".
+func roleName(local string) string {
+	local = strings.ToLower(strings.Trim(local, `"`))
+	if i := strings.IndexByte(local, '+'); i > 0 {
+		local = local[:i]
+	}
+	return local
+}
@@ -0,0 +1,182 @@
+package emails
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeResolver is an MXResolver answering from a map, the domains missing from it do not exist.
+type fakeResolver map[string][]*net.MX
+
+func (r fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if name == "timeout.example" {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestParseAddressSpec(t *testing.T) {
+	for address, domain := range map[string]string{
+		"sam@example.com":                   "example.com",
+		"Sam.O'Neil+news@Example.COM":       "example.com",
+		"!#$%&'*+-/=?^_`{|}~@example.com":   "example.com",
+		`"sam smith"@example.com`:           "example.com",
+		`"sam@home\"s"@example.com`:         "example.com",
+		"sam@[192.0.2.1]":                   "[192.0.2.1]",
+		"sam@[IPv6:2001:db8::1]":            "[IPv6:2001:db8::1]",
+		"δοκιμή@παράδειγμα.δοκιμή":          "xn--hxajbheg2az3al.xn--jxalpdlp",
+		"用户@例子.广告":                          "xn--fsqu00a.xn--4rr70v",
+		"sam@bücher.example":                "xn--bcher-kva.example",
+		"sam@xn--bcher-kva.example":         "xn--bcher-kva.example",
+		strings.Repeat("a", 64) + "@ex.com": "ex.com",
+	} {
+		_, parsed, err := parseAddressSpec(address)
+		if err != nil {
+			t.Errorf("%s: %v", address, err)
+		} else if parsed != domain {
+			t.Errorf("%s: expected domain %s, got %s", address, domain, parsed)
+		}
+	}
+
+	for _, address := range []string{
+		"",
+		"example.com",
+		"@example.com",
+		"sam@",
+		"sam@localhost",
+		"sam@example.123",
+		"sam@example.com.",
+		"sam@-example.com",
+		"sam@exa_mple.com",
+		"sam@example..com",
+		".sam@example.com",
+		"sam.@example.com",
+		"sam..smith@example.com",
+		"sam smith@example.com",
+		"sam<smith@example.com",
+		"Sam <sam@example.com>",
+		`"sam@example.com`,
+		`"sam"smith"@example.com`,
+		`"sam\"@example.com`,
+		"sam@[192.0.2]",
+		"sam@[2001:db8::1]",
+		"sam@[IPv6:192.0.2.1]",
+		"sam@" + strings.Repeat("a", 64) + ".com",
+		strings.Repeat("a", 65) + "@example.com",
+		strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("b", 62)+".", 3) + "com",
+		"sam\xff@example.com",
+	} {
+		if _, _, err := parseAddressSpec(address); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("%q: expected ErrInvalidAddress, got %v", address, err)
+		}
+	}
+}
+
+func TestAddressValidatorNormalization(t *testing.T) {
+	validator := NewAddressValidator(ValidationOptions{})
+	for address, normalized := range map[string]string{
+		"S.a.m+news@Gmail.com":          "sam@gmail.com",
+		"sam.smith@googlemail.com":      "samsmith@gmail.com",
+		"Sam.Smith+work@outlook.com":    "sam.smith@outlook.com",
+		"Sam.Smith+work@example.com":    "Sam.Smith+work@example.com",
+		`"sam+news"@gmail.com`:          `"sam+news"@gmail.com`,
+		"sam@BÜCHER.example":            "sam@xn--bcher-kva.example",
+		"+news@gmail.com":               "+news@gmail.com",
+		"postmaster@[IPv6:2001:db8::1]": "postmaster@[IPv6:2001:db8::1]",
+	} {
+		validation, err := validator.Validate(context.Background(), address)
+		if err != nil {
+			t.Errorf("%s: %v", address, err)
+			continue
+		}
+		if validation.Normalized != normalized {
+			t.Errorf("%s: expected %s, got %s", address, normalized, validation.Normalized)
+		}
+	}
+}
+
+func TestAddressValidatorLevels(t *testing.T) {
+	resolver := fakeResolver{
+		"example.com":        {{Host: "mx.example.com.", Pref: 10}},
+		"no-mail.example":    {{Host: ".", Pref: 0}},
+		"sub.mailinator.com": {{Host: "mx.mailinator.com.", Pref: 10}},
+	}
+	for _, test := range []struct {
+		address string
+		level   ValidationLevel
+		err     error
+	}{
+		{"sam@unknown.example", ValidationSyntax, nil},
+		{"sam@sub.mailinator.com", ValidationSyntax, nil},
+		{"sam@sub.mailinator.com", ValidationDisposable, ErrDisposableAddress},
+		{"Support+billing@example.com", ValidationDisposable, nil},
+		{"Support+billing@example.com", ValidationRole, ErrRoleAddress},
+		{"sam@example.com", ValidationMX, nil},
+		{"sam@[192.0.2.1]", ValidationMX, nil},
+		{"sam@unknown.example", ValidationMX, ErrNoMXRecord},
+		{"sam@no-mail.example", ValidationMX, ErrNoMXRecord},
+		{"postmaster@example.com", ValidationMX, ErrRoleAddress},
+	} {
+		validator := NewAddressValidator(ValidationOptions{Level: test.level, Resolver: resolver})
+		validation, err := validator.Validate(context.Background(), test.address)
+		if !errors.Is(err, test.err) || (err == nil) != (test.err == nil) {
+			t.Errorf("%s at level %d: expected %v, got %v", test.address, test.level, test.err, err)
+		}
+		if validation == nil {
+			t.Errorf("%s: expected a validation", test.address)
+			continue
+		}
+		if validation.Disposable != strings.HasSuffix(test.address, "mailinator.com") {
+			t.Errorf("%s: unexpected disposable %v", test.address, validation.Disposable)
+		}
+	}
+
+	// Resolver failures are not invalid addresses.
+	validator := NewAddressValidator(ValidationOptions{Level: ValidationMX, Resolver: resolver})
+	_, err := validator.Validate(context.Background(), "sam@timeout.example")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || errors.Is(err, ErrNoMXRecord) {
+		t.Errorf("expected the resolver error, got %v", err)
+	}
+
+	// Custom lists replace the default ones.
+	validator = NewAddressValidator(ValidationOptions{Level: ValidationRole, DisposableDomains: []string{"Throwaway.example"}, RoleAccounts: []string{"hr"}})
+	for address, expected := range map[string]error{
+		"sam@mail.throwaway.example": ErrDisposableAddress,
+		"sam@mailinator.com":         nil,
+		"HR@example.com":             ErrRoleAddress,
+		"support@example.com":        nil,
+	} {
+		if _, err := validator.Validate(context.Background(), address); !errors.Is(err, expected) || (err == nil) != (expected == nil) {
+			t.Errorf("%s: expected %v, got %v", address, expected, err)
+		}
+	}
+}
+
+func TestIsEmailValid(t *testing.T) {
+	defer func(validator *AddressValidator) { emailValidator = validator }(emailValidator)
+	emailValidator = NewAddressValidator(ValidationOptions{Level: ValidationMX, Resolver: fakeResolver{
+		"example.com":    {{Host: "mx.example.com.", Pref: 10}},
+		"gmail.com":      {{Host: "gmail-smtp-in.l.google.com.", Pref: 5}},
+		"mailinator.com": {{Host: "mail.mailinator.com.", Pref: 10}},
+	}})
+
+	// Unlike AddressValidator, IsEmailValid does not refuse role accounts and disposable domains.
+	for _, address := range []string{"sam@example.com", "support@gmail.com", "postmaster@example.com", "sam@mailinator.com"} {
+		if !IsEmailValid(address) {
+			t.Errorf("%q: expected a valid address", address)
+		}
+	}
+	for _, address := range []string{"", "a@", "sam@localhost", "sam@unknown.example", "sam@[192.0.2.1]", strings.Repeat("a", 250) + "@example.com"} {
+		if IsEmailValid(address) {
+			t.Errorf("%q: expected an invalid address", address)
+		}
+	}
+}
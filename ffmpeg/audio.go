@@ -0,0 +1,401 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// audioNode is a single operation of an AudioGraph.
+type audioNode struct {
+	filter string // linear audio filter, empty for background music nodes
+
+	// Only set for background music nodes.
+	input string
+	music BackgroundMusicOptions
+}
+
+// AudioGraph builds the audio part of a -filter_complex graph from an ordered list of audio operations.
+// The zero value is an empty graph ready to use.
+type AudioGraph struct {
+	nodes []audioNode
+}
+
+// Clone returns a deep copy of the graph.
+func (g *AudioGraph) Clone() AudioGraph {
+	var clone = AudioGraph{nodes: make([]audioNode, len(g.nodes))}
+	copy(clone.nodes, g.nodes)
+	return clone
+}
+
+// IsEmpty returns true if no operation was added to the graph.
+func (g *AudioGraph) IsEmpty() bool {
+	return len(g.nodes) == 0
+}
+
+// Filter appends a raw ffmpeg audio filter, e.g "volume=0.5" or "highpass=f=200".
+func (g *AudioGraph) Filter(filter string) *AudioGraph {
+	g.nodes = append(g.nodes, audioNode{filter: filter})
+	return g
+}
+
+// Loudnorm appends the second pass of an EBU R128 loudness normalization using the values measured by the first pass.
+func (g *AudioGraph) Loudnorm(target LoudnessTarget, measured LoudnessMeasurement) *AudioGraph {
+	target = target.withDefaults()
+	g.Filter(fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		formatFloat(target.IntegratedLoudness), formatFloat(target.TruePeak), formatFloat(target.LoudnessRange),
+		formatFloat(measured.IntegratedLoudness), formatFloat(measured.TruePeak), formatFloat(measured.LoudnessRange),
+		formatFloat(measured.Threshold), formatFloat(measured.TargetOffset)))
+	// loudnorm upsamples to 192kHz.
+	return g.Filter("aresample=48000")
+}
+
+// BackgroundMusic mixes the audio of path in, or replaces the current audio with it.
+func (g *AudioGraph) BackgroundMusic(path string, options BackgroundMusicOptions) *AudioGraph {
+	g.nodes = append(g.nodes, audioNode{input: path, music: options})
+	return g
+}
+
+// Build returns the extra inputs command line arguments, the -filter_complex value and the label of the output pad.
+//
+// audioPad: is the stream specifier of the audio to filter, e.g "0:a:0", or "" if there is no audio to start from.
+//
+// firstInputIndex: is the index ffmpeg will assign to the first extra input.
+//
+// duration: is the duration of the output, used to cut replaced looping music (0 if unknown).
+func (g *AudioGraph) Build(audioPad string, firstInputIndex int, duration time.Duration) (inputArgs []string, graph string, outputLabel string) {
+	var (
+		chains  []string
+		pending []string
+		current = audioPad
+		label   = 0
+		input   = firstInputIndex
+	)
+
+	nextLabel := func() string {
+		label++
+		return fmt.Sprintf("a%d", label)
+	}
+
+	flush := func() {
+		if len(pending) == 0 || current == "" {
+			pending = nil
+			return
+		}
+		out := nextLabel()
+		chains = append(chains, fmt.Sprintf("[%s]%s[%s]", current, strings.Join(pending, ","), out))
+		current = out
+		pending = nil
+	}
+
+	for _, node := range g.nodes {
+		if node.input == "" {
+			pending = append(pending, escapeFilterGraph(node.filter))
+			continue
+		}
+		flush()
+
+		if node.music.Loop {
+			inputArgs = append(inputArgs, "-stream_loop", "-1")
+		}
+		inputArgs = append(inputArgs, "-i", node.input)
+		musicPad := fmt.Sprintf("%d:a:0", input)
+		input++
+
+		volume := node.music.Volume
+		if volume <= 0 {
+			volume = 1
+		}
+		music := nextLabel()
+		musicChain := fmt.Sprintf("[%s]volume=%s", musicPad, formatFloat(volume))
+
+		switch {
+		case node.music.Replace || current == "":
+			if duration > 0 {
+				musicChain += ",atrim=end=" + formatSeconds(duration)
+			}
+			chains = append(chains, fmt.Sprintf("%s[%s]", musicChain, music))
+		case node.music.Duck:
+			main, sideChain, ducked := nextLabel(), nextLabel(), nextLabel()
+			chains = append(chains,
+				fmt.Sprintf("%s[%s]", musicChain, music),
+				fmt.Sprintf("[%s]asplit=2[%s][%s]", current, main, sideChain),
+				fmt.Sprintf("[%s][%s]sidechaincompress=threshold=0.05:ratio=8:attack=20:release=400[%s]", music, sideChain, ducked),
+			)
+			music = nextLabel()
+			// amix divides the volume of each input by the number of inputs.
+			chains = append(chains, fmt.Sprintf("[%s][%s]amix=inputs=2:duration=first:dropout_transition=0,volume=2[%s]", main, ducked, music))
+		default:
+			mixed := nextLabel()
+			chains = append(chains,
+				fmt.Sprintf("%s[%s]", musicChain, music),
+				fmt.Sprintf("[%s][%s]amix=inputs=2:duration=first:dropout_transition=0,volume=2[%s]", current, music, mixed),
+			)
+			music = mixed
+		}
+		current = music
+	}
+
+	if current == "" {
+		return inputArgs, "", ""
+	}
+	if len(pending) == 0 && current == audioPad {
+		pending = append(pending, "anull")
+	}
+	if len(pending) > 0 {
+		chains = append(chains, fmt.Sprintf("[%s]%s[%s]", current, strings.Join(pending, ","), audioGraphOutputLabel))
+	} else {
+		last := chains[len(chains)-1]
+		chains[len(chains)-1] = strings.TrimSuffix(last, "["+current+"]") + "[" + audioGraphOutputLabel + "]"
+	}
+
+	return inputArgs, strings.Join(chains, ";"), audioGraphOutputLabel
+}
+
+// withDefaults returns the target with default values for unset fields.
+func (t LoudnessTarget) withDefaults() LoudnessTarget {
+	if t.IntegratedLoudness == 0 {
+		t.IntegratedLoudness = -16
+	}
+	if t.TruePeak == 0 {
+		t.TruePeak = -1.5
+	}
+	if t.LoudnessRange == 0 {
+		t.LoudnessRange = 11
+	}
+	return t
+}
+
+// MeasureLoudness runs the first pass of an EBU R128 loudness normalization on the output audio.
+// Audio operations already applied to the video are taken into account.
+func (v *EditableVideo) MeasureLoudness(ctx context.Context, target LoudnessTarget) (*LoudnessMeasurement, error) {
+	if !v.hasAudioOutput() {
+		return nil, errors.New("Video.MeasureLoudness: the video has no audio")
+	}
+	target = target.withDefaults()
+
+	inputArgs, graph, audioPad := v.audio.Build("0:a:0", 1, v.outputDuration())
+	cmdline := []string{"ffmpeg", "-y"}
+	cmdline = append(cmdline, v.inputSeekArgs()...)
+	cmdline = append(cmdline, "-i", v.filepath)
+	cmdline = append(cmdline, inputArgs...)
+	cmdline = append(cmdline,
+		"-filter_complex", fmt.Sprintf("%s;[%s]loudnorm=I=%s:TP=%s:LRA=%s:print_format=json[measure]",
+			graph, audioPad, formatFloat(target.IntegratedLoudness), formatFloat(target.TruePeak), formatFloat(target.LoudnessRange)),
+		"-map", "[measure]",
+	)
+	cmdline = append(cmdline, v.durationArgs()...)
+	cmdline = append(cmdline, "-f", "null", "-")
+
+	var logs bytes.Buffer
+	if err := runCommandWithLogs(ctx, cmdline, v.outputDuration(), nil, &logs); err != nil {
+		return nil, fmt.Errorf("Video.MeasureLoudness: ffmpeg failed: %w", err)
+	}
+	return parseLoudnormOutput(logs.Bytes())
+}
+
+// NormalizeLoudness measures the output audio loudness (first pass) and normalizes it to target while rendering (second pass).
+func (v *EditableVideo) NormalizeLoudness(ctx context.Context, target LoudnessTarget) error {
+	measured, err := v.MeasureLoudness(ctx, target)
+	if err != nil {
+		return err
+	}
+	if math.IsInf(measured.IntegratedLoudness, 0) || math.IsInf(measured.Threshold, 0) {
+		return errors.New("Video.NormalizeLoudness: the audio is silent")
+	}
+	v.audio.Loudnorm(target, *measured)
+	return nil
+}
+
+// parseLoudnormOutput parses the JSON summary printed by loudnorm=print_format=json.
+func parseLoudnormOutput(logs []byte) (*LoudnessMeasurement, error) {
+	start := bytes.LastIndex(logs, []byte("{"))
+	end := bytes.LastIndex(logs, []byte("}"))
+	if start < 0 || end < start {
+		return nil, errors.New("loudnorm summary not found in ffmpeg output")
+	}
+
+	var summary struct {
+		InputI       string `json:"input_i"`
+		InputTP      string `json:"input_tp"`
+		InputLRA     string `json:"input_lra"`
+		InputThresh  string `json:"input_thresh"`
+		TargetOffset string `json:"target_offset"`
+	}
+	if err := json.Unmarshal(logs[start:end+1], &summary); err != nil {
+		return nil, fmt.Errorf("unable to parse loudnorm summary: %v", err)
+	}
+
+	var measurement LoudnessMeasurement
+	for _, field := range []struct {
+		value string
+		dest  *float64
+	}{
+		{summary.InputI, &measurement.IntegratedLoudness},
+		{summary.InputTP, &measurement.TruePeak},
+		{summary.InputLRA, &measurement.LoudnessRange},
+		{summary.InputThresh, &measurement.Threshold},
+		{summary.TargetOffset, &measurement.TargetOffset},
+	} {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field.value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loudnorm value %q: %v", field.value, err)
+		}
+		*field.dest = value
+	}
+	return &measurement, nil
+}
+
+// SetBackgroundMusic mixes the music at path in the output audio, or replaces it, see BackgroundMusicOptions.
+func (v *EditableVideo) SetBackgroundMusic(path string, options BackgroundMusicOptions) {
+	v.audio.BackgroundMusic(path, options)
+}
+
+// GetAudioGraph returns the audio graph applied to the output video, it can be used to add custom audio filters.
+func (v *EditableVideo) GetAudioGraph() *AudioGraph {
+	return &v.audio
+}
+
+// ExtractAudio renders the output audio only, encoded with codec.
+//
+// bitrate: is in kbit/s, 0 uses the encoder default.
+//
+// progressFn: is optional, if set it will be called every time ffmpeg reports progress.
+func (v *EditableVideo) ExtractAudio(ctx context.Context, outputPath string, codec AudioCodec, bitrate int, progressFn ProgressFunc) error {
+	if !v.hasAudioOutput() && v.audio.IsEmpty() {
+		return errors.New("Video.ExtractAudio: the video has no audio")
+	}
+
+	cmdline := []string{"ffmpeg", "-y"}
+	cmdline = append(cmdline, v.inputSeekArgs()...)
+	cmdline = append(cmdline, "-i", v.filepath)
+	if v.audio.IsEmpty() {
+		cmdline = append(cmdline, "-map", "0:a:0")
+	} else {
+		inputArgs, graph, audioPad := v.audio.Build(v.sourceAudioPad(), 1, v.outputDuration())
+		cmdline = append(cmdline, inputArgs...)
+		cmdline = append(cmdline, "-filter_complex", graph, "-map", "["+audioPad+"]")
+	}
+	cmdline = append(cmdline, v.durationArgs()...)
+	cmdline = append(cmdline, "-vn", "-c:a", string(codec))
+	if bitrate > 0 {
+		cmdline = append(cmdline, "-b:a", fmt.Sprintf("%dk", bitrate))
+	}
+	cmdline = append(cmdline, outputPath)
+
+	if err := runCommand(ctx, cmdline, v.outputDuration(), progressFn); err != nil {
+		return fmt.Errorf("Video.ExtractAudio: ffmpeg failed: %w", err)
+	}
+	return nil
+}
+
+var (
+	silenceStartRegex = regexp.MustCompile(`silence_start:\s*(-?[0-9.]+)`)
+	silenceEndRegex   = regexp.MustCompile(`silence_end:\s*(-?[0-9.]+)`)
+)
+
+// DetectSilence returns the silent sections of the video audio.
+func (v *Video) DetectSilence(ctx context.Context, options SilenceOptions) ([]SilenceInterval, error) {
+	if options.NoiseLevel == 0 {
+		options.NoiseLevel = -50
+	}
+	if options.MinDuration <= 0 {
+		options.MinDuration = 500 * time.Millisecond
+	}
+
+	cmdline := []string{
+		"ffmpeg", "-y",
+		"-i", v.filepath,
+		"-map", "0:a:0",
+		"-af", fmt.Sprintf("silencedetect=noise=%sdB:d=%s", formatFloat(options.NoiseLevel), formatSeconds(options.MinDuration)),
+		"-f", "null", "-",
+	}
+
+	var logs bytes.Buffer
+	if err := runCommandWithLogs(ctx, cmdline, v.duration, nil, &logs); err != nil {
+		return nil, fmt.Errorf("Video.DetectSilence: ffmpeg failed: %w", err)
+	}
+	return parseSilenceDetect(logs.String(), v.duration), nil
+}
+
+// parseSilenceDetect parses the silencedetect logs, a silence still running at the end of the media ends at duration.
+func parseSilenceDetect(logs string, duration time.Duration) []SilenceInterval {
+	var intervals []SilenceInterval
+	var open *SilenceInterval
+	for _, line := range strings.Split(logs, "\n") {
+		if match := silenceStartRegex.FindStringSubmatch(line); match != nil {
+			open = &SilenceInterval{Start: parseSecondsString(match[1]), End: duration}
+			continue
+		}
+		if match := silenceEndRegex.FindStringSubmatch(line); match != nil && open != nil {
+			open.End = parseSecondsString(match[1])
+			intervals = append(intervals, *open)
+			open = nil
+		}
+	}
+	if open != nil {
+		intervals = append(intervals, *open)
+	}
+	return intervals
+}
+
+// TrimSilence trims the leading and trailing silences of the output video, within its current trim.
+// Silences are detected on the input audio, the trim is applied while rendering.
+func (v *EditableVideo) TrimSilence(ctx context.Context, options SilenceOptions) error {
+	silences, err := (*Video)(v).DetectSilence(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := trimSilences(silences, v.start, v.start+v.outputDuration())
+	if err != nil {
+		return fmt.Errorf("Video.TrimSilence: %w", err)
+	}
+	(*Video)(v).Trim(start, end)
+	return nil
+}
+
+// trimSilences returns the bounds of the section [start, end] of the input without its leading and trailing silences.
+func trimSilences(silences []SilenceInterval, start, end time.Duration) (time.Duration, time.Duration, error) {
+	// Tolerate silences detected a few milliseconds after the start or before the end.
+	const tolerance = 50 * time.Millisecond
+	for _, s := range silences {
+		if s.Start <= start+tolerance && s.End > start {
+			start = s.End
+		}
+	}
+	if start >= end-tolerance {
+		return 0, 0, errors.New("the audio is entirely silent")
+	}
+	for _, s := range silences {
+		if s.End >= end-tolerance && s.Start > start && s.Start < end {
+			end = s.Start
+		}
+	}
+	return start, end, nil
+}
+
+// sourceAudioPad returns the stream specifier of the input audio, "" if the output has no input audio.
+func (v *EditableVideo) sourceAudioPad() string {
+	if !v.hasAudioOutput() {
+		return ""
+	}
+	return "0:a:0"
+}
+
+// parseSecondsString converts a string in seconds to time.Duration, 0 is returned for invalid values.
+func parseSecondsString(value string) time.Duration {
+	return secondsToDuration(json.Number(value))
+}
+
+// formatFloat formats a float for ffmpeg options.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,118 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAudioGraphBuild(t *testing.T) {
+	var graph AudioGraph
+	graph.Filter("highpass=f=100").
+		BackgroundMusic("music.mp3", BackgroundMusicOptions{Volume: 0.3, Duck: true, Loop: true})
+
+	inputArgs, filterComplex, outputPad := graph.Build("0:a:0", 2, 0)
+	if !reflect.DeepEqual(inputArgs, []string{"-stream_loop", "-1", "-i", "music.mp3"}) {
+		t.Errorf("unexpected input args: %v", inputArgs)
+	}
+	expected := "[0:a:0]highpass=f=100[a1];" +
+		"[2:a:0]volume=0.3[a2];" +
+		"[a1]asplit=2[a3][a4];" +
+		"[a2][a4]sidechaincompress=threshold=0.05:ratio=8:attack=20:release=400[a5];" +
+		"[a3][a5]amix=inputs=2:duration=first:dropout_transition=0,volume=2[aout]"
+	if filterComplex != expected || outputPad != "aout" {
+		t.Errorf("got  %s\nwant %s", filterComplex, expected)
+	}
+}
+
+func TestAudioGraphBuildReplace(t *testing.T) {
+	var graph AudioGraph
+	graph.BackgroundMusic("music.mp3", BackgroundMusicOptions{Replace: true, Loop: true}).Filter("volume=0.8")
+
+	_, filterComplex, _ := graph.Build("", 1, 12500*time.Millisecond)
+	if expected := "[1:a:0]volume=1,atrim=end=12.5[a1];[a1]volume=0.8[aout]"; filterComplex != expected {
+		t.Errorf("got %s, want %s", filterComplex, expected)
+	}
+}
+
+func TestFilterArgsWithAudioOnly(t *testing.T) {
+	video := (&Video{filepath: "in.mp4", duration: 10 * time.Second, end: 10 * time.Second}).GetEditableVideo()
+	video.GetAudioGraph().Filter("volume=2")
+
+	inputArgs, outputArgs := video.filterArgs(1)
+	expected := []string{"-filter_complex", "[0:a:0]volume=2[aout]", "-map", "0:v?", "-map", "[aout]"}
+	if len(inputArgs) != 0 || !reflect.DeepEqual(outputArgs, expected) {
+		t.Errorf("got %v %v, want %v", inputArgs, outputArgs, expected)
+	}
+}
+
+func TestParseLoudnormOutput(t *testing.T) {
+	logs := `[Parsed_loudnorm_0 @ 0x5581] 
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}`
+	measured, err := parseLoudnormOutput([]byte(logs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := LoudnessMeasurement{IntegratedLoudness: -27.61, TruePeak: -4.47, LoudnessRange: 18.06, Threshold: -39.2, TargetOffset: 0.58}
+	if *measured != expected {
+		t.Errorf("got %+v, want %+v", *measured, expected)
+	}
+}
+
+func TestParseSilenceDetect(t *testing.T) {
+	logs := `[silencedetect @ 0x55] silence_start: 0
+[silencedetect @ 0x55] silence_end: 1.52 | silence_duration: 1.52
+[silencedetect @ 0x55] silence_start: 4.2
+[silencedetect @ 0x55] silence_end: 5 | silence_duration: 0.8
+[silencedetect @ 0x55] silence_start: 9.1`
+
+	expected := []SilenceInterval{
+		{Start: 0, End: 1520 * time.Millisecond},
+		{Start: 4200 * time.Millisecond, End: 5 * time.Second},
+		{Start: 9100 * time.Millisecond, End: 10 * time.Second},
+	}
+	if got := parseSilenceDetect(logs, 10*time.Second); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
+
+func TestTrimSilences(t *testing.T) {
+	silences := []SilenceInterval{
+		{Start: 0, End: 1520 * time.Millisecond},
+		{Start: 4200 * time.Millisecond, End: 5 * time.Second},
+		{Start: 9100 * time.Millisecond, End: 10 * time.Second},
+	}
+	for _, test := range []struct {
+		start, end                 time.Duration
+		expectedStart, expectedEnd time.Duration
+	}{
+		{0, 10 * time.Second, 1520 * time.Millisecond, 9100 * time.Millisecond},
+		// An existing trim is kept, only its own leading and trailing silences are removed.
+		{2 * time.Second, 8 * time.Second, 2 * time.Second, 8 * time.Second},
+		{4500 * time.Millisecond, 8 * time.Second, 5 * time.Second, 8 * time.Second},
+		{3 * time.Second, 4800 * time.Millisecond, 3 * time.Second, 4200 * time.Millisecond},
+	} {
+		start, end, err := trimSilences(silences, test.start, test.end)
+		if err != nil || start != test.expectedStart || end != test.expectedEnd {
+			t.Errorf("trim %v-%v: got %v-%v (%v), want %v-%v", test.start, test.end, start, end, err, test.expectedStart, test.expectedEnd)
+		}
+	}
+
+	if _, _, err := trimSilences([]SilenceInterval{{Start: 0, End: 10 * time.Second}}, 0, 10*time.Second); err == nil {
+		t.Error("expected an error for an entirely silent audio")
+	}
+	if _, _, err := trimSilences(silences, 4300*time.Millisecond, 4900*time.Millisecond); err == nil {
+		t.Error("expected an error for an entirely silent trim")
+	}
+}
@@ -26,3 +26,153 @@ const (
 	Landscape ScreenOrientation = 1
 )
 
+
+// StreamType defines the type of a media stream as reported by ffprobe
+type StreamType string
+
+const (
+	VideoStream      StreamType = "video"
+	AudioStream      StreamType = "audio"
+	SubtitleStream   StreamType = "subtitle"
+	DataStream       StreamType = "data"
+	AttachmentStream StreamType = "attachment"
+)
+
+// TransposeDirection defines the transpose filter direction
+type TransposeDirection byte
+
+const (
+	TransposeCounterClockwiseFlip TransposeDirection = 0 // Rotate by 90 degrees counterclockwise and vertically flip
+	TransposeClockwise            TransposeDirection = 1 // Rotate by 90 degrees clockwise
+	TransposeCounterClockwise     TransposeDirection = 2 // Rotate by 90 degrees counterclockwise
+	TransposeClockwiseFlip        TransposeDirection = 3 // Rotate by 90 degrees clockwise and vertically flip
+)
+
+// filterGraphOutputLabel is the label of the video pad produced by a FilterGraph
+const filterGraphOutputLabel = "vout"
+
+// StreamingFormat defines the adaptive bitrate streaming formats to generate
+type StreamingFormat byte
+
+const (
+	HLS  StreamingFormat = 0 // HLS master playlist with MPEG-TS segments
+	DASH StreamingFormat = 1 // DASH manifest and HLS playlists sharing the same fMP4 segments
+)
+
+// DefaultLadder is the default adaptive bitrate ladder, renditions above the source resolution are skipped.
+var DefaultLadder = []Rendition{
+	{Resolution: 240, VideoBitrate: 400},
+	{Resolution: 480, VideoBitrate: 1000},
+	{Resolution: 720, VideoBitrate: 2800},
+	{Resolution: 1080, VideoBitrate: 5000},
+}
+
+const (
+	hlsMasterPlaylistName = "master.m3u8"
+	dashManifestName      = "manifest.mpd"
+)
+
+// ModerationCategory defines a category of forbidden content
+type ModerationCategory string
+
+const (
+	AdultContent    ModerationCategory = "adult"
+	ViolenceContent ModerationCategory = "violence"
+	RacyContent     ModerationCategory = "racy"
+	MedicalContent  ModerationCategory = "medical"
+	SpoofContent    ModerationCategory = "spoof"
+)
+
+// Likelihood defines how likely a frame contains a ModerationCategory, it follows the Cloud Vision Likelihood scale
+type Likelihood int32
+
+const (
+	LikelihoodUnknown      Likelihood = 0
+	LikelihoodVeryUnlikely Likelihood = 1
+	LikelihoodUnlikely     Likelihood = 2
+	LikelihoodPossible     Likelihood = 3
+	LikelihoodLikely       Likelihood = 4
+	LikelihoodVeryLikely   Likelihood = 5
+)
+
+// SamplingMode defines how frames are selected from a video
+type SamplingMode byte
+
+const (
+	SampleInterval    SamplingMode = 0 // a frame every SamplingOptions.Interval
+	SampleSceneChange SamplingMode = 1 // the first frame and every frame starting a new scene
+	SampleKeyframes   SamplingMode = 2 // every I-frame, the fastest mode as non keyframes are not decoded
+)
+
+// BestThumbnail can be passed as second to GetThumbnailAtSec to pick the least blurry, non-black frame of the video
+const BestThumbnail float64 = -1
+
+// AudioCodec defines the codec used to encode an audio output
+type AudioCodec string
+
+const (
+	AAC  AudioCodec = "aac"
+	MP3  AudioCodec = "libmp3lame"
+	Opus AudioCodec = "libopus"
+	FLAC AudioCodec = "flac"
+)
+
+// audioGraphOutputLabel is the label of the audio pad produced by an AudioGraph
+const audioGraphOutputLabel = "aout"
+
+// SeekMode defines how the start of a trimmed video is located
+type SeekMode byte
+
+const (
+	AccurateSeek SeekMode = 0 // decodes from the previous keyframe and starts exactly at the requested time, requires a re-encode
+	KeyframeSeek SeekMode = 1 // starts at the keyframe preceding the requested time, allows stream copy when no re-encode is needed
+)
+
+// VideoCodec defines the encoder used to encode a video output
+type VideoCodec string
+
+const (
+	H264   VideoCodec = "libx264"
+	HEVC   VideoCodec = "libx265"
+	VP9    VideoCodec = "libvpx-vp9"
+	AV1    VideoCodec = "libaom-av1" // reference AV1 encoder, slow but widely available
+	SVTAV1 VideoCodec = "libsvtav1"  // faster AV1 encoder
+)
+
+// Names of the built-in encoding profiles, see GetEncodingProfile
+const (
+	WebMP4Profile  = "web-mp4" // H.264/AAC in a streamable MP4, plays everywhere
+	WebMProfile    = "webm"    // VP9/Opus in WebM
+	WebAV1Profile  = "web-av1" // AV1/Opus in WebM, smallest files for modern browsers
+	ArchiveProfile = "archive" // high quality 10 bit HEVC/FLAC in Matroska
+)
+
+// PreviewFormat defines the format of an animated preview
+type PreviewFormat byte
+
+const (
+	AnimatedWebP PreviewFormat = 0
+	AnimatedGIF  PreviewFormat = 1
+)
+
+// WatermarkAnchor defines where a watermark is placed on the video
+type WatermarkAnchor byte
+
+const (
+	TopLeft     WatermarkAnchor = 0
+	TopRight    WatermarkAnchor = 1
+	BottomLeft  WatermarkAnchor = 2
+	BottomRight WatermarkAnchor = 3
+	Center      WatermarkAnchor = 4
+)
+
+// JobStatus defines the state of a TranscodeQueue job
+type JobStatus string
+
+const (
+	JobQueued   JobStatus = "queued"
+	JobRunning  JobStatus = "running"
+	JobDone     JobStatus = "done"
+	JobFailed   JobStatus = "failed"
+	JobCanceled JobStatus = "canceled"
+)
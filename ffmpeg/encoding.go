@@ -0,0 +1,183 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// encodingProfiles are the built-in profiles returned by GetEncodingProfile.
+var encodingProfiles = map[string]EncodingProfile{
+	WebMP4Profile: {
+		Name:         WebMP4Profile,
+		VideoCodec:   H264,
+		Format:       "mp4",
+		PixelFormat:  "yuv420p",
+		Preset:       Fast,
+		CRF:          23,
+		AudioCodec:   AAC,
+		AudioBitrate: 128,
+		Streamable:   true,
+	},
+	WebMProfile: {
+		Name:         WebMProfile,
+		VideoCodec:   VP9,
+		Format:       "webm",
+		PixelFormat:  "yuv420p",
+		Preset:       Fast,
+		CRF:          32,
+		AudioCodec:   Opus,
+		AudioBitrate: 96,
+	},
+	WebAV1Profile: {
+		Name:         WebAV1Profile,
+		VideoCodec:   SVTAV1,
+		Format:       "webm",
+		PixelFormat:  "yuv420p",
+		Preset:       Medium,
+		CRF:          35,
+		AudioCodec:   Opus,
+		AudioBitrate: 96,
+	},
+	ArchiveProfile: {
+		Name:        ArchiveProfile,
+		VideoCodec:  HEVC,
+		Format:      "matroska",
+		PixelFormat: "yuv420p10le",
+		Preset:      Slow,
+		CRF:         18,
+		AudioCodec:  FLAC,
+	},
+}
+
+// GetEncodingProfile returns the built-in profile registered under name, e.g WebMP4Profile.
+// The returned profile is a copy and can be customized before calling SetEncodingProfile.
+func GetEncodingProfile(name string) (EncodingProfile, error) {
+	profile, ok := encodingProfiles[name]
+	if !ok {
+		return EncodingProfile{}, fmt.Errorf("unknown encoding profile %q", name)
+	}
+	return profile, nil
+}
+
+// webmCodecs are the codecs the WebM container accepts.
+var webmCodecs = map[string]bool{
+	string(VP9):    true,
+	string(AV1):    true,
+	string(SVTAV1): true,
+	string(Opus):   true,
+	"libvpx":       true,
+	"libvorbis":    true,
+}
+
+// Validate returns an error if the profile can not be encoded.
+func (p EncodingProfile) Validate() error {
+	if p.VideoCodec == "" {
+		return fmt.Errorf("encoding profile %q: missing video codec", p.Name)
+	}
+	if p.CRF < 0 || p.VideoBitrate < 0 || p.GOPSize < 0 || p.AudioBitrate < 0 {
+		return fmt.Errorf("encoding profile %q: negative CRF, bitrate or GOP size", p.Name)
+	}
+	if p.Format == "webm" {
+		if !webmCodecs[string(p.VideoCodec)] {
+			return fmt.Errorf("encoding profile %q: %s can not be muxed in WebM", p.Name, p.VideoCodec)
+		}
+		if p.AudioCodec != "" && !webmCodecs[string(p.AudioCodec)] {
+			return fmt.Errorf("encoding profile %q: %s can not be muxed in WebM", p.Name, p.AudioCodec)
+		}
+	}
+	return nil
+}
+
+// args returns the output options encoding a video with the profile.
+func (p EncodingProfile) args() []string {
+	args := []string{"-c:v", string(p.VideoCodec)}
+	args = append(args, p.presetArgs()...)
+
+	switch {
+	case p.VideoBitrate > 0:
+		args = append(args, "-b:v", fmt.Sprintf("%dk", p.VideoBitrate))
+	case p.CRF > 0:
+		args = append(args, "-crf", strconv.Itoa(p.CRF))
+		// libvpx and libaom only use the constant quality mode without a target bitrate.
+		if p.VideoCodec == VP9 || p.VideoCodec == AV1 {
+			args = append(args, "-b:v", "0")
+		}
+	}
+
+	if p.GOPSize > 0 {
+		args = append(args, "-g", strconv.Itoa(p.GOPSize))
+	}
+	if p.PixelFormat != "" {
+		args = append(args, "-pix_fmt", p.PixelFormat)
+	}
+	// Apple players only decode HEVC in MP4 with the hvc1 tag.
+	if p.VideoCodec == HEVC && (p.Format == "mp4" || p.Format == "mov") {
+		args = append(args, "-tag:v", "hvc1")
+	}
+
+	if p.AudioCodec != "" {
+		args = append(args, "-c:a", string(p.AudioCodec))
+	}
+	if p.AudioBitrate > 0 {
+		args = append(args, "-b:a", fmt.Sprintf("%dk", p.AudioBitrate))
+	}
+
+	if p.Streamable {
+		args = append(args, "-movflags", "+faststart")
+	}
+	if p.Format != "" {
+		args = append(args, "-f", p.Format)
+	}
+	return args
+}
+
+// presetSpeeds maps every ConversionPreset to the speed option of the codecs that do not use x264 presets,
+// from the slowest (best compression) to the fastest.
+var presetSpeeds = map[VideoCodec]map[ConversionPreset]int{
+	VP9:    {Placebo: 0, Veryslow: 0, Slower: 1, Slow: 1, Medium: 2, Fast: 3, Faster: 4, Veryfast: 4, Superfast: 5, Ultrafast: 5},
+	AV1:    {Placebo: 0, Veryslow: 1, Slower: 2, Slow: 3, Medium: 4, Fast: 5, Faster: 5, Veryfast: 6, Superfast: 7, Ultrafast: 8},
+	SVTAV1: {Placebo: 0, Veryslow: 2, Slower: 4, Slow: 5, Medium: 6, Fast: 8, Faster: 9, Veryfast: 10, Superfast: 11, Ultrafast: 12},
+}
+
+// presetArgs returns the options selecting the encoding speed of the profile.
+func (p EncodingProfile) presetArgs() []string {
+	if p.Preset == "" {
+		return nil
+	}
+	speeds, ok := presetSpeeds[p.VideoCodec]
+	if !ok {
+		// x264, x265 and the codecs sharing their presets.
+		return []string{"-preset", string(p.Preset)}
+	}
+	speed, ok := speeds[p.Preset]
+	if !ok {
+		return nil
+	}
+	switch p.VideoCodec {
+	case VP9:
+		return []string{"-deadline", "good", "-cpu-used", strconv.Itoa(speed), "-row-mt", "1"}
+	case AV1:
+		return []string{"-cpu-used", strconv.Itoa(speed), "-row-mt", "1"}
+	default:
+		return []string{"-preset", strconv.Itoa(speed)}
+	}
+}
+
+// SetEncodingProfile defines the codecs, container and quality of the output video, replacing the default H.264 encoding.
+// Options set with SetPreset, SetConstantRateFactor and SetBitrate are placed after the profile options and take precedence.
+func (v *EditableVideo) SetEncodingProfile(profile EncodingProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	v.profile = &profile
+	return nil
+}
+
+// GetEncodingProfile returns the profile used to encode the output video, nil if the default H.264 encoding is used.
+func (v *EditableVideo) GetEncodingProfile() *EncodingProfile {
+	if v.profile == nil {
+		return nil
+	}
+	profile := *v.profile
+	return &profile
+}
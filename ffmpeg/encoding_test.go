@@ -0,0 +1,81 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodingProfileArgs(t *testing.T) {
+	tests := []struct {
+		profile  string
+		expected string
+	}{
+		{WebMP4Profile, "-c:v libx264 -preset fast -crf 23 -pix_fmt yuv420p -c:a aac -b:a 128k -movflags +faststart -f mp4"},
+		{WebMProfile, "-c:v libvpx-vp9 -deadline good -cpu-used 3 -row-mt 1 -crf 32 -b:v 0 -pix_fmt yuv420p -c:a libopus -b:a 96k -f webm"},
+		{WebAV1Profile, "-c:v libsvtav1 -preset 6 -crf 35 -pix_fmt yuv420p -c:a libopus -b:a 96k -f webm"},
+		{ArchiveProfile, "-c:v libx265 -preset slow -crf 18 -pix_fmt yuv420p10le -c:a flac -f matroska"},
+	}
+	for _, test := range tests {
+		profile, err := GetEncodingProfile(test.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := profile.Validate(); err != nil {
+			t.Errorf("%s: %v", test.profile, err)
+		}
+		if got := strings.Join(profile.args(), " "); got != test.expected {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.profile, got, test.expected)
+		}
+	}
+
+	if _, err := GetEncodingProfile("unknown"); err == nil {
+		t.Errorf("expected an error for an unknown profile")
+	}
+}
+
+func TestEncodingProfileBitrateMode(t *testing.T) {
+	profile := EncodingProfile{VideoCodec: AV1, Format: "mp4", Preset: Veryfast, VideoBitrate: 1500, GOPSize: 60}
+	expected := "-c:v libaom-av1 -cpu-used 6 -row-mt 1 -b:v 1500k -g 60 -f mp4"
+	if got := strings.Join(profile.args(), " "); got != expected {
+		t.Errorf("got  %s\nwant %s", got, expected)
+	}
+
+	profile = EncodingProfile{VideoCodec: HEVC, Format: "mp4"}
+	if got := strings.Join(profile.args(), " "); got != "-c:v libx265 -tag:v hvc1 -f mp4" {
+		t.Errorf("unexpected HEVC arguments %s", got)
+	}
+}
+
+func TestEncodingProfileValidate(t *testing.T) {
+	for _, profile := range []EncodingProfile{
+		{},
+		{VideoCodec: H264, Format: "webm"},
+		{VideoCodec: VP9, Format: "webm", AudioCodec: AAC},
+		{VideoCodec: H264, CRF: -1},
+	} {
+		if err := profile.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", profile)
+		}
+	}
+}
+
+func TestCommandLineWithEncodingProfile(t *testing.T) {
+	video := (&Video{filepath: "in.mp4", width: 1280, height: 720}).GetEditableVideo()
+	profile, _ := GetEncodingProfile(WebMProfile)
+	if err := video.SetEncodingProfile(profile); err != nil {
+		t.Fatal(err)
+	}
+	video.SetConstantRateFactor(40)
+
+	expected := []string{
+		"ffmpeg", "-y", "-i", "in.mp4",
+		"-c:v", "libvpx-vp9", "-deadline", "good", "-cpu-used", "3", "-row-mt", "1", "-crf", "32", "-b:v", "0",
+		"-pix_fmt", "yuv420p", "-c:a", "libopus", "-b:a", "96k", "-f", "webm",
+		"-crf", "40",
+		"out.webm",
+	}
+	if got := video.commandLine("out.webm"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+}
@@ -4,21 +4,17 @@ import (
 	"bytes"
 	Vision "cloud.google.com/go/vision/apiv1"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	osUtils "github.com/sabriboughanmi/go_utils/os"
-	"github.com/sabriboughanmi/go_utils/utils"
-	"google.golang.org/protobuf/reflect/protoreflect"
 	"io"
 	"os"
 	"os/exec"
 	"strconv"
-	"sync"
 	"time"
 )
 
-// GetVideoOrientation returns the video Screen Orientation
+// GetVideoOrientation returns the video Screen Orientation, rotation metadata and non square pixels included, see GetDisplayGeometry.
 func (v *Video) GetVideoOrientation() ScreenOrientation {
 	if v.width > v.height {
 		return Landscape
@@ -26,12 +22,12 @@ func (v *Video) GetVideoOrientation() ScreenOrientation {
 	return Portrait
 }
 
-// GetVideoRotate returns the video Screen Orientation
+// GetVideoRotate returns the clockwise rotation of the video in [0-360), nil if the video has no rotation metadata.
 func (v *Video) GetVideoRotate() *int {
 	return v.rotate
 }
 
-// GetEditableVideoResolution returns the lowest value between  width and height
+// GetEditableVideoResolution returns the lowest value between the displayed width and height
 func (v *EditableVideo) GetEditableVideoResolution() VideoResolution {
 	if v.width > v.height {
 		return VideoResolution(v.height)
@@ -39,7 +35,7 @@ func (v *EditableVideo) GetEditableVideoResolution() VideoResolution {
 	return VideoResolution(v.width)
 }
 
-// GetVideoResolution returns the lowest value between  width and height
+// GetVideoResolution returns the lowest value between the displayed width and height
 func (v *Video) GetVideoResolution() VideoResolution {
 	if v.width > v.height {
 		return VideoResolution(v.height)
@@ -47,7 +43,7 @@ func (v *Video) GetVideoResolution() VideoResolution {
 	return VideoResolution(v.width)
 }
 
-// GetAspectRatio returns the Aspect Ratio
+// GetAspectRatio returns the displayed Aspect Ratio, always >= 1
 func (v *EditableVideo) GetAspectRatio() float32 {
 	if v.width > v.height {
 		return float32(v.width) / float32(v.height)
@@ -62,6 +58,18 @@ func (v *Video) GetDuration() float64 {
 
 // GetThumbnailAtSec Creates a Thumbnail at path for a given time
 func (v *Video) GetThumbnailAtSec(outputPath string, second float64) error {
+	return v.GetThumbnailAtSecContext(context.Background(), outputPath, second)
+}
+
+// GetThumbnailAtSecContext Creates a Thumbnail at path for a given time.
+// ffmpeg is killed if ctx is done before the thumbnail is created.
+//
+// second: pass BestThumbnail to pick the least blurry, non-black frame of the video.
+func (v *Video) GetThumbnailAtSecContext(ctx context.Context, outputPath string, second float64) error {
+	if second == BestThumbnail {
+		return v.GetBestThumbnail(ctx, outputPath)
+	}
+
 	cmds := []string{
 		"ffmpeg",
 		"-y",
@@ -72,14 +80,8 @@ func (v *Video) GetThumbnailAtSec(outputPath string, second float64) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(cmds[0], cmds[1:]...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = nil
-
-	err := cmd.Run()
-	if err != nil {
-		return errors.New("Video.Render: ffmpeg failed: " + stderr.String())
+	if err := runCommand(ctx, cmds, 0, nil); err != nil {
+		return fmt.Errorf("Video.Render: ffmpeg failed: %w", err)
 	}
 	return nil
 }
@@ -95,14 +97,8 @@ func (v *Video) GetStreamableVersion(outputPath string) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(cmds[0], cmds[1:]...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = nil
-
-	err := cmd.Run()
-	if err != nil {
-		return errors.New("Video.Render: ffmpeg failed: " + stderr.String())
+	if err := runCommand(context.Background(), cmds, 0, nil); err != nil {
+		return fmt.Errorf("Video.Render: ffmpeg failed: %w", err)
 	}
 	return nil
 }
@@ -111,78 +107,23 @@ type FFmpegError error
 
 var ForbiddenContentError = errors.New("Forbidden Content")
 
-// calculateFramesToModerate returns the number of frames to moderate
-func (v *Video) calculateFramesToModerate(durationStep float64) int {
-	var videoDuration = v.GetDuration()
-	var duration float64 = 0
-	var framesCount = 0
-	for {
-		framesCount++
-		duration += durationStep
-		if durationStep+duration > videoDuration {
-			break
-		}
-	}
-
-	return framesCount
-}
-
-// ModerateVideo verify if a video contain forbidden content
+// ModerateVideo verify if a video contain forbidden content.
+// Frames are moderated every durationStep seconds, Adult and Violence likelihoods must not exceed tolerance.
+// Use Moderate to get a per-frame report or to plug another FrameModerator.
 func (v *Video) ModerateVideo(durationStep float64, ctx context.Context, tolerance int32, imgAnnotClient *Vision.ImageAnnotatorClient) (error, bool) {
-	var framesToModerateCount = v.calculateFramesToModerate(durationStep)
-	fmt.Printf("frames : %d \n", framesToModerateCount)
-	wg := sync.WaitGroup{}
-	var duration float64 = 0
-	errorChannel := make(chan error, framesToModerateCount)
-	for i := 0; i < framesToModerateCount; i++ {
-		wg.Add(1)
-
-		//moderate frame every x sec
-		go func(frameSec float64, waitGroup *sync.WaitGroup, errorChan chan error) {
-			defer func() {
-				waitGroup.Done()
-			}()
-
-			path, err := osUtils.CreateTempFile("pic.png", nil)
-			if err != nil {
-				errorChan <- fmt.Errorf("CreateTempFile: , Error:  %v", err)
-				return
-			}
-
-			defer func(name string) {
-				if err = os.Remove(name); err != nil {
-					fmt.Printf("ModerateVideo Error removing temp file - %v\n", err)
-				}
-			}(path)
-			if err = v.GetThumbnailAtSec(path, frameSec); err != nil {
-				errorChan <- fmt.Errorf("GetThumbnailAtSec %f : , Error:  %v", duration, err)
-			}
-			ok, err := ModerateVideoFrame(path, ctx, tolerance, imgAnnotClient)
-			if err != nil {
-				fmt.Printf("ModerateVideoFrame got an - error : %v\n", err)
-				errorChan <- err
-				return
-			}
-			if !ok {
-				errorChan <- ForbiddenContentError
-				return
-			}
-		}(duration, &wg, errorChannel)
-		duration += durationStep
-	}
-
-	wg.Wait()
-
-	close(errorChannel)
+	var sampling = SamplingOptions{Mode: SampleInterval, Interval: time.Duration(durationStep * float64(time.Second))}
+	report, err := v.Moderate(ctx, sampling, &VisionModerator{Client: imgAnnotClient}, legacyThresholds(tolerance))
+	if err != nil {
+		return err, false
+	}
+	if report.Rejected {
+		return ForbiddenContentError, false
+	}
 
 	var receivedErrors []string
-	for err := range errorChannel {
-		if err == ForbiddenContentError {
-			return err, false
-		}
+	for _, err := range report.Errors() {
 		receivedErrors = append(receivedErrors, err.Error())
 	}
-
 	if len(receivedErrors) > 0 {
 		return fmt.Errorf("Got %d Errors while moderating video - Errors : %s  \n", len(receivedErrors), receivedErrors), false
 	}
@@ -192,24 +133,11 @@ func (v *Video) ModerateVideo(durationStep float64, ctx context.Context, toleran
 // ModerateVideoFrame if verify if an extended frame contain forbidden content.
 // False -> frame contains forbidden content.
 func ModerateVideoFrame(localPath string, ctx context.Context, tolerance int32, client *Vision.ImageAnnotatorClient) (bool, error) {
-	fmt.Printf("Called with image : %s\n", localPath)
-	f, err := os.Open(localPath)
-	if err != nil {
-		return false, fmt.Errorf("os.Open, Error:  %v", err)
-	}
-	defer f.Close()
-
-	image, err := Vision.NewImageFromReader(f)
-	if err != nil {
-		return false, fmt.Errorf("NewImageFromReader, Error:  %v", err)
-	}
-	props, err := client.DetectSafeSearch(ctx, image, nil)
-	if err != nil {
-		return false, fmt.Errorf("DetectSafeSearch, Error:  %v", err)
+	result := evaluateFrame(ctx, Frame{Path: localPath}, &VisionModerator{Client: client}, legacyThresholds(tolerance))
+	if result.Err != nil {
+		return false, result.Err
 	}
-	var tolr = protoreflect.EnumNumber(tolerance)
-
-	if props.Adult.Number() > tolr || props.Violence.Number() > tolr {
+	if len(result.Violations) > 0 {
 		return false, errors.New("frame contain forbidden content")
 	}
 
@@ -220,121 +148,61 @@ func ModerateVideoFrame(localPath string, ctx context.Context, tolerance int32,
 // or load it into memory. Apply operations to the Video and call Render to
 // generate the output video file.
 func LoadVideo(path string) (*Video, error) {
-	if _, err := exec.LookPath("ffprobe"); err != nil {
-		return nil, errors.New("cinema.Load: ffprobe was not found in your PATH " +
-			"environment variable, make sure to install ffmpeg " +
-			"(https://ffmpeg.org/) and add ffmpeg, ffplay and ffprobe to your " +
-			"PATH")
-	}
-
 	if _, err := os.Stat(path); err != nil {
 		return nil, errors.New("cinema.Load: unable to load file: " + err.Error())
 	}
 
-	cmdArgs := []string{"ffprobe",
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_format",
-		"-show_streams",
-		path}
-
-	fmt.Println(cmdArgs)
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-
-	var stderr bytes.Buffer
-	var stdout bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = nil
-	out, err := cmd.Output()
-
+	info, err := ProbeMediaInfo(path)
 	if err != nil {
-		return nil, errors.New("Load: ffprobe failed with Error: " + stderr.String() + stdout.String())
-	}
-
-	type description struct {
-		Streams []struct {
-			Width  int `json:"width"`
-			Height int `json:"height"`
-			Tags   struct {
-				// Rotation is optional -> use a pointer.
-				Rotation *json.Number `json:"rotate"`
-			} `json:"tags"`
-		} `json:"streams"`
-		Format struct {
-			DurationSec json.Number `json:"duration"`
-			Bitrate     json.Number `json:"bit_rate"`
-		} `json:"format"`
-	}
-	var desc description
-	if err := json.Unmarshal(out, &desc); err != nil {
-		return nil, errors.New("cinema.vc: unable to parse JSON output " +
-			"from ffprobe: " + err.Error())
-	}
-	if len(desc.Streams) == 0 {
+		return nil, errors.New("cinema.Load: " + err.Error())
+	}
+	if len(info.Streams) == 0 {
 		return nil, errors.New("cinema.Load: ffprobe does not contain stream " +
 			"data, make sure the file " + path + " contains a valid video.")
 	}
 
-	secs, err := desc.Format.DurationSec.Float64()
-	if err != nil {
-		return nil, errors.New("cinema.Load: ffprobe returned invalid duration: " +
-			err.Error())
+	if info.Format.Duration <= 0 {
+		return nil, errors.New("cinema.Load: ffprobe returned invalid duration")
 	}
-	bitrate, err := desc.Format.Bitrate.Int64()
-	if err != nil {
-		return nil, errors.New("cinema.Load: ffprobe returned invalid duration: " +
-			err.Error())
+
+	return newVideoFromMediaInfo(path, info), nil
+}
+
+// newVideoFromMediaInfo returns a Video described by the ffprobe MediaInfo.
+func newVideoFromMediaInfo(path string, info *MediaInfo) *Video {
+	var video = Video{
+		filepath: path,
+		fps:      30,
+		bitrate:  int(info.Format.BitRate),
+		start:    0,
+		end:      info.Format.Duration,
+		duration: info.Format.Duration,
+		info:     info,
 	}
 
-	// Round seconds (floating point value) up to time.Duration. seconds will
-	// be >= 0 so adding 0.5 rounds to the right integer Duration value.
-	duration := time.Duration(secs*float64(time.Second) + 0.5)
+	stream := info.PrimaryVideoStream()
+	if stream == nil {
+		return &video
+	}
 
-	dsIndex := 0
-	for index, v := range desc.Streams {
-		if v.Width != 0 && v.Height != 0 {
-			dsIndex = index
-			break
-		}
+	// ffmpeg rotates the decoded frames, cropping etc. works on the displayed size.
+	video.geometry = stream.DisplayGeometry()
+	video.width = video.geometry.Width
+	video.height = video.geometry.Height
+	if stream.AvgFrameRate > 0 {
+		video.fps = int(stream.AvgFrameRate + 0.5)
 	}
-	width := desc.Streams[dsIndex].Width
-	height := desc.Streams[dsIndex].Height
-	if desc.Streams[dsIndex].Tags.Rotation != nil {
-		// If the video is rotated by -270, -90, 90 or 270 degrees, we need to
-		// flip the width and height because they will be reported in unrotated
-		// coordinates while cropping etc. works on the rotated dimensions.
-		rotation, err := desc.Streams[dsIndex].Tags.Rotation.Int64()
-		if err != nil {
-			return nil, errors.New("cinema.Load: ffprobe returned invalid " +
-				"rotation: " + err.Error())
-		}
-		flipCount := rotation / 90
-		if flipCount%2 != 0 {
-			width, height = height, width
-		}
+
+	if _, ok := stream.Tags["rotate"]; ok || stream.DisplayMatrix != nil {
+		rotation := video.geometry.Rotation
+		video.rotate = &rotation
 	}
+	return &video
+}
 
-	return &Video{
-		filepath: path,
-		width:    width,
-		height:   height,
-		fps:      30,
-		bitrate:  int(bitrate),
-		rotate: func() *int {
-			if desc.Streams[dsIndex].Tags.Rotation == nil {
-				return nil
-			}
-			rotation, err := desc.Streams[dsIndex].Tags.Rotation.Int64()
-			if err != nil {
-				return nil
-			}
-			var rotationInt = int(rotation)
-			return &rotationInt
-		}(),
-		start:    0,
-		end:      duration,
-		duration: duration,
-	}, nil
+// GetMediaInfo returns the typed ffprobe description of the input video, streams and container tags included.
+func (v *Video) GetMediaInfo() *MediaInfo {
+	return v.info
 }
 
 // LoadVideoFromFragments returns a Video that can be operated on. Load does not open the file or load it into memory.
@@ -368,14 +236,8 @@ func LoadVideoFromFragments(path string, fragmentsPath ...string) (*Video, error
 		path,
 	}
 
-	cmd := exec.Command(cmdline[0], cmdline[1:]...)
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = nil
-
-	if err = cmd.Run(); err != nil {
-		return nil, fmt.Errorf(stderr.String())
+	if err = runCommand(context.Background(), cmdline, 0, nil); err != nil {
+		return nil, err
 	}
 
 	return LoadVideo(path)
@@ -387,100 +249,15 @@ func LoadVideoFromFragments(path string, fragmentsPath ...string) (*Video, error
 //
 // Note! path and Fragments need to be already Existing.
 //
-// Note! this function will ReEncode all fragments to Fix any Resolution/Rotation problem
+// Note! fragments are only re-encoded if their codecs, resolution or rotation differ, see MergeFragments.
 func MergeFragmentsFragments(outputPath string, deleteFragments bool, fragmentsPath ...string) error {
+	return MergeFragmentsFragmentsContext(context.Background(), outputPath, deleteFragments, fragmentsPath...)
+}
 
-	if len(fragmentsPath) < 2 {
-		return fmt.Errorf("at least 2 fragments must be passed")
-	}
-
-	wg := sync.WaitGroup{}
-	var errChannel = make(chan error)
-	var tsFragmentsPaths = make([]string, len(fragmentsPath))
-
-	for i, fragmentPath := range fragmentsPath {
-		wg.Add(1)
-
-		go func(path string, index int, waitGroup *sync.WaitGroup, errChan chan error) {
-			defer func() {
-				wg.Done()
-				//Delete Fragment if requested
-				if deleteFragments {
-					osUtils.RemovePathIfExists(path)
-				}
-			}()
-			var tsFragmentPath, err = osUtils.CreateTempFile("file.ts", nil)
-			if err != nil {
-				errChan <- err
-				return
-			}
-
-			cmdline := []string{
-				"ffmpeg",
-				"-y",
-				"-i",
-				path,
-				"-c:v",
-				"libx264",
-				"-preset",
-				"Superfast",
-				"-f",
-				"mpegts",
-				tsFragmentPath,
-			}
-			tsFragmentsPaths[index] = tsFragmentPath
-
-			cmd := exec.Command(cmdline[0], cmdline[1:]...)
-
-			var stderr bytes.Buffer
-			cmd.Stderr = &stderr
-			cmd.Stdout = nil
-
-			if err := cmd.Run(); err != nil {
-				errChan <- err
-				return
-			}
-
-		}(fragmentPath, i, &wg, errChannel)
-	}
-
-	if err := utils.HandleGoroutineErrors(&wg, errChannel); err != nil {
-		return err
-	}
-
-	//Concat
-	var tsConcat = fmt.Sprintf("concat:%s", tsFragmentsPaths[0])
-	for i := 1; i < len(tsFragmentsPaths); i++ {
-		tsConcat += "|"
-		tsConcat += tsFragmentsPaths[i]
-	}
-	//tsConcat += "\""
-
-	cmdline := []string{
-		"ffmpeg",
-		"-y",
-		"-i",
-		tsConcat,
-		"-c", "copy",
-		outputPath,
-	}
-
-	cmd := exec.Command(cmdline[0], cmdline[1:]...)
-
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = nil
-
-	//Remove generated TS Files
-	defer func() {
-		osUtils.RemovePathsIfExists(tsFragmentsPaths...)
-	}()
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf(stderr.String())
-	}
-
-	return nil
+// MergeFragmentsFragmentsContext is MergeFragmentsFragments with a ctx, all running ffmpeg processes are killed when ctx is done.
+func MergeFragmentsFragmentsContext(ctx context.Context, outputPath string, deleteFragments bool, fragmentsPath ...string) error {
+	_, err := MergeFragments(ctx, outputPath, fragmentsPath, MergeOptions{DeleteFragments: deleteFragments})
+	return err
 }
 
 // LoadVideoFromReEncodedFragments returns a merged Video that can be operated on.
@@ -516,51 +293,36 @@ func (v *EditableVideo) executeCommandInBackground(command []string, consoleOutp
 func (v *Video) GetEditableVideo() *EditableVideo {
 	var eVideo = EditableVideo(*v)
 
-	eVideo.filters = make([]string, len(v.filters))
-	copy(eVideo.filters, v.filters)
+	eVideo.filters = v.filters.Clone()
+	eVideo.audio = v.audio.Clone()
 
 	eVideo.additionalArgs = make([]string, len(v.additionalArgs))
 	copy(eVideo.additionalArgs, v.additionalArgs)
 
+	eVideo.subtitles = make([]SubtitleTrack, len(v.subtitles))
+	copy(eVideo.subtitles, v.subtitles)
+
 	return &eVideo
 }
 
-// AddWaterMark Adds a Water mark to a video
-func (v *EditableVideo) AddWaterMark(videoPath, iconPath, outputPath string, widthSize, heightSize int) error {
-
-	cmdline := []string{
-		"ffmpeg",
-		"-y",
-		"-i", videoPath,
-		"-i", iconPath,
-		//Places the watermark at the bottom right corner with a 10-pixel offset from the edges.
-		"-filter_complex", "overlay=W-w-10:H-h-10",
-		//Copies the audio stream without re-encoding.
-		"-codec:a", "copy",
-		"-vcodec", "libx264",
-	}
-	cmdline = append(cmdline, v.additionalArgs...)
-	cmdline = append(cmdline, "-filter_complex")
-	cmdline = append(cmdline, fmt.Sprintf("[1]scale=%d:%d[wm];[0][wm]overlay=10:10", widthSize, heightSize))
-	cmdline = append(cmdline, outputPath)
-
-	//fmt.Println(cmdline)
-	cmd := exec.Command(cmdline[0], cmdline[1:]...)
-
-	var stderr bytes.Buffer
-
-	cmd.Stderr = &stderr
-	cmd.Stdout = nil
-
-	err := cmd.Run()
-	if err != nil {
-		return errors.New("Video.Render: ffmpeg failed: " + stderr.String())
-	}
-	return nil
+// AddWaterMarkContext renders the video to outputPath with the image at iconPath resized to widthSize x heightSize
+// and placed at the top-left corner. Use AddWatermark to position, resize or animate the watermark.
+// ffmpeg is killed if ctx is done before the output is rendered, progressFn is optional.
+func (v *EditableVideo) AddWaterMarkContext(ctx context.Context, iconPath, outputPath string, widthSize, heightSize int, progressFn ProgressFunc) error {
+	//The watermark is overlaid after the operations already applied to the video.
+	watermarked := (*Video)(v).GetEditableVideo()
+	watermarked.filters.Overlay(iconPath, "10", "10", nil, fmt.Sprintf("scale=%d:%d", widthSize, heightSize))
+	return watermarked.RenderContext(ctx, outputPath, progressFn)
 }
 
 // ConvertFromTo Converts any media file type to another
 func ConvertFromTo(inputPath, outputPath string) error {
+	return ConvertFromToContext(context.Background(), inputPath, outputPath, nil)
+}
+
+// ConvertFromToContext Converts any media file type to another.
+// ffmpeg is killed if ctx is done before the conversion ends, progressFn is optional.
+func ConvertFromToContext(ctx context.Context, inputPath, outputPath string, progressFn ProgressFunc) error {
 	cmds := []string{
 		"ffmpeg",
 		"-y",
@@ -568,14 +330,16 @@ func ConvertFromTo(inputPath, outputPath string) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(cmds[0], cmds[1:]...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = nil
+	// The input duration is only required to report the progress percentage.
+	var total time.Duration
+	if progressFn != nil {
+		if info, err := ProbeMediaInfo(inputPath); err == nil {
+			total = info.Format.Duration
+		}
+	}
 
-	err := cmd.Run()
-	if err != nil {
-		return errors.New("Video.Render: ffmpeg failed: " + stderr.String())
+	if err := runCommand(ctx, cmds, total, progressFn); err != nil {
+		return fmt.Errorf("Video.Render: ffmpeg failed: %w", err)
 	}
 	return nil
 }
@@ -604,14 +368,8 @@ func (v *EditableVideo) GetThumbnail(outputPath string, second float64) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(cmds[0], cmds[1:]...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = nil
-
-	err := cmd.Run()
-	if err != nil {
-		return errors.New("Video.Render: ffmpeg failed: " + stderr.String())
+	if err := runCommand(context.Background(), cmds, 0, nil); err != nil {
+		return fmt.Errorf("Video.Render: ffmpeg failed: %w", err)
 	}
 	return nil
 }
@@ -620,12 +378,24 @@ func (v *EditableVideo) GetThumbnail(outputPath string, second float64) error {
 // of the given name. This method won't return anything on stdout / stderr.
 // If you need to read ffmpeg's outputs, use RenderWithStreams
 func (v *EditableVideo) Render(output string) error {
-	return v.RenderWithStreams(output, nil, nil)
+	return v.RenderContext(context.Background(), output, nil)
+}
+
+// RenderContext applies all operations to the Video and creates an output video file
+// of the given name. ffmpeg is killed if ctx is done before the output is rendered.
+//
+// progressFn: is optional, if set it will be called every time ffmpeg reports progress.
+func (v *EditableVideo) RenderContext(ctx context.Context, output string, progressFn ProgressFunc) error {
+	if err := runCommand(ctx, v.commandLine(output), v.outputDuration(), progressFn); err != nil {
+		return fmt.Errorf("Video.Render: ffmpeg failed: %w", err)
+	}
+	return nil
 }
 
 // RenderInBackground applies all operations to the Video and creates an output video file
 // of the given name. This method won't return anything on stdout / stderr.
 // If you need to read ffmpeg's outputs, use RenderWithStreams
+// Renders are not limited, use a TranscodeQueue to avoid overloading the machine with concurrent renders.
 func (v *EditableVideo) RenderInBackground(output string) (*exec.Cmd, error) {
 	return v.RenderWithStreamsInBackground(output, nil)
 }
@@ -655,15 +425,13 @@ func (v *EditableVideo) RenderWithStreamsInBackground(output string, os io.Write
 // ffmpeg's stdout and stderr.
 func (v *EditableVideo) RenderWithStreams(output string, os io.Writer, es io.Writer) error {
 	line := v.commandLine(output)
-	fmt.Println(line)
 
-	cmd := exec.Command(line[0], line[1:]...)
 	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = os
-
-	err := cmd.Run()
-	if err != nil {
+	var stderrWriter io.Writer = &stderr
+	if es != nil {
+		stderrWriter = io.MultiWriter(&stderr, es)
+	}
+	if err := GetRunner().Run(context.Background(), line, os, stderrWriter); err != nil {
 		return errors.New("Video.Render: ffmpeg failed: " + stderr.String())
 	}
 	return nil
@@ -714,12 +482,19 @@ func (v *Video) SetEnd(end time.Duration) {
 	}
 }
 
+// SetSeekMode defines how the start of the trimmed video is located, see SeekMode.
+// With KeyframeSeek, a trim without any other operation is rendered using stream copy.
+func (v *Video) SetSeekMode(mode SeekMode) {
+	v.seekMode = mode
+}
+
 // SetFPS sets the framerate (frames per second) of the output video.
 func (v *Video) SetFPS(fps int) {
 	v.fps = fps
+	v.fpsSet = true
 }
 
-// SetBitrate sets the bitrate of the output video.
+// SetBitrate sets the bitrate (bits per second) of the output video.
 func (v *Video) SetBitrate(bitrate int) {
 	v.bitrate = bitrate
 }
@@ -728,8 +503,38 @@ func (v *Video) SetBitrate(bitrate int) {
 func (v *EditableVideo) SetSize(width int, height int) {
 	v.width = width
 	v.height = height
-	v.additionalArgs = append(v.additionalArgs, "-s")
-	v.additionalArgs = append(v.additionalArgs, fmt.Sprintf("%dx%d", width, height))
+	v.filters.Scale(width, height)
+}
+
+// Pad extends the output video to width x height placing the video at (x,y), the added area is filled with color (e.g "black").
+func (v *EditableVideo) Pad(width, height, x, y int, color string) {
+	v.width = width
+	v.height = height
+	v.filters.Pad(width, height, x, y, color)
+}
+
+// Rotate rotates the output video clockwise by degrees.
+func (v *EditableVideo) Rotate(degrees int) {
+	if ((degrees%180)+180)%180 == 90 {
+		v.width, v.height = v.height, v.width
+	}
+	v.filters.Rotate(degrees)
+}
+
+// DrawText draws a text over the output video.
+func (v *EditableVideo) DrawText(options DrawTextOptions) {
+	v.filters.DrawText(options)
+}
+
+// Overlay places the media at path over the output video at (x,y). x and y are ffmpeg expressions, e.g "W-w-10".
+// See FilterGraph.Overlay for inputArgs and inputFilters.
+func (v *EditableVideo) Overlay(path string, x, y string, inputArgs []string, inputFilters ...string) {
+	v.filters.Overlay(path, x, y, inputArgs, inputFilters...)
+}
+
+// GetFilterGraph returns the filter graph applied to the output video, it can be used to add custom filters.
+func (v *EditableVideo) GetFilterGraph() *FilterGraph {
+	return &v.filters
 }
 
 // SetStreamable makes the rendered video as Streamable by moving the MetaData to the start of the video.
@@ -789,10 +594,7 @@ func (v *Video) SetFilePath(p string) {
 func (v *Video) Crop(x, y, width, height int) {
 	v.width = width
 	v.height = height
-	v.filters = append(
-		v.filters,
-		fmt.Sprintf("crop=%d:%d:%d:%d", width, height, x, y),
-	)
+	v.filters.Crop(x, y, width, height)
 }
 
 // Filepath returns the path of the input video.
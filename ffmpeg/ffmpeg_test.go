@@ -6,6 +6,8 @@ import (
 	"context"
 	"fmt"
 	"google.golang.org/api/option"
+	"os"
+	"os/exec"
 	"sync"
 	"testing"
 	"time"
@@ -42,7 +44,23 @@ func duration(msg string, start time.Time) {
 	fmt.Printf("%v: %v\n", msg, time.Since(start))
 }
 
+// skipIfUnavailable skips tests that require the ffmpeg binaries or local sample files.
+func skipIfUnavailable(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, bin := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not found in PATH", bin)
+		}
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Skipf("sample file not available: %v", err)
+		}
+	}
+}
+
 func TestModerateVideo(t *testing.T) {
+	skipIfUnavailable(t, "C:/Users/T4ULabs/Downloads/vd.mp4", serviceAccountPath)
 
 	vid, err := LoadVideo("C:/Users/T4ULabs/Downloads/vd.mp4")
 	if err != nil {
@@ -64,8 +82,9 @@ func TestModerateVideo(t *testing.T) {
 }
 
 func TestLoadVideoFromReEncodedFragments(t *testing.T) {
+	skipIfUnavailable(t, "C:\\Users\\Sabri\\Downloads\\Video\\1.mp4", "C:\\Users\\Sabri\\Downloads\\Video\\2.mp4")
 
-	video, err := LoadVideoFromReEncodedFragments("C:\\Users\\Sabri\\Downloads\\Video\\output.mp4", false,
+	video, err := LoadVideoFromReEncodedFragments("C:\\Users\\Sabri\\Downloads\\Video\\output.mp4",
 		"C:\\Users\\Sabri\\Downloads\\Video\\1.mp4", "C:\\Users\\Sabri\\Downloads\\Video\\2.mp4")
 
 	if err != nil {
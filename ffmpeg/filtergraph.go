@@ -0,0 +1,299 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filterNode is a single operation of a FilterGraph.
+type filterNode struct {
+	filter string // e.g "crop=100:100:0:0", or the overlay filter "overlay=10:10"
+
+	// Only set for nodes that overlay an extra input on the video.
+	input        string   // path of the extra input
+	inputArgs    []string // input options placed before -i, e.g "-loop 1"
+	inputFilters []string // filters applied to the extra input before it is overlaid
+}
+
+// FilterGraph builds a single -filter_complex graph with labeled pads from an ordered list of video operations.
+// Operations are applied in the order they are added, the zero value is an empty graph ready to use.
+type FilterGraph struct {
+	nodes []filterNode
+}
+
+// Clone returns a deep copy of the graph.
+func (g *FilterGraph) Clone() FilterGraph {
+	var clone = FilterGraph{nodes: make([]filterNode, len(g.nodes))}
+	copy(clone.nodes, g.nodes)
+	return clone
+}
+
+// IsEmpty returns true if no operation was added to the graph.
+func (g *FilterGraph) IsEmpty() bool {
+	return len(g.nodes) == 0
+}
+
+// Filter appends a raw ffmpeg video filter, e.g "hflip" or "eq=brightness=0.1".
+func (g *FilterGraph) Filter(filter string) *FilterGraph {
+	g.nodes = append(g.nodes, filterNode{filter: filter})
+	return g
+}
+
+// Crop keeps the width x height sub-rectangle at (x,y). (0,0) is the top-left of the video.
+func (g *FilterGraph) Crop(x, y, width, height int) *FilterGraph {
+	return g.Filter(fmt.Sprintf("crop=%d:%d:%d:%d", width, height, x, y))
+}
+
+// Scale resizes the video, a width or height of -2 keeps the aspect ratio with an even size.
+func (g *FilterGraph) Scale(width, height int) *FilterGraph {
+	return g.Filter(fmt.Sprintf("scale=%d:%d", width, height))
+}
+
+// Pad extends the video to width x height placing the input at (x,y), the added area is filled with color (e.g "black").
+func (g *FilterGraph) Pad(width, height, x, y int, color string) *FilterGraph {
+	if color == "" {
+		color = "black"
+	}
+	return g.Filter(fmt.Sprintf("pad=%d:%d:%d:%d:color=%s", width, height, x, y, color))
+}
+
+// Transpose applies the ffmpeg transpose filter.
+func (g *FilterGraph) Transpose(direction TransposeDirection) *FilterGraph {
+	return g.Filter(fmt.Sprintf("transpose=%d", direction))
+}
+
+// Rotate rotates the video clockwise. Multiples of 90 degrees are lossless transpositions,
+// any other angle uses the rotate filter and keeps the input size.
+func (g *FilterGraph) Rotate(degrees int) *FilterGraph {
+	switch ((degrees % 360) + 360) % 360 {
+	case 0:
+		return g
+	case 90:
+		return g.Transpose(TransposeClockwise)
+	case 180:
+		return g.Filter("hflip").Filter("vflip")
+	case 270:
+		return g.Transpose(TransposeCounterClockwise)
+	}
+	return g.Filter(fmt.Sprintf("rotate=%d*PI/180", degrees))
+}
+
+// FPS converts the video to a constant frame rate.
+func (g *FilterGraph) FPS(fps int) *FilterGraph {
+	return g.Filter(fmt.Sprintf("fps=%d", fps))
+}
+
+// Trim keeps the [start,end[ section of the video and resets its timestamps.
+func (g *FilterGraph) Trim(start, end time.Duration) *FilterGraph {
+	return g.Filter(fmt.Sprintf("trim=start=%s:end=%s", formatSeconds(start), formatSeconds(end))).
+		Filter("setpts=PTS-STARTPTS")
+}
+
+// DrawText draws a text over the video.
+func (g *FilterGraph) DrawText(options DrawTextOptions) *FilterGraph {
+	return g.Filter(options.filter())
+}
+
+// Overlay places the media at path over the video at (x,y). x and y are ffmpeg expressions, e.g "W-w-10".
+//
+// inputArgs: are the options applied to the overlaid input, e.g []string{"-loop", "1"}.
+//
+// inputFilters: are applied to the overlaid input before placing it, e.g "scale=100:-1".
+func (g *FilterGraph) Overlay(path string, x, y string, inputArgs []string, inputFilters ...string) *FilterGraph {
+	return g.overlay(path, fmt.Sprintf("overlay=%s:%s", x, y), inputArgs, inputFilters)
+}
+
+// overlay places the media at path over the video using a custom overlay filter description.
+func (g *FilterGraph) overlay(path string, filter string, inputArgs []string, inputFilters []string) *FilterGraph {
+	g.nodes = append(g.nodes, filterNode{
+		filter:       filter,
+		input:        path,
+		inputArgs:    inputArgs,
+		inputFilters: inputFilters,
+	})
+	return g
+}
+
+// Build returns the extra inputs command line arguments, the -filter_complex value and the label of the output pad.
+//
+// videoPad: is the stream specifier of the video to filter, e.g "0:v".
+//
+// firstInputIndex: is the index ffmpeg will assign to the first extra input.
+func (g *FilterGraph) Build(videoPad string, firstInputIndex int) (inputArgs []string, graph string, outputLabel string) {
+	var (
+		chains  []string
+		pending []string
+		current = videoPad
+		label   = 0
+		input   = firstInputIndex
+	)
+
+	nextLabel := func(prefix string) string {
+		label++
+		return fmt.Sprintf("%s%d", prefix, label)
+	}
+
+	// flush closes the pending linear chain into a labeled pad.
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		out := nextLabel("v")
+		chains = append(chains, fmt.Sprintf("[%s]%s[%s]", current, strings.Join(pending, ","), out))
+		current = out
+		pending = nil
+	}
+
+	for _, node := range g.nodes {
+		if node.input == "" {
+			pending = append(pending, escapeFilterGraph(node.filter))
+			continue
+		}
+		flush()
+
+		inputArgs = append(inputArgs, node.inputArgs...)
+		inputArgs = append(inputArgs, "-i", node.input)
+		overlayPad := fmt.Sprintf("%d:v", input)
+		input++
+
+		if len(node.inputFilters) > 0 {
+			var filters = make([]string, len(node.inputFilters))
+			for i, f := range node.inputFilters {
+				filters[i] = escapeFilterGraph(f)
+			}
+			out := nextLabel("ov")
+			chains = append(chains, fmt.Sprintf("[%s]%s[%s]", overlayPad, strings.Join(filters, ","), out))
+			overlayPad = out
+		}
+
+		out := nextLabel("v")
+		chains = append(chains, fmt.Sprintf("[%s][%s]%s[%s]", current, overlayPad, escapeFilterGraph(node.filter), out))
+		current = out
+	}
+
+	// Always end the graph with a named pad, "null" is a pass-through filter.
+	if len(pending) == 0 && current == videoPad {
+		pending = append(pending, "null")
+	}
+	if len(pending) > 0 {
+		chains = append(chains, fmt.Sprintf("[%s]%s[%s]", current, strings.Join(pending, ","), filterGraphOutputLabel))
+		current = filterGraphOutputLabel
+	} else {
+		// rename the last pad to the output label.
+		last := chains[len(chains)-1]
+		chains[len(chains)-1] = strings.TrimSuffix(last, "["+current+"]") + "[" + filterGraphOutputLabel + "]"
+		current = filterGraphOutputLabel
+	}
+
+	return inputArgs, strings.Join(chains, ";"), current
+}
+
+// Subtitles burns the subtitles file at path (SRT, WebVTT or ASS) into the video.
+func (g *FilterGraph) Subtitles(path string, options SubtitleOptions) *FilterGraph {
+	var filter = "subtitles=filename=" + escapeFilterOption(path)
+	if strings.EqualFold(filepath.Ext(path), ".ass") || strings.EqualFold(filepath.Ext(path), ".ssa") {
+		// ASS files carry their own styles, the ass filter renders them as is.
+		filter = "ass=filename=" + escapeFilterOption(path)
+	} else {
+		if options.Charset != "" {
+			filter += ":charenc=" + escapeFilterOption(options.Charset)
+		}
+		if options.ForceStyle != "" {
+			filter += ":force_style=" + escapeFilterOption(options.ForceStyle)
+		}
+	}
+	if options.FontsDir != "" {
+		filter += ":fontsdir=" + escapeFilterOption(options.FontsDir)
+	}
+	return g.Filter(filter)
+}
+
+// filter returns the drawtext filter description.
+func (o DrawTextOptions) filter() string {
+	var opts = []string{"text=" + escapeFilterOption(drawTextEscaper.Replace(o.Text))}
+	if o.FontFile != "" {
+		opts = append(opts, "fontfile="+escapeFilterOption(o.FontFile))
+	}
+
+	fontSize := o.FontSize
+	if fontSize <= 0 {
+		fontSize = 24
+	}
+	fontColor := o.FontColor
+	if fontColor == "" {
+		fontColor = "white"
+	}
+	x, y := o.X, o.Y
+	if x == "" {
+		x = "10"
+	}
+	if y == "" {
+		y = "10"
+	}
+
+	opts = append(opts,
+		"fontsize="+strconv.Itoa(fontSize),
+		"fontcolor="+escapeFilterOption(fontColor),
+		"x="+escapeFilterOption(x),
+		"y="+escapeFilterOption(y),
+	)
+
+	if o.Box {
+		boxColor := o.BoxColor
+		if boxColor == "" {
+			boxColor = "black@0.5"
+		}
+		opts = append(opts, "box=1", "boxcolor="+escapeFilterOption(boxColor))
+		if o.BoxBorderWidth > 0 {
+			opts = append(opts, "boxborderw="+strconv.Itoa(o.BoxBorderWidth))
+		}
+	}
+	if o.BorderWidth > 0 {
+		borderColor := o.BorderColor
+		if borderColor == "" {
+			borderColor = "black"
+		}
+		opts = append(opts, "borderw="+strconv.Itoa(o.BorderWidth), "bordercolor="+escapeFilterOption(borderColor))
+	}
+
+	if enable := timelineExpression(o.Start, o.End); enable != "" {
+		opts = append(opts, "enable="+escapeFilterOption(enable))
+	}
+	return "drawtext=" + strings.Join(opts, ":")
+}
+
+// timelineExpression returns the timeline editing expression enabling a filter between start and end,
+// empty if the filter is always enabled. An end of 0 enables the filter until the end of the video.
+func timelineExpression(start, end time.Duration) string {
+	switch {
+	case end > 0:
+		return fmt.Sprintf("between(t,%s,%s)", formatSeconds(start), formatSeconds(end))
+	case start > 0:
+		return fmt.Sprintf("gte(t,%s)", formatSeconds(start))
+	}
+	return ""
+}
+
+// formatSeconds formats a duration as seconds for ffmpeg options.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// escapeFilterOption escapes a filter option value, see https://ffmpeg.org/ffmpeg-filters.html#Notes-on-filtergraph-escaping
+func escapeFilterOption(value string) string {
+	return filterOptionEscaper.Replace(value)
+}
+
+// escapeFilterGraph escapes a filter description to be used in a filter graph.
+func escapeFilterGraph(value string) string {
+	return filterGraphEscaper.Replace(value)
+}
+
+var (
+	drawTextEscaper     = strings.NewReplacer(`\`, `\\`, `%`, `\%`)
+	filterOptionEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`)
+	filterGraphEscaper  = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`)
+)
@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterGraphBuild(t *testing.T) {
+	var graph FilterGraph
+	graph.Crop(10, 20, 640, 360).
+		Scale(1280, 720).
+		Overlay("logo.png", "W-w-10", "H-h-10", []string{"-loop", "1"}, "scale=100:-1").
+		DrawText(DrawTextOptions{Text: "it's 100%: ok", X: "10", Y: "h-th-10"}).
+		Rotate(90)
+
+	inputArgs, filterComplex, outputPad := graph.Build("0:v", 1)
+
+	if !reflect.DeepEqual(inputArgs, []string{"-loop", "1", "-i", "logo.png"}) {
+		t.Errorf("unexpected input args: %v", inputArgs)
+	}
+	expected := `[0:v]crop=640:360:10:20,scale=1280:720[v1];` +
+		`[1:v]scale=100:-1[ov2];` +
+		`[v1][ov2]overlay=W-w-10:H-h-10[v3];` +
+		`[v3]drawtext=text=it\\\'s 100\\\\%\\: ok:fontsize=24:fontcolor=white:x=10:y=h-th-10,transpose=1[vout]`
+	if filterComplex != expected {
+		t.Errorf("got  %s\nwant %s", filterComplex, expected)
+	}
+	if outputPad != "vout" {
+		t.Errorf("got output pad %s, want vout", outputPad)
+	}
+}
+
+func TestFilterGraphBuildEndingWithOverlay(t *testing.T) {
+	var graph FilterGraph
+	graph.Overlay("logo.png", "10", "10", nil)
+
+	_, filterComplex, _ := graph.Build("0:v", 1)
+	if expected := "[0:v][1:v]overlay=10:10[vout]"; filterComplex != expected {
+		t.Errorf("got %s, want %s", filterComplex, expected)
+	}
+}
+
+func TestEditableVideoStacksOperations(t *testing.T) {
+	video := &Video{filepath: "in.mp4", width: 1920, height: 1080}
+	video.Crop(0, 0, 1080, 1080)
+	editable := video.GetEditableVideo()
+	editable.SetSize(720, 720)
+
+	expected := []string{
+		"ffmpeg", "-y", "-i", "in.mp4",
+		"-filter_complex", "[0:v]crop=1080:1080:0:0,scale=720:720[vout]",
+		"-map", "[vout]", "-map", "0:a?",
+		"-vcodec", "libx264",
+		"out.mp4",
+	}
+	if got := editable.commandLine("out.mp4"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+
+	// The source video must not be affected by the editable copy.
+	if len(video.filters.nodes) != 1 {
+		t.Errorf("editable video operations leaked to the source video")
+	}
+}
@@ -0,0 +1,334 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"math/cmplx"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// audioSignatureSampleRate is the sample rate the audio is resampled to before computing its signature.
+	audioSignatureSampleRate = 11025
+	// audioSignatureFrameSize is the number of samples of every analysed frame.
+	audioSignatureFrameSize = 2048
+	// audioSignatureHop is the number of samples between two sub-fingerprints.
+	audioSignatureHop = 512
+)
+
+// Fingerprint computes the perceptual hashes of the sampled frames and the audio signature of the video.
+// Fingerprints of re-encoded, resized or slightly altered copies of a video are similar, see CompareFingerprints.
+func (v *Video) Fingerprint(ctx context.Context, options FingerprintOptions) (*Fingerprint, error) {
+	if options.Sampling == (SamplingOptions{}) {
+		options.Sampling = SamplingOptions{Mode: SampleKeyframes, Width: 128, MaxFrames: 300}
+	}
+	if options.AudioDuration <= 0 {
+		options.AudioDuration = 2 * time.Minute
+	}
+
+	dir, frames, err := v.ExtractFrames(ctx, options.Sampling)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var fingerprint = Fingerprint{Duration: v.duration, Frames: make([]FrameHash, len(frames))}
+	var errs = make([]error, len(frames))
+	processFrames(frames, options.Sampling.Workers, func(i int, frame Frame) {
+		fingerprint.Frames[i], errs[i] = hashFrame(frame)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("Video.Fingerprint: %w", err)
+		}
+	}
+
+	if !options.SkipAudio && v.info != nil && v.info.HasAudio() {
+		samples, err := v.decodeAudio(ctx, options.AudioDuration)
+		if err != nil {
+			return nil, fmt.Errorf("Video.Fingerprint: ffmpeg failed: %w", err)
+		}
+		fingerprint.Audio = audioSignature(samples)
+	}
+	return &fingerprint, nil
+}
+
+// hashFrame computes the perceptual hashes of an extracted frame.
+func hashFrame(frame Frame) (FrameHash, error) {
+	f, err := os.Open(frame.Path)
+	if err != nil {
+		return FrameHash{}, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return FrameHash{}, fmt.Errorf("image.Decode %s, Error: %v", frame.Path, err)
+	}
+	return FrameHash{Timestamp: frame.Timestamp, DHash: DHash(img), PHash: PHash(img)}, nil
+}
+
+// decodeAudio returns the first duration of the audio of the video as mono 16 bit samples at audioSignatureSampleRate.
+func (v *Video) decodeAudio(ctx context.Context, duration time.Duration) ([]int16, error) {
+	cmdline := []string{
+		"ffmpeg", "-i", v.filepath,
+		"-vn", "-ac", "1", "-ar", strconv.Itoa(audioSignatureSampleRate),
+		"-t", formatSeconds(duration),
+		"-f", "s16le", "pipe:1",
+	}
+	var stdout, stderr bytes.Buffer
+	if err := GetRunner().Run(ctx, cmdline, &stdout, &stderr); err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("%s", stderr.String())
+		}
+		return nil, err
+	}
+
+	var samples = make([]int16, stdout.Len()/2)
+	if err := binary.Read(&stdout, binary.LittleEndian, samples); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// resizeLuma returns the luma of img resized to width x height, every pixel is the mean of the source pixels it covers.
+func resizeLuma(img image.Image, width, height int) []float64 {
+	bounds := img.Bounds()
+	luma := toLuma(img)
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	var resized = make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, (y+1)*srcHeight/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, (x+1)*srcWidth/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var sum float64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					sum += luma[sy*srcWidth+sx]
+				}
+			}
+			resized[y*width+x] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+	return resized
+}
+
+// DHash returns the difference hash of img: every bit tells if a pixel of the 9x8 grayscale thumbnail is brighter than its right neighbour.
+func DHash(img image.Image) uint64 {
+	if img.Bounds().Empty() {
+		return 0
+	}
+	luma := resizeLuma(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luma[y*9+x] > luma[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// PHash returns the perceptual hash of img: every bit tells if a low frequency DCT coefficient of the 32x32 grayscale thumbnail
+// is above the median of the coefficients.
+func PHash(img image.Image) uint64 {
+	if img.Bounds().Empty() {
+		return 0
+	}
+	const size = 32
+	luma := resizeLuma(img, size, size)
+
+	// Only the 8x8 lowest frequencies of the 2D DCT-II are needed: transform the rows, then the columns.
+	var rows [size][8]float64
+	for y := 0; y < size; y++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for x := 0; x < size; x++ {
+				sum += luma[y*size+x] * math.Cos(math.Pi*float64(u)*(2*float64(x)+1)/(2*size))
+			}
+			rows[y][u] = sum
+		}
+	}
+	var coefficients [64]float64
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for y := 0; y < size; y++ {
+				sum += rows[y][u] * math.Cos(math.Pi*float64(v)*(2*float64(y)+1)/(2*size))
+			}
+			coefficients[v*8+u] = sum
+		}
+	}
+
+	// The DC coefficient is the mean brightness, it is excluded from the median.
+	var sorted = make([]float64, 63)
+	copy(sorted, coefficients[1:])
+	sort.Float64s(sorted)
+	median := (sorted[31] + sorted[32]) / 2
+
+	var hash uint64
+	for _, c := range coefficients {
+		hash <<= 1
+		if c > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+// audioSignature computes the sub-fingerprints of mono samples at audioSignatureSampleRate, in the spirit of chromaprint:
+// every bit tells if the energy difference of two adjacent frequency bands increased since the previous frame.
+func audioSignature(samples []int16) []uint32 {
+	const bands = 33
+	// Bands are logarithmically spaced between 300 and 2000 Hz where most of the perceived content is.
+	var edges [bands + 1]int
+	for i := range edges {
+		frequency := 300 * math.Pow(2000.0/300.0, float64(i)/bands)
+		edges[i] = int(frequency * audioSignatureFrameSize / audioSignatureSampleRate)
+	}
+
+	var window = make([]float64, audioSignatureFrameSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/(audioSignatureFrameSize-1))
+	}
+
+	var signature []uint32
+	var previous [bands]float64
+	var buffer = make([]complex128, audioSignatureFrameSize)
+	for start, frame := 0, 0; start+audioSignatureFrameSize <= len(samples); start, frame = start+audioSignatureHop, frame+1 {
+		for i := range buffer {
+			buffer[i] = complex(float64(samples[start+i])*window[i], 0)
+		}
+		fft(buffer)
+
+		var energies [bands]float64
+		for b := 0; b < bands; b++ {
+			for k := edges[b]; k < edges[b+1]; k++ {
+				magnitude := cmplx.Abs(buffer[k])
+				energies[b] += magnitude * magnitude
+			}
+		}
+
+		if frame > 0 {
+			var subFingerprint uint32
+			for b := 0; b < bands-1; b++ {
+				subFingerprint <<= 1
+				if (energies[b]-energies[b+1])-(previous[b]-previous[b+1]) > 0 {
+					subFingerprint |= 1
+				}
+			}
+			signature = append(signature, subFingerprint)
+		}
+		previous = energies
+	}
+	return signature
+}
+
+// fft computes the discrete Fourier transform of x in place, len(x) must be a power of 2.
+func fft(x []complex128) {
+	n := len(x)
+	// Bit reversal permutation.
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for length := 2; length <= n; length <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(length)))
+		for i := 0; i < n; i += length {
+			w := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				even, odd := x[i+k], x[i+k+length/2]*w
+				x[i+k], x[i+k+length/2] = even+odd, even-odd
+				w *= step
+			}
+		}
+	}
+}
+
+// CompareFingerprints returns the similarity of two videos.
+// Frames of the video with fewer frames are matched to their closest frame in the other one and audio signatures are
+// aligned on their best offset, so a clip re-uploaded inside a longer video also scores high.
+// Re-encoded copies usually score above 0.8.
+func CompareFingerprints(a, b *Fingerprint) Similarity {
+	var similarity = Similarity{Visual: compareFrameHashes(a.Frames, b.Frames), Audio: -1}
+	similarity.Score = similarity.Visual
+	if len(a.Audio) > 0 && len(b.Audio) > 0 {
+		similarity.Audio = compareAudioSignatures(a.Audio, b.Audio)
+		similarity.Score = (similarity.Visual + similarity.Audio) / 2
+	}
+	return similarity
+}
+
+// compareFrameHashes returns the mean similarity of the frames of the shortest list to their closest frame in the other one.
+// Unrelated hashes differ by half of their bits, so a frame similarity is 1 - 2 * the ratio of different bits.
+func compareFrameHashes(a, b []FrameHash) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	var total float64
+	for _, frame := range a {
+		var closest = 128
+		for _, other := range b {
+			distance := bits.OnesCount64(frame.DHash^other.DHash) + bits.OnesCount64(frame.PHash^other.PHash)
+			if distance < closest {
+				closest = distance
+			}
+		}
+		total += math.Max(0, 1-2*float64(closest)/128)
+	}
+	return total / float64(len(a))
+}
+
+// compareAudioSignatures returns the similarity of the signatures at their best alignment: 1 - 2 * the bit error rate,
+// unrelated signatures have a bit error rate around 0.5.
+// Only offsets where the signatures overlap on at least half of the shortest one are considered.
+func compareAudioSignatures(a, b []uint32) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	minOverlap := (len(a) + 1) / 2
+
+	var best float64
+	for offset := minOverlap - len(a); offset <= len(b)-minOverlap; offset++ {
+		var differences, compared int
+		for i := range a {
+			j := i + offset
+			if j < 0 || j >= len(b) {
+				continue
+			}
+			differences += bits.OnesCount32(a[i] ^ b[j])
+			compared += 32
+		}
+		if similarity := 1 - 2*float64(differences)/float64(compared); similarity > best {
+			best = similarity
+		}
+	}
+	return best
+}
@@ -0,0 +1,106 @@
+package ffmpeg
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// newPatternImage returns a width x height image of smooth shapes, the same picture at every size.
+func newPatternImage(width, height int, seed float64) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			value := 128 + 60*math.Sin(6*fx+seed) + 60*math.Cos(4*fy*fx+2*seed)
+			img.Set(x, y, color.RGBA{R: uint8(value), G: uint8(255 - value), B: uint8(value / 2), A: 255})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHashes(t *testing.T) {
+	original := newPatternImage(320, 180, 0)
+	resized := newPatternImage(128, 72, 0)
+	other := newPatternImage(320, 180, 2)
+
+	for name, hash := range map[string]func(image.Image) uint64{"DHash": DHash, "PHash": PHash} {
+		if d := bits.OnesCount64(hash(original) ^ hash(resized)); d > 6 {
+			t.Errorf("%s: resized image differs by %d bits", name, d)
+		}
+		if d := bits.OnesCount64(hash(original) ^ hash(other)); d < 16 {
+			t.Errorf("%s: different image differs by %d bits only", name, d)
+		}
+	}
+}
+
+// newTestSignal returns seconds of random notes with harmonics at audioSignatureSampleRate.
+func newTestSignal(seed int64, seconds float64) []int16 {
+	random := rand.New(rand.NewSource(seed))
+	var samples = make([]int16, int(seconds*audioSignatureSampleRate))
+	var frequency float64
+	for i := range samples {
+		// A new note every 0.1 second.
+		if i%(audioSignatureSampleRate/10) == 0 {
+			frequency = 100 + random.Float64()*500
+		}
+		var value float64
+		for harmonic := 1.0; harmonic <= 4; harmonic++ {
+			value += 3000 / harmonic * math.Sin(2*math.Pi*frequency*harmonic*float64(i)/audioSignatureSampleRate)
+		}
+		samples[i] = int16(value)
+	}
+	return samples
+}
+
+func TestCompareAudioSignatures(t *testing.T) {
+	original := newTestSignal(1, 10)
+
+	// The copy starts later and has some noise.
+	random := rand.New(rand.NewSource(3))
+	var copied []int16
+	for _, s := range original[3*audioSignatureHop:] {
+		copied = append(copied, s+int16(random.Intn(400)-200))
+	}
+
+	signature := audioSignature(original)
+	if expected := (len(original) - audioSignatureFrameSize) / audioSignatureHop; len(signature) != expected {
+		t.Errorf("got %d sub-fingerprints, want %d", len(signature), expected)
+	}
+	if similarity := compareAudioSignatures(signature, audioSignature(copied)); similarity < 0.8 {
+		t.Errorf("copy similarity is %v, want > 0.8", similarity)
+	}
+	if similarity := compareAudioSignatures(signature, audioSignature(newTestSignal(2, 10))); similarity > 0.3 {
+		t.Errorf("unrelated similarity is %v, want < 0.3", similarity)
+	}
+}
+
+func TestCompareFingerprints(t *testing.T) {
+	var clip, copied, other Fingerprint
+	for i := 0; i < 5; i++ {
+		img := newPatternImage(160, 90, float64(i))
+		clip.Frames = append(clip.Frames, FrameHash{DHash: DHash(img), PHash: PHash(img)})
+		resized := newPatternImage(96, 54, float64(i))
+		copied.Frames = append(copied.Frames, FrameHash{DHash: DHash(resized), PHash: PHash(resized)})
+		unrelated := newPatternImage(160, 90, float64(i)+0.5)
+		other.Frames = append(other.Frames, FrameHash{DHash: DHash(unrelated) ^ 0xF0F0F0F0F0F0F0F0, PHash: PHash(unrelated) ^ 0x0F0F0F0F0F0F0F0F})
+	}
+	// The copy is a re-upload in a longer video.
+	copied.Frames = append(copied.Frames, other.Frames...)
+
+	if similarity := CompareFingerprints(&clip, &copied); similarity.Visual < 0.8 || similarity.Audio != -1 || similarity.Score != similarity.Visual {
+		t.Errorf("unexpected copy similarity %+v", similarity)
+	}
+	if similarity := CompareFingerprints(&clip, &other); similarity.Score > 0.5 {
+		t.Errorf("unexpected unrelated similarity %+v", similarity)
+	}
+
+	clip.Audio = audioSignature(newTestSignal(1, 5))
+	copied.Audio = clip.Audio
+	if similarity := CompareFingerprints(&clip, &copied); similarity.Audio != 1 || similarity.Score != (similarity.Visual+1)/2 {
+		t.Errorf("unexpected similarity with audio %+v", similarity)
+	}
+}
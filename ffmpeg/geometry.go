@@ -0,0 +1,90 @@
+package ffmpeg
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DisplayGeometry returns how players display the stream.
+func (s *StreamInfo) DisplayGeometry() DisplayGeometry {
+	var geometry = DisplayGeometry{
+		CodedWidth:  s.Width,
+		CodedHeight: s.Height,
+		SAR:         1,
+		Width:       s.Width,
+		Height:      s.Height,
+	}
+
+	// The Display Matrix rotation is counter clockwise, the rotate tag is clockwise.
+	var rotation float64
+	if s.DisplayMatrix != nil {
+		rotation = -s.DisplayMatrix.Rotation
+	} else if rotate, ok := s.Tags["rotate"]; ok {
+		if degrees, err := strconv.Atoi(rotate); err == nil {
+			rotation = float64(degrees)
+		}
+	}
+	geometry.Rotation = int(math.Mod(math.Mod(math.Round(rotation), 360)+360, 360))
+
+	if sar := parseRational(strings.Replace(s.SampleAspectRatio, ":", "/", 1)); sar > 0 && sar != 1 {
+		geometry.SAR = sar
+		geometry.Width = toEvenNumber(int(math.Round(float64(s.Width) * sar)))
+	}
+	if geometry.IsQuarterTurn() {
+		geometry.Width, geometry.Height = geometry.Height, geometry.Width
+	}
+	return geometry
+}
+
+// IsQuarterTurn returns true if the video is rotated by 90 or 270 degrees, the displayed width is the coded height.
+func (g DisplayGeometry) IsQuarterTurn() bool {
+	quarters := (g.Rotation + 45) / 90
+	return quarters%2 == 1
+}
+
+// AspectRatio returns the displayed width / height ratio, 0 if the size is unknown.
+func (g DisplayGeometry) AspectRatio() float64 {
+	if g.Height == 0 {
+		return 0
+	}
+	return float64(g.Width) / float64(g.Height)
+}
+
+// Orientation returns the displayed Screen Orientation.
+func (g DisplayGeometry) Orientation() ScreenOrientation {
+	if g.Width > g.Height {
+		return Landscape
+	}
+	return Portrait
+}
+
+// Resolution returns the lowest value between the displayed width and height.
+func (g DisplayGeometry) Resolution() VideoResolution {
+	if g.Width > g.Height {
+		return VideoResolution(g.Height)
+	}
+	return VideoResolution(g.Width)
+}
+
+// HasSquarePixels returns true if the SAR is 1, or unknown.
+func (g DisplayGeometry) HasSquarePixels() bool {
+	return g.SAR == 0 || g.SAR == 1
+}
+
+// GetDisplayGeometry returns how players display the input video. Its displayed size is the initial size of the Video.
+func (v *Video) GetDisplayGeometry() DisplayGeometry {
+	return v.geometry
+}
+
+// displayFilters returns a copy of the filter graph operating on square pixels: the operations of the graph use
+// the displayed size of the video, ffmpeg only applies the rotation to the decoded frames.
+func (v *EditableVideo) displayFilters() FilterGraph {
+	if v.geometry.HasSquarePixels() {
+		return v.filters.Clone()
+	}
+	var graph FilterGraph
+	graph.Scale(v.geometry.Width, v.geometry.Height).Filter("setsar=1")
+	graph.nodes = append(graph.nodes, v.filters.nodes...)
+	return graph
+}
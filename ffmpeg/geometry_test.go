@@ -0,0 +1,111 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDisplayGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   StreamInfo
+		expected DisplayGeometry
+	}{
+		{
+			name:     "landscape",
+			stream:   StreamInfo{Width: 1920, Height: 1080, SampleAspectRatio: "1:1"},
+			expected: DisplayGeometry{CodedWidth: 1920, CodedHeight: 1080, SAR: 1, Width: 1920, Height: 1080},
+		},
+		{
+			name:     "rotate tag",
+			stream:   StreamInfo{Width: 1920, Height: 1080, Tags: map[string]string{"rotate": "90"}},
+			expected: DisplayGeometry{CodedWidth: 1920, CodedHeight: 1080, Rotation: 90, SAR: 1, Width: 1080, Height: 1920},
+		},
+		{
+			name:     "negative rotate tag",
+			stream:   StreamInfo{Width: 1920, Height: 1080, Tags: map[string]string{"rotate": "-90"}},
+			expected: DisplayGeometry{CodedWidth: 1920, CodedHeight: 1080, Rotation: 270, SAR: 1, Width: 1080, Height: 1920},
+		},
+		{
+			name:     "display matrix only",
+			stream:   StreamInfo{Width: 1920, Height: 1080, DisplayMatrix: &DisplayMatrix{Rotation: -90}},
+			expected: DisplayGeometry{CodedWidth: 1920, CodedHeight: 1080, Rotation: 90, SAR: 1, Width: 1080, Height: 1920},
+		},
+		{
+			name: "display matrix over rotate tag",
+			stream: StreamInfo{Width: 1920, Height: 1080, DisplayMatrix: &DisplayMatrix{Rotation: 180},
+				Tags: map[string]string{"rotate": "90"}},
+			expected: DisplayGeometry{CodedWidth: 1920, CodedHeight: 1080, Rotation: 180, SAR: 1, Width: 1920, Height: 1080},
+		},
+		{
+			name:     "anamorphic",
+			stream:   StreamInfo{Width: 720, Height: 576, SampleAspectRatio: "16:15"},
+			expected: DisplayGeometry{CodedWidth: 720, CodedHeight: 576, SAR: 16.0 / 15, Width: 768, Height: 576},
+		},
+		{
+			name:     "rotated anamorphic",
+			stream:   StreamInfo{Width: 720, Height: 576, SampleAspectRatio: "16:15", DisplayMatrix: &DisplayMatrix{Rotation: 90}},
+			expected: DisplayGeometry{CodedWidth: 720, CodedHeight: 576, Rotation: 270, SAR: 16.0 / 15, Width: 576, Height: 768},
+		},
+		{
+			name:     "unknown SAR",
+			stream:   StreamInfo{Width: 640, Height: 480, SampleAspectRatio: "0:1"},
+			expected: DisplayGeometry{CodedWidth: 640, CodedHeight: 480, SAR: 1, Width: 640, Height: 480},
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.stream.DisplayGeometry(); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestVideoDisplayGeometry(t *testing.T) {
+	// Recent phones only write the Display Matrix, without the legacy rotate tag.
+	info := &MediaInfo{
+		Format: FormatInfo{Duration: 10e9},
+		Streams: []StreamInfo{{
+			Type: VideoStream, Width: 1920, Height: 1080, AvgFrameRate: 30,
+			DisplayMatrix: &DisplayMatrix{Rotation: -90},
+		}},
+	}
+	video := newVideoFromMediaInfo("portrait.mp4", info)
+
+	if video.width != 1080 || video.height != 1920 {
+		t.Errorf("got size %dx%d, want 1080x1920", video.width, video.height)
+	}
+	if video.GetVideoOrientation() != Portrait {
+		t.Errorf("the video should be portrait")
+	}
+	if rotate := video.GetVideoRotate(); rotate == nil || *rotate != 90 {
+		t.Errorf("got rotate %v, want 90", rotate)
+	}
+	if res := video.GetVideoResolution(); res != 1080 {
+		t.Errorf("got resolution %d, want 1080", res)
+	}
+	if ratio := video.GetEditableVideo().GetAspectRatio(); ratio != 1920.0/1080 {
+		t.Errorf("got aspect ratio %v", ratio)
+	}
+}
+
+func TestRenderAnamorphicVideo(t *testing.T) {
+	info := &MediaInfo{
+		Format:  FormatInfo{Duration: 10e9},
+		Streams: []StreamInfo{{Type: VideoStream, Width: 720, Height: 576, AvgFrameRate: 25, SampleAspectRatio: "16:15"}},
+	}
+	video := newVideoFromMediaInfo("dvd.mpg", info).GetEditableVideo()
+	width, height := video.GetResolutions(360)
+	video.SetSize(width, height)
+
+	// The size is computed on square pixels, the frames are converted before resizing.
+	expected := []string{
+		"ffmpeg", "-y", "-i", "dvd.mpg",
+		"-filter_complex", "[0:v]scale=768:576,setsar=1,scale=480:360[vout]", "-map", "[vout]", "-map", "0:a?",
+		"-vcodec", "libx264",
+		"out.mp4",
+	}
+	if got := video.commandLine("out.mp4"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+}
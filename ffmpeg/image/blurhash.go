@@ -0,0 +1,153 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	stdimage "image"
+	"image/png"
+	"math"
+	"strings"
+)
+
+// thumbnailSize is the maximum size of the thumbnails analyzed by BlurHash and DominantColor.
+const thumbnailSize = 64
+
+// decodeThumbnail decodes the image at path (any format supported by ffmpeg), upright and resized to fit size x size.
+func decodeThumbnail(ctx context.Context, path string, size int) (stdimage.Image, error) {
+	orientation, err := ReadOrientation(path)
+	if err != nil {
+		return nil, err
+	}
+	filters := append(orientationFilters(orientation), fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", size, size))
+	args := []string{
+		"-noautorotate", "-i", path,
+		"-vf", strings.Join(filters, ","),
+		"-frames:v", "1", "-an",
+		"-c:v", "png", "-pix_fmt", "rgba", "-f", "image2pipe", "-",
+	}
+	data, err := run(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+// BlurHash returns the BlurHash (https://blurha.sh) of the image at path, see EncodeBlurHash.
+func BlurHash(ctx context.Context, path string, xComponents, yComponents int) (string, error) {
+	img, err := decodeThumbnail(ctx, path, thumbnailSize)
+	if err != nil {
+		return "", fmt.Errorf("image.BlurHash: %w", err)
+	}
+	return EncodeBlurHash(img, xComponents, yComponents)
+}
+
+// EncodeBlurHash returns the BlurHash of img using xComponents x yComponents [1-9] components, 4 x 3 is a common choice.
+// Small images give the same result much faster, see BlurHash.
+func EncodeBlurHash(img stdimage.Image, xComponents, yComponents int) (string, error) {
+	if xComponents < 1 || xComponents > 9 || yComponents < 1 || yComponents > 9 {
+		return "", errors.New("image.EncodeBlurHash: components must be between 1 and 9")
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return "", errors.New("image.EncodeBlurHash: empty image")
+	}
+
+	// Linear RGB values of every pixel, row by row.
+	var linear = make([][3]float64, 0, width*height)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			linear = append(linear, [3]float64{sRGBToLinear(r >> 8), sRGBToLinear(g >> 8), sRGBToLinear(b >> 8)})
+		}
+	}
+
+	var factors = make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := normalisation * math.Cos(math.Pi*float64(i*x)/float64(width)) * math.Cos(math.Pi*float64(j*y)/float64(height))
+					pixel := linear[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+			scale := 1 / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encodeBase83((xComponents-1)+(yComponents-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	var maximumValue = 1.0
+	if len(ac) > 0 {
+		var actualMax float64
+		for _, f := range ac {
+			actualMax = math.Max(actualMax, math.Max(math.Abs(f[0]), math.Max(math.Abs(f[1]), math.Abs(f[2]))))
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maximumValue = float64(quantisedMax+1) / 166
+		hash.WriteString(encodeBase83(quantisedMax, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	hash.WriteString(encodeBase83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, f := range ac {
+		hash.WriteString(encodeBase83(encodeAC(f, maximumValue), 2))
+	}
+	return hash.String(), nil
+}
+
+// encodeAC quantizes an AC component on 19 levels per channel.
+func encodeAC(value [3]float64, maximumValue float64) int {
+	quantize := func(v float64) int {
+		return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maximumValue, 0.5)*9+9.5))))
+	}
+	return quantize(value[0])*19*19 + quantize(value[1])*19 + quantize(value[2])
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
+
+// sRGBToLinear converts a [0-255] sRGB value to linear light [0-1].
+func sRGBToLinear(value uint32) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts a linear light value [0-1] to sRGB [0-255].
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+const base83Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// encodeBase83 encodes value on length base 83 digits.
+func encodeBase83(value, length int) string {
+	var encoded = make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		encoded[i] = base83Characters[value%83]
+		value /= 83
+	}
+	return string(encoded)
+}
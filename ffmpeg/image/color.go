@@ -0,0 +1,61 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	stdimage "image"
+	"image/color"
+)
+
+// DominantColor returns the most represented color of the image at path, see DominantColorOf.
+func DominantColor(ctx context.Context, path string) (color.RGBA, error) {
+	img, err := decodeThumbnail(ctx, path, thumbnailSize)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("image.DominantColor: %w", err)
+	}
+	return DominantColorOf(img), nil
+}
+
+// DominantColorOf returns the average color of the most populated color bucket of img, e.g to fill a placeholder.
+// Colors are grouped in 4096 buckets (4 bits per channel), transparent pixels are ignored.
+func DominantColorOf(img stdimage.Image) color.RGBA {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	var buckets = make(map[int]*bucket)
+	var best *bucket
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 128 {
+				continue
+			}
+			key := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			b, ok := buckets[key]
+			if !ok {
+				b = &bucket{}
+				buckets[key] = b
+			}
+			b.count++
+			b.r += int(c.R)
+			b.g += int(c.G)
+			b.b += int(c.B)
+			if best == nil || b.count > best.count {
+				best = b
+			}
+		}
+	}
+
+	if best == nil {
+		return color.RGBA{}
+	}
+	return color.RGBA{
+		R: uint8(best.r / best.count),
+		G: uint8(best.g / best.count),
+		B: uint8(best.b / best.count),
+		A: 255,
+	}
+}
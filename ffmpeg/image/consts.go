@@ -0,0 +1,33 @@
+package image
+
+// Format defines the encoding of an output image
+type Format string
+
+const (
+	JPEG Format = "jpeg"
+	WebP Format = "webp"
+	AVIF Format = "avif"
+	PNG  Format = "png"
+)
+
+// ResizeMode defines how an image is resized to the requested box
+type ResizeMode byte
+
+const (
+	Fit  ResizeMode = 0 // the whole image fits in the box, keeping its aspect ratio
+	Fill ResizeMode = 1 // the image covers the box keeping its aspect ratio, the overflow is cropped from the center
+)
+
+// Orientation is the EXIF orientation of an image
+type Orientation int
+
+const (
+	OrientationNormal         Orientation = 1
+	OrientationFlipHorizontal Orientation = 2
+	OrientationRotate180      Orientation = 3
+	OrientationFlipVertical   Orientation = 4
+	OrientationTranspose      Orientation = 5
+	OrientationRotate90       Orientation = 6 // the image must be rotated 90 degrees clockwise to be displayed
+	OrientationTransverse     Orientation = 7
+	OrientationRotate270      Orientation = 8 // the image must be rotated 90 degrees counterclockwise to be displayed
+)
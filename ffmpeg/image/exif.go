@@ -0,0 +1,177 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// JPEG markers
+const (
+	markerSOI   = 0xD8 // start of image
+	markerSOS   = 0xDA // start of scan, followed by the entropy coded data
+	markerAPP1  = 0xE1 // Exif and XMP
+	markerAPP2  = 0xE2 // ICC profile
+	markerAPP14 = 0xEE // Adobe color transform
+	markerCOM   = 0xFE // comment
+)
+
+// exifOrientationTag is the TIFF tag of the orientation in IFD0.
+const exifOrientationTag = 0x0112
+
+// ReadOrientation returns the EXIF orientation of the image at path.
+// OrientationNormal is returned for images without orientation, and for formats other than JPEG.
+func ReadOrientation(path string) (Orientation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return readJPEGOrientation(bufio.NewReader(f))
+}
+
+// readJPEGOrientation reads the orientation from the Exif segment of a JPEG stream.
+func readJPEGOrientation(r *bufio.Reader) (Orientation, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != markerSOI {
+		// Not a JPEG
+		return OrientationNormal, nil
+	}
+
+	for {
+		marker, segment, err := readJPEGSegment(r)
+		if err != nil {
+			return 0, err
+		}
+		if marker == markerSOS {
+			return OrientationNormal, nil
+		}
+		if marker == markerAPP1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseExifOrientation(segment[6:]), nil
+		}
+	}
+}
+
+// readJPEGSegment reads the next marker and its payload. The payload of SOS is not read.
+func readJPEGSegment(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("invalid JPEG: %v", err)
+	}
+	if header[0] != 0xFF {
+		return 0, nil, errors.New("invalid JPEG: marker expected")
+	}
+	marker := header[1]
+	// Fill bytes
+	for marker == 0xFF {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid JPEG: %v", err)
+		}
+		marker = b
+	}
+	if marker == markerSOS {
+		return marker, nil, nil
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return 0, nil, fmt.Errorf("invalid JPEG: %v", err)
+	}
+	size := int(binary.BigEndian.Uint16(length[:]))
+	if size < 2 {
+		return 0, nil, errors.New("invalid JPEG: invalid segment length")
+	}
+	segment := make([]byte, size-2)
+	if _, err := io.ReadFull(r, segment); err != nil {
+		return 0, nil, fmt.Errorf("invalid JPEG: %v", err)
+	}
+	return marker, segment, nil
+}
+
+// parseExifOrientation returns the orientation stored in a TIFF structure, OrientationNormal if it is missing or invalid.
+func parseExifOrientation(tiff []byte) Orientation {
+	if len(tiff) < 8 {
+		return OrientationNormal
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return OrientationNormal
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return OrientationNormal
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		// SHORT value stored in the first bytes of the value field.
+		orientation := Orientation(order.Uint16(tiff[entry+8:]))
+		if orientation < OrientationNormal || orientation > OrientationRotate270 {
+			return OrientationNormal
+		}
+		return orientation
+	}
+	return OrientationNormal
+}
+
+// StripMetadata copies the JPEG read from r to w without its Exif, XMP and comment segments, the image is not re-encoded.
+// The ICC color profile and the Adobe segment, telling how CMYK and YCCK images are decoded, are kept.
+// Convert already strips metadata of the images it encodes.
+func StripMetadata(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != markerSOI {
+		return errors.New("image.StripMetadata: not a JPEG")
+	}
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(soi[:]); err != nil {
+		return err
+	}
+
+	for {
+		marker, segment, err := readJPEGSegment(br)
+		if err != nil {
+			return fmt.Errorf("image.StripMetadata: %w", err)
+		}
+		if marker == markerSOS {
+			// Copy the scans and the end of image as is.
+			if _, err := bw.Write([]byte{0xFF, markerSOS}); err != nil {
+				return err
+			}
+			if _, err := io.Copy(bw, br); err != nil {
+				return err
+			}
+			return bw.Flush()
+		}
+
+		// APP1 (Exif, XMP), APP3-APP13, APP15 and comments only carry metadata.
+		if marker == markerCOM || (marker >= markerAPP1 && marker <= 0xEF && marker != markerAPP2 && marker != markerAPP14) {
+			continue
+		}
+		var header = []byte{0xFF, marker, 0, 0}
+		binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+		if _, err := bw.Write(header); err != nil {
+			return err
+		}
+		if _, err := bw.Write(segment); err != nil {
+			return err
+		}
+	}
+}
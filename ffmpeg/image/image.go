@@ -0,0 +1,116 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sabriboughanmi/go_utils/ffmpeg"
+	"strconv"
+	"strings"
+)
+
+// run executes ffmpeg with args using the ffmpeg package Runner and returns its output.
+// The process is killed as soon as ctx is done.
+func run(ctx context.Context, args []string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	err := ffmpeg.GetRunner().Run(ctx, append([]string{"ffmpeg"}, args...), &stdout, &stderr)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("execution interrupted: %w", ctxErr)
+	}
+	if err != nil {
+		if stderr.Len() > 0 {
+			return nil, errors.New(stderr.String())
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// orientationFilters returns the filters displaying an image with the given EXIF orientation upright.
+func orientationFilters(orientation Orientation) []string {
+	switch orientation {
+	case OrientationFlipHorizontal:
+		return []string{"hflip"}
+	case OrientationRotate180:
+		return []string{"hflip", "vflip"}
+	case OrientationFlipVertical:
+		return []string{"vflip"}
+	case OrientationTranspose:
+		return []string{"transpose=0"}
+	case OrientationRotate90:
+		return []string{"transpose=1"}
+	case OrientationTransverse:
+		return []string{"transpose=3"}
+	case OrientationRotate270:
+		return []string{"transpose=2"}
+	}
+	return nil
+}
+
+// resizeFilters returns the filters resizing an image to the box of options.
+func resizeFilters(options Options) []string {
+	switch {
+	case options.Width > 0 && options.Height > 0 && options.Mode == Fill:
+		return []string{
+			fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=increase", options.Width, options.Height),
+			fmt.Sprintf("crop=%d:%d", options.Width, options.Height),
+		}
+	case options.Width > 0 && options.Height > 0:
+		return []string{fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", options.Width, options.Height)}
+	case options.Width > 0:
+		return []string{fmt.Sprintf("scale=%d:-1", options.Width)}
+	case options.Height > 0:
+		return []string{fmt.Sprintf("scale=-1:%d", options.Height)}
+	}
+	return nil
+}
+
+// encodingArgs returns the output options encoding an image to the format of options.
+func encodingArgs(options Options) []string {
+	quality := options.Quality
+	if quality <= 0 || quality > 100 {
+		quality = 80
+	}
+	switch options.Format {
+	case WebP:
+		return []string{"-c:v", "libwebp", "-quality", strconv.Itoa(quality), "-f", "webp"}
+	case AVIF:
+		// libaom constant quality goes from 0 (lossless) to 63.
+		crf := 63 - quality*63/100
+		return []string{"-c:v", "libaom-av1", "-still-picture", "1", "-crf", strconv.Itoa(crf), "-b:v", "0", "-pix_fmt", "yuv420p", "-f", "avif"}
+	case PNG:
+		return []string{"-c:v", "png", "-f", "image2", "-update", "1"}
+	}
+	// mjpeg quality goes from 2 (best) to 31.
+	q := 2 + (100-quality)*29/100
+	return []string{"-c:v", "mjpeg", "-q:v", strconv.Itoa(q), "-pix_fmt", "yuvj420p", "-f", "image2", "-update", "1"}
+}
+
+// convertArgs returns the ffmpeg arguments converting the image at inputPath to outputPath.
+func convertArgs(inputPath, outputPath string, orientation Orientation, options Options) []string {
+	// EXIF orientation is applied explicitly, recent ffmpeg versions would otherwise apply it twice.
+	args := []string{"-y", "-noautorotate", "-i", inputPath}
+
+	filters := append(orientationFilters(orientation), resizeFilters(options)...)
+	if len(filters) > 0 {
+		args = append(args, "-vf", strings.Join(filters, ","))
+	}
+	// Metadata (EXIF, XMP..) is stripped, only the first frame of animated images is kept.
+	args = append(args, "-map_metadata", "-1", "-frames:v", "1", "-an")
+	args = append(args, encodingArgs(options)...)
+	return append(args, outputPath)
+}
+
+// Convert resizes and converts the image at inputPath to outputPath.
+// The EXIF orientation of the input is applied to the pixels and every metadata is stripped from the output.
+func Convert(ctx context.Context, inputPath, outputPath string, options Options) error {
+	orientation, err := ReadOrientation(inputPath)
+	if err != nil {
+		return err
+	}
+	if _, err := run(ctx, convertArgs(inputPath, outputPath, orientation, options)); err != nil {
+		return fmt.Errorf("image.Convert: ffmpeg failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertArgs(t *testing.T) {
+	args := convertArgs("in.jpg", "out.webp", OrientationRotate90, Options{Width: 400, Height: 300, Mode: Fill, Format: WebP, Quality: 70})
+	expected := []string{
+		"-y", "-noautorotate", "-i", "in.jpg",
+		"-vf", "transpose=1,scale=400:300:force_original_aspect_ratio=increase,crop=400:300",
+		"-map_metadata", "-1", "-frames:v", "1", "-an",
+		"-c:v", "libwebp", "-quality", "70", "-f", "webp",
+		"out.webp",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("got  %v\nwant %v", args, expected)
+	}
+
+	line := strings.Join(convertArgs("in.png", "out.jpg", OrientationNormal, Options{Width: 200}), " ")
+	if !strings.Contains(line, "-vf scale=200:-1 ") || !strings.Contains(line, "-c:v mjpeg -q:v 7 ") {
+		t.Errorf("unexpected JPEG arguments %s", line)
+	}
+	line = strings.Join(convertArgs("in.png", "out.avif", OrientationNormal, Options{Format: AVIF, Quality: 100}), " ")
+	if strings.Contains(line, "-vf") || !strings.Contains(line, "-crf 0 -b:v 0") {
+		t.Errorf("unexpected AVIF arguments %s", line)
+	}
+}
+
+// exifJPEG returns a minimal JPEG header with an Exif orientation, a comment and an ICC profile.
+func exifJPEG(order binary.ByteOrder, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	binary.Write(&tiff, order, uint16(42))
+	binary.Write(&tiff, order, uint32(8))
+	binary.Write(&tiff, order, uint16(2)) // entries
+	// ImageWidth
+	binary.Write(&tiff, order, []uint16{0x0100, 3})
+	binary.Write(&tiff, order, uint32(1))
+	binary.Write(&tiff, order, []uint16{640, 0})
+	// Orientation
+	binary.Write(&tiff, order, []uint16{0x0112, 3})
+	binary.Write(&tiff, order, uint32(1))
+	binary.Write(&tiff, order, []uint16{orientation, 0})
+
+	var jpeg bytes.Buffer
+	jpeg.Write([]byte{0xFF, 0xD8})
+	writeSegment := func(marker byte, payload []byte) {
+		jpeg.Write([]byte{0xFF, marker})
+		binary.Write(&jpeg, binary.BigEndian, uint16(len(payload)+2))
+		jpeg.Write(payload)
+	}
+	writeSegment(0xE0, []byte("JFIF\x00\x01\x01"))
+	writeSegment(0xE1, append([]byte("Exif\x00\x00"), tiff.Bytes()...))
+	writeSegment(0xE2, []byte("ICC_PROFILE\x00"))
+	writeSegment(0xEE, []byte("Adobe\x00\x64\x00\x00\x00\x00\x02"))
+	writeSegment(0xFE, []byte("comment"))
+	jpeg.Write([]byte{0xFF, 0xDA, 0x01, 0x02, 0x03, 0xFF, 0xD9})
+	return jpeg.Bytes()
+}
+
+func TestReadJPEGOrientation(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		data := exifJPEG(order, 6)
+		if got, err := readJPEGOrientation(bufioReader(data)); err != nil || got != OrientationRotate90 {
+			t.Errorf("%v: got %v, %v", order, got, err)
+		}
+	}
+	if got, _ := readJPEGOrientation(bufioReader([]byte("\x89PNG"))); got != OrientationNormal {
+		t.Errorf("got %v for a PNG", got)
+	}
+	if got, _ := readJPEGOrientation(bufioReader(exifJPEG(binary.BigEndian, 42))); got != OrientationNormal {
+		t.Errorf("got %v for an invalid orientation", got)
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	var stripped bytes.Buffer
+	if err := StripMetadata(bytes.NewReader(exifJPEG(binary.LittleEndian, 6)), &stripped); err != nil {
+		t.Fatal(err)
+	}
+	data := stripped.Bytes()
+	if bytes.Contains(data, []byte("Exif")) || bytes.Contains(data, []byte("comment")) {
+		t.Errorf("metadata not stripped: %q", data)
+	}
+	if !bytes.Contains(data, []byte("JFIF")) || !bytes.Contains(data, []byte("ICC_PROFILE")) || !bytes.Contains(data, []byte("Adobe")) {
+		t.Errorf("image segments removed: %q", data)
+	}
+	if !bytes.HasSuffix(data, []byte{0xFF, 0xDA, 0x01, 0x02, 0x03, 0xFF, 0xD9}) {
+		t.Errorf("scan data not copied: %q", data)
+	}
+	if got, _ := readJPEGOrientation(bufioReader(data)); got != OrientationNormal {
+		t.Errorf("orientation still present")
+	}
+}
+
+func TestEncodeBlurHashSolidColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 6))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []byte{255, 0, 0, 255})
+	}
+	hash, err := EncodeBlurHash(img, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// size flag, maximum AC then the DC (pure red).
+	if expected := "00" + encodeBase83(0xFF0000, 4); hash != expected {
+		t.Errorf("got %s, want %s", hash, expected)
+	}
+
+	hash, err = EncodeBlurHash(img, 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash) != 28 || hash[:1] != encodeBase83(3+2*9, 1) || hash[2:6] != encodeBase83(0xFF0000, 4) {
+		t.Errorf("unexpected hash %s", hash)
+	}
+
+	if _, err := EncodeBlurHash(img, 0, 3); err == nil {
+		t.Errorf("expected an error for invalid components")
+	}
+}
+
+func TestEncodeBase83(t *testing.T) {
+	if got := encodeBase83(83*83+5, 3); got != "105" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestDominantColorOf(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			switch {
+			case y < 6:
+				img.Set(x, y, color.NRGBA{R: 10, G: 100, B: 200, A: 255})
+			case y < 8:
+				img.Set(x, y, color.NRGBA{R: 250, G: 250, B: 250, A: 255})
+			default:
+				// Transparent pixels are ignored.
+				img.Set(x, y, color.NRGBA{R: 250, G: 250, B: 250, A: 0})
+			}
+		}
+	}
+	if got := DominantColorOf(img); got != (color.RGBA{R: 10, G: 100, B: 200, A: 255}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func bufioReader(data []byte) *bufio.Reader {
+	return bufio.NewReader(bytes.NewReader(data))
+}
@@ -0,0 +1,10 @@
+package image
+
+// Options configures the conversion of an image by Convert.
+type Options struct {
+	Width   int        // width of the resize box, 0 keeps the aspect ratio of Height
+	Height  int        // height of the resize box, 0 keeps the aspect ratio of Width
+	Mode    ResizeMode // only used if both Width and Height are set
+	Format  Format     // defaults to JPEG
+	Quality int        // [1-100], defaults to 80. Ignored for PNG.
+}
@@ -0,0 +1,346 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeOutput mirrors the JSON printed by ffprobe -show_format -show_streams.
+// ffprobe prints most numeric values as strings, they are converted to typed values by toMediaInfo.
+type probeOutput struct {
+	Streams []struct {
+		Index              int               `json:"index"`
+		CodecName          string            `json:"codec_name"`
+		CodecLongName      string            `json:"codec_long_name"`
+		Profile            string            `json:"profile"`
+		CodecType          string            `json:"codec_type"`
+		CodecTagString     string            `json:"codec_tag_string"`
+		Width              int               `json:"width"`
+		Height             int               `json:"height"`
+		CodedWidth         int               `json:"coded_width"`
+		CodedHeight        int               `json:"coded_height"`
+		SampleAspectRatio  string            `json:"sample_aspect_ratio"`
+		DisplayAspectRatio string            `json:"display_aspect_ratio"`
+		PixFmt             string            `json:"pix_fmt"`
+		Level              int               `json:"level"`
+		ColorRange         string            `json:"color_range"`
+		ColorSpace         string            `json:"color_space"`
+		ColorTransfer      string            `json:"color_transfer"`
+		ColorPrimaries     string            `json:"color_primaries"`
+		FieldOrder         string            `json:"field_order"`
+		SampleFmt          string            `json:"sample_fmt"`
+		SampleRate         json.Number       `json:"sample_rate"`
+		Channels           int               `json:"channels"`
+		ChannelLayout      string            `json:"channel_layout"`
+		BitsPerSample      int               `json:"bits_per_sample"`
+		RFrameRate         string            `json:"r_frame_rate"`
+		AvgFrameRate       string            `json:"avg_frame_rate"`
+		TimeBase           string            `json:"time_base"`
+		Duration           json.Number       `json:"duration"`
+		BitRate            json.Number       `json:"bit_rate"`
+		NbFrames           json.Number       `json:"nb_frames"`
+		Disposition        map[string]int    `json:"disposition"`
+		Tags               map[string]string `json:"tags"`
+		SideDataList       []struct {
+			SideDataType  string      `json:"side_data_type"`
+			DisplayMatrix string      `json:"displaymatrix"`
+			Rotation      json.Number `json:"rotation"`
+			RedX          string      `json:"red_x"`
+			RedY          string      `json:"red_y"`
+			GreenX        string      `json:"green_x"`
+			GreenY        string      `json:"green_y"`
+			BlueX         string      `json:"blue_x"`
+			BlueY         string      `json:"blue_y"`
+			WhitePointX   string      `json:"white_point_x"`
+			WhitePointY   string      `json:"white_point_y"`
+			MinLuminance  string      `json:"min_luminance"`
+			MaxLuminance  string      `json:"max_luminance"`
+			MaxContent    int         `json:"max_content"`
+			MaxAverage    int         `json:"max_average"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		Filename       string            `json:"filename"`
+		NbStreams      int               `json:"nb_streams"`
+		FormatName     string            `json:"format_name"`
+		FormatLongName string            `json:"format_long_name"`
+		StartTime      json.Number       `json:"start_time"`
+		Duration       json.Number       `json:"duration"`
+		Size           json.Number       `json:"size"`
+		BitRate        json.Number       `json:"bit_rate"`
+		ProbeScore     int               `json:"probe_score"`
+		Tags           map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// ProbeMediaInfo runs ffprobe on path and returns the typed description of its container and streams.
+func ProbeMediaInfo(path string) (*MediaInfo, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.New("ffmpeg.ProbeMediaInfo: unable to load file: " + err.Error())
+	}
+
+	cmdArgs := []string{"ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path}
+
+	var stdout, stderr bytes.Buffer
+	if err := GetRunner().Run(context.Background(), cmdArgs, &stdout, &stderr); err != nil {
+		if stderr.Len() == 0 {
+			return nil, errors.New("ffmpeg.ProbeMediaInfo: ffprobe failed with Error: " + err.Error())
+		}
+		return nil, errors.New("ffmpeg.ProbeMediaInfo: ffprobe failed with Error: " + stderr.String())
+	}
+
+	return parseMediaInfo(stdout.Bytes())
+}
+
+// parseMediaInfo converts ffprobe JSON output to a MediaInfo.
+func parseMediaInfo(data []byte) (*MediaInfo, error) {
+	var probe probeOutput
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, errors.New("ffmpeg.parseMediaInfo: unable to parse JSON output " +
+			"from ffprobe: " + err.Error())
+	}
+	return probe.toMediaInfo(), nil
+}
+
+// toMediaInfo converts the raw ffprobe output to a MediaInfo.
+func (p *probeOutput) toMediaInfo() *MediaInfo {
+	var info = MediaInfo{
+		Format: FormatInfo{
+			Filename:       p.Format.Filename,
+			FormatName:     p.Format.FormatName,
+			FormatLongName: p.Format.FormatLongName,
+			StreamsCount:   p.Format.NbStreams,
+			StartTime:      secondsToDuration(p.Format.StartTime),
+			Duration:       secondsToDuration(p.Format.Duration),
+			Size:           numberToInt64(p.Format.Size),
+			BitRate:        numberToInt64(p.Format.BitRate),
+			ProbeScore:     p.Format.ProbeScore,
+			Tags:           p.Format.Tags,
+		},
+		Streams: make([]StreamInfo, 0, len(p.Streams)),
+	}
+
+	for _, s := range p.Streams {
+		var stream = StreamInfo{
+			Index:              s.Index,
+			Type:               StreamType(s.CodecType),
+			CodecName:          s.CodecName,
+			CodecLongName:      s.CodecLongName,
+			CodecTag:           s.CodecTagString,
+			Profile:            s.Profile,
+			Level:              s.Level,
+			BitRate:            numberToInt64(s.BitRate),
+			Duration:           secondsToDuration(s.Duration),
+			FramesCount:        numberToInt64(s.NbFrames),
+			TimeBase:           s.TimeBase,
+			Width:              s.Width,
+			Height:             s.Height,
+			CodedWidth:         s.CodedWidth,
+			CodedHeight:        s.CodedHeight,
+			SampleAspectRatio:  s.SampleAspectRatio,
+			DisplayAspectRatio: s.DisplayAspectRatio,
+			PixelFormat:        s.PixFmt,
+			FieldOrder:         s.FieldOrder,
+			FrameRate:          parseRational(s.RFrameRate),
+			AvgFrameRate:       parseRational(s.AvgFrameRate),
+			Color: ColorInfo{
+				Range:     s.ColorRange,
+				Space:     s.ColorSpace,
+				Transfer:  s.ColorTransfer,
+				Primaries: s.ColorPrimaries,
+			},
+			SampleFormat:  s.SampleFmt,
+			SampleRate:    int(numberToInt64(s.SampleRate)),
+			Channels:      s.Channels,
+			ChannelLayout: s.ChannelLayout,
+			BitsPerSample: s.BitsPerSample,
+			Disposition:   s.Disposition,
+			Tags:          s.Tags,
+		}
+
+		for _, sd := range s.SideDataList {
+			switch sd.SideDataType {
+			case "Display Matrix":
+				stream.DisplayMatrix = &DisplayMatrix{
+					Matrix: strings.TrimSpace(sd.DisplayMatrix),
+				}
+				if rotation, err := sd.Rotation.Float64(); err == nil {
+					stream.DisplayMatrix.Rotation = rotation
+				}
+			case "Mastering display metadata":
+				stream.MasteringDisplay = &MasteringDisplayMetadata{
+					RedX:         parseRational(sd.RedX),
+					RedY:         parseRational(sd.RedY),
+					GreenX:       parseRational(sd.GreenX),
+					GreenY:       parseRational(sd.GreenY),
+					BlueX:        parseRational(sd.BlueX),
+					BlueY:        parseRational(sd.BlueY),
+					WhitePointX:  parseRational(sd.WhitePointX),
+					WhitePointY:  parseRational(sd.WhitePointY),
+					MinLuminance: parseRational(sd.MinLuminance),
+					MaxLuminance: parseRational(sd.MaxLuminance),
+				}
+			case "Content light level metadata":
+				stream.ContentLightLevel = &ContentLightLevel{
+					MaxContent: sd.MaxContent,
+					MaxAverage: sd.MaxAverage,
+				}
+			}
+		}
+
+		info.Streams = append(info.Streams, stream)
+	}
+
+	return &info
+}
+
+// streamsOfType returns all streams of a given type.
+func (m *MediaInfo) streamsOfType(streamType StreamType) []StreamInfo {
+	var streams []StreamInfo
+	for _, s := range m.Streams {
+		if s.Type == streamType {
+			streams = append(streams, s)
+		}
+	}
+	return streams
+}
+
+// VideoStreams returns the video streams of the media, attached pictures (cover arts) included.
+func (m *MediaInfo) VideoStreams() []StreamInfo {
+	return m.streamsOfType(VideoStream)
+}
+
+// AudioStreams returns the audio streams of the media.
+func (m *MediaInfo) AudioStreams() []StreamInfo {
+	return m.streamsOfType(AudioStream)
+}
+
+// SubtitleStreams returns the subtitle streams of the media.
+func (m *MediaInfo) SubtitleStreams() []StreamInfo {
+	return m.streamsOfType(SubtitleStream)
+}
+
+// DataStreams returns the data streams (timecodes, metadata tracks..) of the media.
+func (m *MediaInfo) DataStreams() []StreamInfo {
+	return m.streamsOfType(DataStream)
+}
+
+// PrimaryVideoStream returns the first video stream that is not an attached picture, nil if there is none.
+func (m *MediaInfo) PrimaryVideoStream() *StreamInfo {
+	for i := range m.Streams {
+		if m.Streams[i].Type == VideoStream && !m.Streams[i].IsAttachedPicture() && m.Streams[i].Width != 0 && m.Streams[i].Height != 0 {
+			return &m.Streams[i]
+		}
+	}
+	return nil
+}
+
+// PrimaryAudioStream returns the first audio stream, nil if there is none.
+func (m *MediaInfo) PrimaryAudioStream() *StreamInfo {
+	for i := range m.Streams {
+		if m.Streams[i].Type == AudioStream {
+			return &m.Streams[i]
+		}
+	}
+	return nil
+}
+
+// HasAudio returns true if the media contains at least one audio stream.
+func (m *MediaInfo) HasAudio() bool {
+	return m.PrimaryAudioStream() != nil
+}
+
+// Language returns the ISO 639 language tag of the stream, "" if not set.
+func (s *StreamInfo) Language() string {
+	return s.Tags["language"]
+}
+
+// Title returns the title tag of the stream, "" if not set.
+func (s *StreamInfo) Title() string {
+	return s.Tags["title"]
+}
+
+// IsAttachedPicture returns true if the stream is a cover art and not an actual video.
+func (s *StreamInfo) IsAttachedPicture() bool {
+	return s.Disposition["attached_pic"] == 1
+}
+
+// IsDefault returns true if the stream is flagged as the default stream of its type.
+func (s *StreamInfo) IsDefault() bool {
+	return s.Disposition["default"] == 1
+}
+
+// Rotation returns the rotation of the stream in degrees as reported by ffprobe, the Display Matrix (counter clockwise)
+// takes precedence over the legacy rotate tag (clockwise). Use DisplayGeometry for the normalized clockwise rotation.
+func (s *StreamInfo) Rotation() int {
+	if s.DisplayMatrix != nil {
+		return int(s.DisplayMatrix.Rotation)
+	}
+	if rotate, ok := s.Tags["rotate"]; ok {
+		if rotation, err := strconv.Atoi(rotate); err == nil {
+			return rotation
+		}
+	}
+	return 0
+}
+
+// IsHDR returns true if the stream uses a PQ (HDR10) or HLG transfer function or carries HDR side data.
+func (s *StreamInfo) IsHDR() bool {
+	switch s.Color.Transfer {
+	case "smpte2084", "arib-std-b67":
+		return true
+	}
+	return s.MasteringDisplay != nil || s.ContentLightLevel != nil
+}
+
+// parseRational parses ffprobe rationals such as "30000/1001" or plain numbers, 0 is returned for invalid values.
+func parseRational(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	parts := strings.Split(value, "/")
+	numerator, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0
+	}
+	if len(parts) == 1 {
+		return numerator
+	}
+	denominator, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
+
+// numberToInt64 converts a json.Number to int64, 0 is returned for missing or invalid values.
+func numberToInt64(n json.Number) int64 {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return int64(f)
+	}
+	return 0
+}
+
+// secondsToDuration converts a json.Number in seconds to time.Duration, 0 is returned for missing or invalid values.
+func secondsToDuration(n json.Number) time.Duration {
+	secs, err := n.Float64()
+	if err != nil {
+		return 0
+	}
+	// seconds will be >= 0 so adding 0.5 rounds to the right integer Duration value.
+	return time.Duration(secs*float64(time.Second) + 0.5)
+}
@@ -0,0 +1,105 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+)
+
+const sampleProbeOutput = `{
+	"streams": [
+		{
+			"index": 0,
+			"codec_name": "hevc",
+			"profile": "Main 10",
+			"codec_type": "video",
+			"width": 1920,
+			"height": 1080,
+			"sample_aspect_ratio": "1:1",
+			"pix_fmt": "yuv420p10le",
+			"color_range": "tv",
+			"color_space": "bt2020nc",
+			"color_transfer": "smpte2084",
+			"color_primaries": "bt2020",
+			"r_frame_rate": "30000/1001",
+			"avg_frame_rate": "30000/1001",
+			"duration": "10.010000",
+			"bit_rate": "8000000",
+			"disposition": {"default": 1, "attached_pic": 0},
+			"tags": {"rotate": "90", "language": "und"},
+			"side_data_list": [
+				{"side_data_type": "Display Matrix", "displaymatrix": "\n00000000:            0       65536           0\n", "rotation": -90},
+				{"side_data_type": "Mastering display metadata", "red_x": "34000/50000", "min_luminance": "50/10000", "max_luminance": "10000000/10000"},
+				{"side_data_type": "Content light level metadata", "max_content": 1000, "max_average": 400}
+			]
+		},
+		{
+			"index": 1,
+			"codec_name": "aac",
+			"profile": "LC",
+			"codec_type": "audio",
+			"sample_fmt": "fltp",
+			"sample_rate": "48000",
+			"channels": 6,
+			"channel_layout": "5.1",
+			"tags": {"language": "eng"}
+		},
+		{
+			"index": 2,
+			"codec_name": "mov_text",
+			"codec_type": "subtitle",
+			"tags": {"language": "fra"}
+		},
+		{
+			"index": 3,
+			"codec_type": "data",
+			"codec_tag_string": "tmcd"
+		}
+	],
+	"format": {
+		"filename": "sample.mov",
+		"nb_streams": 4,
+		"format_name": "mov,mp4,m4a,3gp,3g2,mj2",
+		"duration": "10.010000",
+		"size": "10485760",
+		"bit_rate": "8380000",
+		"tags": {"major_brand": "qt  "}
+	}
+}`
+
+func TestParseMediaInfo(t *testing.T) {
+	info, err := parseMediaInfo([]byte(sampleProbeOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(info.VideoStreams()) != 1 || len(info.AudioStreams()) != 1 || len(info.SubtitleStreams()) != 1 || len(info.DataStreams()) != 1 {
+		t.Fatalf("unexpected streams split: %+v", info.Streams)
+	}
+	if info.Format.Duration != 10010*time.Millisecond || info.Format.BitRate != 8380000 || info.Format.Tags["major_brand"] != "qt  " {
+		t.Errorf("unexpected format: %+v", info.Format)
+	}
+
+	video := info.PrimaryVideoStream()
+	if video.CodecName != "hevc" || video.Profile != "Main 10" || video.PixelFormat != "yuv420p10le" {
+		t.Errorf("unexpected video stream: %+v", video)
+	}
+	if !video.IsHDR() || video.ContentLightLevel.MaxContent != 1000 || video.MasteringDisplay.MaxLuminance != 1000 {
+		t.Errorf("unexpected HDR metadata: %+v", video)
+	}
+	if video.Rotation() != -90 {
+		t.Errorf("got rotation %d, want -90", video.Rotation())
+	}
+
+	audio := info.PrimaryAudioStream()
+	if audio.SampleRate != 48000 || audio.ChannelLayout != "5.1" || audio.Language() != "eng" {
+		t.Errorf("unexpected audio stream: %+v", audio)
+	}
+	if info.SubtitleStreams()[0].Language() != "fra" {
+		t.Errorf("unexpected subtitle stream: %+v", info.SubtitleStreams()[0])
+	}
+
+	v := newVideoFromMediaInfo("sample.mov", info)
+	if v.width != 1080 || v.height != 1920 || v.fps != 30 || v.GetMediaInfo() != info {
+		t.Errorf("unexpected video: %+v", v)
+	}
+}
@@ -0,0 +1,264 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	osUtils "github.com/sabriboughanmi/go_utils/os"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// probeFragments probes every fragment concurrently, probing errors are reported in the diagnostics.
+func probeFragments(fragmentsPath []string) []FragmentDiagnostic {
+	var diagnostics = make([]FragmentDiagnostic, len(fragmentsPath))
+	wg := sync.WaitGroup{}
+	for i, path := range fragmentsPath {
+		wg.Add(1)
+		go func(index int, path string) {
+			defer wg.Done()
+			diagnostics[index].Path = path
+			info, err := ProbeMediaInfo(path)
+			if err != nil {
+				diagnostics[index].Err = err
+				return
+			}
+			if info.PrimaryVideoStream() == nil {
+				diagnostics[index].Err = errors.New("no video stream")
+				return
+			}
+			diagnostics[index].Info = info
+		}(i, path)
+	}
+	wg.Wait()
+	return diagnostics
+}
+
+// diagnoseFragments fills the Issues of every fragment differing from the first one.
+// It returns true if all fragments can be concatenated by the concat demuxer without re-encoding.
+func diagnoseFragments(diagnostics []FragmentDiagnostic) bool {
+	var compatible = true
+	reference := diagnostics[0].Info
+	refVideo, refAudio := reference.PrimaryVideoStream(), reference.PrimaryAudioStream()
+
+	for i := range diagnostics {
+		d := &diagnostics[i]
+		video, audio := d.Info.PrimaryVideoStream(), d.Info.PrimaryAudioStream()
+
+		addIssue := func(property string, value, expected interface{}) {
+			if value != expected {
+				d.Issues = append(d.Issues, fmt.Sprintf("%s %v differs from %v", property, value, expected))
+			}
+		}
+		addIssue("video codec", video.CodecName, refVideo.CodecName)
+		addIssue("video profile", video.Profile, refVideo.Profile)
+		addIssue("resolution", fmt.Sprintf("%dx%d", video.Width, video.Height), fmt.Sprintf("%dx%d", refVideo.Width, refVideo.Height))
+		addIssue("pixel format", video.PixelFormat, refVideo.PixelFormat)
+		addIssue("sample aspect ratio", normalizedSAR(video.SampleAspectRatio), normalizedSAR(refVideo.SampleAspectRatio))
+		addIssue("rotation", video.DisplayGeometry().Rotation, refVideo.DisplayGeometry().Rotation)
+		addIssue("time base", video.TimeBase, refVideo.TimeBase)
+
+		switch {
+		case (audio == nil) != (refAudio == nil):
+			addIssue("audio stream", audio != nil, refAudio != nil)
+		case audio != nil:
+			addIssue("audio codec", audio.CodecName, refAudio.CodecName)
+			addIssue("audio sample rate", audio.SampleRate, refAudio.SampleRate)
+			addIssue("audio channels", audio.Channels, refAudio.Channels)
+		}
+
+		if len(d.Issues) > 0 {
+			compatible = false
+		}
+	}
+	return compatible
+}
+
+// normalizedSAR returns the sample aspect ratio, ffprobe reports "0:1" or nothing for unknown (square) pixels.
+func normalizedSAR(sar string) string {
+	if sar == "" || sar == "0:1" {
+		return "1:1"
+	}
+	return sar
+}
+
+// mergeTarget computes the common format of the fragments: the smallest size with the aspect ratio of the first fragment,
+// and the highest frame rate.
+func mergeTarget(diagnostics []FragmentDiagnostic) MergeTarget {
+	var target = MergeTarget{SAR: "1:1"}
+	reference := diagnostics[0].Info.PrimaryVideoStream().DisplayGeometry()
+	refWidth, refHeight := reference.Width, reference.Height
+	var shortest = 0
+	for _, d := range diagnostics {
+		video := d.Info.PrimaryVideoStream()
+		geometry := video.DisplayGeometry()
+		width, height := geometry.Width, geometry.Height
+		short := width
+		if height < short {
+			short = height
+		}
+		if shortest == 0 || short < shortest {
+			shortest = short
+		}
+		if fps := int(video.AvgFrameRate + 0.5); fps > target.FPS {
+			target.FPS = fps
+		}
+		if audio := d.Info.PrimaryAudioStream(); audio != nil && audio.SampleRate > target.SampleRate {
+			target.SampleRate = audio.SampleRate
+		}
+	}
+	if target.FPS <= 0 {
+		target.FPS = 30
+	}
+	if target.FPS > 60 {
+		target.FPS = 60
+	}
+
+	if refWidth < refHeight {
+		target.Width = toEvenNumber(shortest)
+		target.Height = toEvenNumber(shortest * refHeight / refWidth)
+	} else {
+		target.Height = toEvenNumber(shortest)
+		target.Width = toEvenNumber(shortest * refWidth / refHeight)
+	}
+	return target
+}
+
+// concatListFile returns the concat demuxer script listing the fragments.
+// The concat demuxer resolves relative paths from the directory of the script, the paths are listed as absolute paths.
+func concatListFile(fragmentsPath []string) (string, error) {
+	var list = "ffconcat version 1.0"
+	for _, path := range fragmentsPath {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		list += "\nfile '" + strings.Replace(path, "'", `'\''`, -1) + "'"
+	}
+	return list + "\n", nil
+}
+
+// concatCommandLine returns the command line concatenating the fragments listed in listPath without re-encoding.
+func concatCommandLine(listPath, outputPath string) []string {
+	return []string{
+		"ffmpeg", "-y",
+		"-f", "concat", "-safe", "0", "-i", listPath,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-c", "copy",
+		outputPath,
+	}
+}
+
+// reEncodeCommandLine returns the command line converting every fragment to target and concatenating them in a single pass.
+func reEncodeCommandLine(diagnostics []FragmentDiagnostic, target MergeTarget, profile EncodingProfile, outputPath string) []string {
+	cmdline := []string{"ffmpeg", "-y"}
+	for _, d := range diagnostics {
+		cmdline = append(cmdline, "-i", d.Path)
+	}
+
+	var chains, pads []string
+	for i, d := range diagnostics {
+		// Fragments are rotated while decoding, fit them in the target size keeping their aspect ratio.
+		chains = append(chains, fmt.Sprintf("[%d:v:0]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%d,format=yuv420p[v%d]",
+			i, target.Width, target.Height, target.Width, target.Height, target.FPS, i))
+		pads = append(pads, fmt.Sprintf("[v%d]", i))
+
+		if target.SampleRate == 0 {
+			continue
+		}
+		audioFormat := fmt.Sprintf("aresample=%d,aformat=sample_fmts=fltp:channel_layouts=stereo", target.SampleRate)
+		if d.Info.HasAudio() {
+			chains = append(chains, fmt.Sprintf("[%d:a:0]%s[a%d]", i, audioFormat, i))
+		} else {
+			// Silent fragments get a silent track so every segment of the concat filter has audio.
+			chains = append(chains, fmt.Sprintf("anullsrc=r=%d:cl=stereo,atrim=duration=%s,%s[a%d]",
+				target.SampleRate, formatSeconds(d.Info.Format.Duration), audioFormat, i))
+		}
+		pads[i] += fmt.Sprintf("[a%d]", i)
+	}
+
+	withAudio := 0
+	if target.SampleRate > 0 {
+		withAudio = 1
+	}
+	graph := strings.Join(chains, ";") + ";" + strings.Join(pads, "") +
+		fmt.Sprintf("concat=n=%d:v=1:a=%d[vout]", len(diagnostics), withAudio)
+	if withAudio == 1 {
+		graph += "[aout]"
+	}
+
+	cmdline = append(cmdline, "-filter_complex", graph, "-map", "[vout]")
+	if withAudio == 1 {
+		cmdline = append(cmdline, "-map", "[aout]")
+	}
+	cmdline = append(cmdline, profile.args()...)
+	return append(cmdline, outputPath)
+}
+
+// defaultMergeProfile is used to re-encode fragments if MergeOptions.Profile is not set.
+var defaultMergeProfile = EncodingProfile{
+	Name:        "merge",
+	VideoCodec:  H264,
+	PixelFormat: "yuv420p",
+	Preset:      Superfast,
+	CRF:         23,
+	AudioCodec:  AAC,
+}
+
+// MergeFragments merges the fragments into outputPath.
+// Every fragment is probed first: if codecs, resolution and rotation match they are concatenated without re-encoding,
+// otherwise they are re-encoded to a common MergeTarget in a single ffmpeg pass.
+//
+// The returned report describes every fragment, an error is returned if a fragment can not be merged.
+func MergeFragments(ctx context.Context, outputPath string, fragmentsPath []string, options MergeOptions) (*MergeReport, error) {
+	if len(fragmentsPath) < 2 {
+		return nil, fmt.Errorf("at least 2 fragments must be passed")
+	}
+
+	var report = MergeReport{Fragments: probeFragments(fragmentsPath)}
+	for i, d := range report.Fragments {
+		if d.Err != nil {
+			return &report, fmt.Errorf("MergeFragments: fragment %d (%s): %w", i, d.Path, d.Err)
+		}
+	}
+
+	report.StreamCopy = diagnoseFragments(report.Fragments) && !options.ForceReEncode
+	if report.StreamCopy {
+		list, err := concatListFile(fragmentsPath)
+		if err != nil {
+			return &report, fmt.Errorf("MergeFragments: %w", err)
+		}
+		listPath, err := osUtils.CreateTempFile("list.txt", []byte(list))
+		if err != nil {
+			return &report, err
+		}
+		defer os.Remove(listPath)
+
+		if err := runCommand(ctx, concatCommandLine(listPath, outputPath), 0, nil); err != nil {
+			return &report, fmt.Errorf("MergeFragments: ffmpeg failed: %w", err)
+		}
+	} else {
+		target := mergeTarget(report.Fragments)
+		report.Target = &target
+
+		profile := defaultMergeProfile
+		if options.Profile != nil {
+			profile = *options.Profile
+		}
+		var total time.Duration
+		for _, d := range report.Fragments {
+			total += d.Info.Format.Duration
+		}
+		if err := runCommand(ctx, reEncodeCommandLine(report.Fragments, target, profile, outputPath), total, nil); err != nil {
+			return &report, fmt.Errorf("MergeFragments: ffmpeg failed: %w", err)
+		}
+	}
+
+	if options.DeleteFragments {
+		osUtils.RemovePathsIfExists(fragmentsPath...)
+	}
+	return &report, nil
+}
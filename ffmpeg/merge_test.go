@@ -0,0 +1,107 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFragmentDiagnostic(path string, codec string, width, height int, rotate string, fps float64, withAudio bool) FragmentDiagnostic {
+	var info = MediaInfo{
+		Format: FormatInfo{Duration: 5 * time.Second},
+		Streams: []StreamInfo{{
+			Type: VideoStream, CodecName: codec, Width: width, Height: height, PixelFormat: "yuv420p",
+			SampleAspectRatio: "1:1", AvgFrameRate: fps, TimeBase: "1/15360", Tags: map[string]string{},
+		}},
+	}
+	if rotate != "" {
+		info.Streams[0].Tags["rotate"] = rotate
+	}
+	if withAudio {
+		info.Streams = append(info.Streams, StreamInfo{Type: AudioStream, CodecName: "aac", SampleRate: 44100, Channels: 2})
+	}
+	return FragmentDiagnostic{Path: path, Info: &info}
+}
+
+func TestDiagnoseCompatibleFragments(t *testing.T) {
+	fragments := []FragmentDiagnostic{
+		newFragmentDiagnostic("a.mp4", "h264", 1920, 1080, "", 30, true),
+		newFragmentDiagnostic("b.mp4", "h264", 1920, 1080, "", 30, true),
+	}
+	fragments[1].Info.Streams[0].SampleAspectRatio = "0:1"
+	if !diagnoseFragments(fragments) {
+		t.Errorf("identical fragments should be stream copied: %+v", fragments)
+	}
+}
+
+func TestDiagnoseIncompatibleFragments(t *testing.T) {
+	fragments := []FragmentDiagnostic{
+		newFragmentDiagnostic("a.mp4", "h264", 1920, 1080, "", 30, true),
+		newFragmentDiagnostic("b.mp4", "hevc", 1920, 1080, "90", 60, true),
+		newFragmentDiagnostic("c.mp4", "h264", 1920, 1080, "", 30, false),
+	}
+	if diagnoseFragments(fragments) {
+		t.Fatalf("incompatible fragments should be re-encoded")
+	}
+	if len(fragments[0].Issues) != 0 {
+		t.Errorf("the first fragment is the reference, got issues %v", fragments[0].Issues)
+	}
+	expected := []string{"video codec hevc differs from h264", "rotation 90 differs from 0"}
+	if strings.Join(fragments[1].Issues, ";") != strings.Join(expected, ";") {
+		t.Errorf("got issues %v, want %v", fragments[1].Issues, expected)
+	}
+	if len(fragments[2].Issues) != 1 || !strings.HasPrefix(fragments[2].Issues[0], "audio stream") {
+		t.Errorf("unexpected issues %v", fragments[2].Issues)
+	}
+
+	target := mergeTarget(fragments)
+	if target != (MergeTarget{Width: 1920, Height: 1080, FPS: 60, SAR: "1:1", SampleRate: 44100}) {
+		t.Errorf("unexpected target %+v", target)
+	}
+
+	line := strings.Join(reEncodeCommandLine(fragments, target, defaultMergeProfile, "out.mp4"), " ")
+	for _, part := range []string{
+		"[1:v:0]scale=1920:1080:force_original_aspect_ratio=decrease,pad=1920:1080:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=60,format=yuv420p[v1]",
+		"anullsrc=r=44100:cl=stereo,atrim=duration=5,aresample=44100,aformat=sample_fmts=fltp:channel_layouts=stereo[a2]",
+		"[v0][a0][v1][a1][v2][a2]concat=n=3:v=1:a=1[vout][aout]",
+		"-map [vout] -map [aout] -c:v libx264 -preset superfast -crf 23",
+	} {
+		if !strings.Contains(line, part) {
+			t.Errorf("command line does not contain %q:\n%s", part, line)
+		}
+	}
+}
+
+func TestMergeTargetPortrait(t *testing.T) {
+	fragments := []FragmentDiagnostic{
+		newFragmentDiagnostic("a.mp4", "h264", 1280, 720, "90", 30, false),
+		newFragmentDiagnostic("b.mp4", "h264", 480, 854, "", 25, false),
+	}
+	target := mergeTarget(fragments)
+	if target.Width != 480 || target.Height != 854 || target.FPS != 30 || target.SampleRate != 0 {
+		t.Errorf("unexpected target %+v", target)
+	}
+	line := strings.Join(reEncodeCommandLine(fragments, target, defaultMergeProfile, "out.mp4"), " ")
+	if !strings.Contains(line, "[v0][v1]concat=n=2:v=1:a=0[vout] -map [vout] -c:v") {
+		t.Errorf("unexpected command line:\n%s", line)
+	}
+}
+
+func TestConcatListFile(t *testing.T) {
+	expected := "ffconcat version 1.0\nfile '/tmp/a.mp4'\nfile '/tmp/it'\\''s.mp4'\n"
+	if got, err := concatListFile([]string{"/tmp/a.mp4", "/tmp/it's.mp4"}); err != nil || got != expected {
+		t.Errorf("got %q (%v), want %q", got, err, expected)
+	}
+
+	// The list is written to the temporary directory, relative paths are resolved from the working directory.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "ffconcat version 1.0\nfile '" + filepath.Join(wd, "fragments", "a.mp4") + "'\n"
+	if got, err := concatListFile([]string{"fragments/a.mp4"}); err != nil || got != expected {
+		t.Errorf("got %q (%v), want %q", got, err, expected)
+	}
+}
@@ -0,0 +1,161 @@
+package ffmpeg
+
+import (
+	Vision "cloud.google.com/go/vision/apiv1"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FrameModerator scores the forbidden content of a single frame.
+type FrameModerator interface {
+	ModerateFrame(ctx context.Context, frame Frame) (FrameScores, error)
+}
+
+// VisionModerator is a FrameModerator backed by the Cloud Vision SafeSearch detection.
+type VisionModerator struct {
+	Client *Vision.ImageAnnotatorClient
+}
+
+// ModerateFrame returns the SafeSearch scores of the frame.
+func (m *VisionModerator) ModerateFrame(ctx context.Context, frame Frame) (FrameScores, error) {
+	f, err := os.Open(frame.Path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open, Error:  %v", err)
+	}
+	defer f.Close()
+
+	image, err := Vision.NewImageFromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("NewImageFromReader, Error:  %v", err)
+	}
+	props, err := m.Client.DetectSafeSearch(ctx, image, nil)
+	if err != nil {
+		return nil, fmt.Errorf("DetectSafeSearch, Error:  %v", err)
+	}
+
+	return FrameScores{
+		AdultContent:    Likelihood(props.GetAdult().Number()),
+		ViolenceContent: Likelihood(props.GetViolence().Number()),
+		RacyContent:     Likelihood(props.GetRacy().Number()),
+		MedicalContent:  Likelihood(props.GetMedical().Number()),
+		SpoofContent:    Likelihood(props.GetSpoof().Number()),
+	}, nil
+}
+
+// FakeFrameModerator is a FrameModerator returning predefined scores, it is intended for tests and offline development.
+type FakeFrameModerator struct {
+	Scores   FrameScores                   // returned for every frame not listed in ScoresAt
+	ScoresAt map[time.Duration]FrameScores // scores of the frames at a given timestamp
+	Err      error                         // if set, returned for every frame
+
+	mu     sync.Mutex
+	frames []Frame
+}
+
+// ModerateFrame returns the predefined scores of the frame.
+func (m *FakeFrameModerator) ModerateFrame(ctx context.Context, frame Frame) (FrameScores, error) {
+	m.mu.Lock()
+	m.frames = append(m.frames, frame)
+	m.mu.Unlock()
+
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if scores, ok := m.ScoresAt[frame.Timestamp]; ok {
+		return scores, nil
+	}
+	return m.Scores, nil
+}
+
+// ModeratedFrames returns the frames received by the moderator, sorted by Timestamp.
+func (m *FakeFrameModerator) ModeratedFrames() []Frame {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var frames = make([]Frame, len(m.frames))
+	copy(frames, m.frames)
+	sort.Slice(frames, func(i, j int) bool { return frames[i].Timestamp < frames[j].Timestamp })
+	return frames
+}
+
+// Violations returns the categories of scores above their threshold, sorted by name.
+func (t ModerationThresholds) Violations(scores FrameScores) []ModerationCategory {
+	var violations []ModerationCategory
+	for category, threshold := range t {
+		if scores[category] > threshold {
+			violations = append(violations, category)
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i] < violations[j] })
+	return violations
+}
+
+// RejectedFrames returns the frames containing forbidden content.
+func (r *ModerationReport) RejectedFrames() []FrameModeration {
+	var frames []FrameModeration
+	for _, f := range r.Frames {
+		if len(f.Violations) > 0 {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+// Errors returns the errors of the frames that could not be moderated.
+func (r *ModerationReport) Errors() []error {
+	var errs []error
+	for _, f := range r.Frames {
+		if f.Err != nil {
+			errs = append(errs, f.Err)
+		}
+	}
+	return errs
+}
+
+// Moderate samples frames of the video in a single ffmpeg pass and scores them using moderator,
+// at most sampling.Workers frames are moderated concurrently.
+// The returned error is only set if the frames could not be extracted, frames errors are reported in the ModerationReport.
+func (v *Video) Moderate(ctx context.Context, sampling SamplingOptions, moderator FrameModerator, thresholds ModerationThresholds) (*ModerationReport, error) {
+	dir, frames, err := v.ExtractFrames(ctx, sampling)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var report = ModerationReport{Frames: make([]FrameModeration, len(frames))}
+	processFrames(frames, sampling.Workers, func(index int, frame Frame) {
+		report.Frames[index] = evaluateFrame(ctx, frame, moderator, thresholds)
+	})
+
+	for _, f := range report.Frames {
+		if len(f.Violations) > 0 {
+			report.Rejected = true
+			break
+		}
+	}
+	return &report, nil
+}
+
+// evaluateFrame scores an already extracted frame and checks it against thresholds.
+func evaluateFrame(ctx context.Context, frame Frame, moderator FrameModerator, thresholds ModerationThresholds) FrameModeration {
+	var result = FrameModeration{Timestamp: frame.Timestamp}
+	scores, err := moderator.ModerateFrame(ctx, frame)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Scores = scores
+	result.Violations = thresholds.Violations(scores)
+	return result
+}
+
+// legacyThresholds returns the thresholds used by ModerateVideo and ModerateVideoFrame.
+func legacyThresholds(tolerance int32) ModerationThresholds {
+	return ModerationThresholds{
+		AdultContent:    Likelihood(tolerance),
+		ViolenceContent: Likelihood(tolerance),
+	}
+}
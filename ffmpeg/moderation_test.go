@@ -0,0 +1,67 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestModerationThresholdsViolations(t *testing.T) {
+	thresholds := ModerationThresholds{
+		AdultContent:   LikelihoodUnlikely,
+		RacyContent:    LikelihoodPossible,
+		MedicalContent: LikelihoodLikely,
+	}
+	scores := FrameScores{
+		AdultContent:    LikelihoodPossible,
+		RacyContent:     LikelihoodPossible,
+		MedicalContent:  LikelihoodVeryLikely,
+		ViolenceContent: LikelihoodVeryLikely, // not checked
+	}
+
+	violations := thresholds.Violations(scores)
+	if !reflect.DeepEqual(violations, []ModerationCategory{AdultContent, MedicalContent}) {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+}
+
+func TestEvaluateFrameWithFakeModerator(t *testing.T) {
+	moderator := &FakeFrameModerator{
+		Scores:   FrameScores{AdultContent: LikelihoodVeryUnlikely},
+		ScoresAt: map[time.Duration]FrameScores{5 * time.Second: {AdultContent: LikelihoodVeryLikely}},
+	}
+	thresholds := legacyThresholds(int32(LikelihoodPossible))
+
+	clean := evaluateFrame(context.Background(), Frame{Path: "0.png"}, moderator, thresholds)
+	rejected := evaluateFrame(context.Background(), Frame{Path: "5.png", Timestamp: 5 * time.Second}, moderator, thresholds)
+	if clean.Err != nil || len(clean.Violations) != 0 {
+		t.Errorf("unexpected result for a clean frame: %+v", clean)
+	}
+	if rejected.Timestamp != 5*time.Second || !reflect.DeepEqual(rejected.Violations, []ModerationCategory{AdultContent}) {
+		t.Errorf("unexpected result for a forbidden frame: %+v", rejected)
+	}
+	if frames := moderator.ModeratedFrames(); len(frames) != 2 || frames[1].Path != "5.png" {
+		t.Errorf("unexpected moderated frames: %v", frames)
+	}
+
+	moderator.Err = errors.New("offline")
+	if failed := evaluateFrame(context.Background(), Frame{}, moderator, thresholds); failed.Err != moderator.Err {
+		t.Errorf("got %v, want %v", failed.Err, moderator.Err)
+	}
+}
+
+func TestModerationReport(t *testing.T) {
+	report := ModerationReport{Frames: []FrameModeration{
+		{Timestamp: 0},
+		{Timestamp: time.Second, Violations: []ModerationCategory{ViolenceContent}},
+		{Timestamp: 2 * time.Second, Err: errors.New("failed")},
+	}}
+	if rejected := report.RejectedFrames(); len(rejected) != 1 || rejected[0].Timestamp != time.Second {
+		t.Errorf("unexpected rejected frames: %v", rejected)
+	}
+	if errs := report.Errors(); len(errs) != 1 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,178 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// scaledHeight returns the even height of the output video scaled to width.
+func (v *EditableVideo) scaledHeight(width int) int {
+	if v.width <= 0 || v.height <= 0 {
+		return width
+	}
+	return toEvenNumber((width*v.height + v.width/2) / v.width)
+}
+
+// previewCommandLine returns the command line rendering the animated preview to outputPath.
+func (v *EditableVideo) previewCommandLine(outputPath string, options PreviewOptions) []string {
+	cmdline := []string{"ffmpeg", "-y"}
+	if start := v.start + options.Start; start > 0 {
+		cmdline = append(cmdline, "-ss", formatSeconds(start))
+	}
+	cmdline = append(cmdline, "-t", formatSeconds(options.Duration), "-i", v.filepath)
+
+	filters := v.displayFilters()
+	filters.FPS(options.FPS).Scale(options.Width, -2)
+	inputArgs, graph, outputPad := filters.Build("0:v", 1)
+	cmdline = append(cmdline, inputArgs...)
+
+	if options.Format == AnimatedGIF {
+		// A palette generated from the preview frames gives far better colors than the default GIF palette.
+		graph += fmt.Sprintf(";[%s]split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse[preview]", outputPad)
+		outputPad = "preview"
+	}
+	cmdline = append(cmdline, "-filter_complex", graph, "-map", "["+outputPad+"]", "-an")
+
+	if options.Format == AnimatedGIF {
+		cmdline = append(cmdline, "-f", "gif")
+	} else {
+		cmdline = append(cmdline, "-c:v", "libwebp", "-quality", "75", "-f", "webp")
+	}
+	return append(cmdline, "-loop", "0", outputPath)
+}
+
+// GetAnimatedPreview creates a looping animated WebP or GIF preview of the output video at outputPath.
+func (v *EditableVideo) GetAnimatedPreview(ctx context.Context, outputPath string, options PreviewOptions) error {
+	if options.Duration <= 0 {
+		options.Duration = 3 * time.Second
+	}
+	if options.FPS <= 0 {
+		options.FPS = 10
+	}
+	if options.Width <= 0 {
+		options.Width = 320
+	}
+	if remaining := v.outputDuration() - options.Start; remaining < options.Duration {
+		options.Duration = remaining
+	}
+	if options.Duration <= 0 {
+		return fmt.Errorf("Video.GetAnimatedPreview: start %v is after the end of the video", options.Start)
+	}
+
+	if err := runCommand(ctx, v.previewCommandLine(outputPath, options), options.Duration, nil); err != nil {
+		return fmt.Errorf("Video.GetAnimatedPreview: ffmpeg failed: %w", err)
+	}
+	return nil
+}
+
+// spriteSheetName is the file name pattern of the sheets generated by GetSpriteSheet.
+const spriteSheetName = "sprite_%03d.jpg"
+
+// spriteTiles returns the tiles of the output video, sheets are numbered from 1 like the ffmpeg image2 muxer.
+func (v *EditableVideo) spriteTiles(options SpriteOptions) []SpriteTile {
+	duration := v.outputDuration()
+	count := int((duration + options.Interval - 1) / options.Interval)
+	height := v.scaledHeight(options.Width)
+
+	var tiles []SpriteTile
+	for i := 0; i < count; i++ {
+		index := i % (options.Columns * options.Rows)
+		tile := SpriteTile{
+			Start:  time.Duration(i) * options.Interval,
+			End:    time.Duration(i+1) * options.Interval,
+			Image:  fmt.Sprintf(spriteSheetName, i/(options.Columns*options.Rows)+1),
+			X:      (index % options.Columns) * options.Width,
+			Y:      (index / options.Columns) * height,
+			Width:  options.Width,
+			Height: height,
+		}
+		if tile.End > duration {
+			tile.End = duration
+		}
+		tiles = append(tiles, tile)
+	}
+	return tiles
+}
+
+// spriteCommandLine returns the command line rendering the sheets to outputDir.
+func (v *EditableVideo) spriteCommandLine(outputDir string, options SpriteOptions) []string {
+	cmdline := []string{"ffmpeg", "-y"}
+	cmdline = append(cmdline, v.inputSeekArgs()...)
+	cmdline = append(cmdline, "-i", v.filepath)
+
+	filters := v.displayFilters()
+	filters.Filter("fps=1/"+formatSeconds(options.Interval)).
+		Scale(options.Width, v.scaledHeight(options.Width)).
+		Filter(fmt.Sprintf("tile=%dx%d", options.Columns, options.Rows))
+	inputArgs, graph, outputPad := filters.Build("0:v", 1)
+	cmdline = append(cmdline, inputArgs...)
+	cmdline = append(cmdline, "-filter_complex", graph, "-map", "["+outputPad+"]", "-an")
+	cmdline = append(cmdline, v.durationArgs()...)
+	return append(cmdline, "-vsync", "vfr", "-q:v", "3", filepath.Join(outputDir, spriteSheetName))
+}
+
+// GetSpriteSheet creates JPEG sprite sheets of frames taken every options.Interval in outputDir,
+// and the WebVTT thumbnails track (thumbnails.vtt) mapping every time range to its tile, as used by video players scrubbing previews.
+func (v *EditableVideo) GetSpriteSheet(ctx context.Context, outputDir string, options SpriteOptions) (*SpriteSheet, error) {
+	if options.Interval <= 0 {
+		options.Interval = 10 * time.Second
+	}
+	if options.Width <= 0 {
+		options.Width = 160
+	}
+	if options.Columns <= 0 {
+		options.Columns = 10
+	}
+	tilesCount := int((v.outputDuration() + options.Interval - 1) / options.Interval)
+	if tilesCount == 0 {
+		return nil, fmt.Errorf("Video.GetSpriteSheet: the video is empty")
+	}
+	if options.Rows <= 0 {
+		options.Rows = (tilesCount + options.Columns - 1) / options.Columns
+	}
+
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := runCommand(ctx, v.spriteCommandLine(outputDir, options), v.outputDuration(), nil); err != nil {
+		return nil, fmt.Errorf("Video.GetSpriteSheet: ffmpeg failed: %w", err)
+	}
+
+	var sheet = SpriteSheet{
+		WebVTT: filepath.Join(outputDir, "thumbnails.vtt"),
+		Tiles:  v.spriteTiles(options),
+	}
+	for _, tile := range sheet.Tiles {
+		path := filepath.Join(outputDir, tile.Image)
+		if len(sheet.Images) == 0 || sheet.Images[len(sheet.Images)-1] != path {
+			sheet.Images = append(sheet.Images, path)
+		}
+	}
+	if err := ioutil.WriteFile(sheet.WebVTT, []byte(spriteWebVTT(sheet.Tiles, options.URLPrefix)), 0644); err != nil {
+		return nil, err
+	}
+	return &sheet, nil
+}
+
+// spriteWebVTT returns the WebVTT thumbnails track of tiles using the media fragments syntax, e.g "sprite_001.jpg#xywh=160,0,160,90".
+func spriteWebVTT(tiles []SpriteTile, urlPrefix string) string {
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+	for _, tile := range tiles {
+		fmt.Fprintf(&vtt, "\n%s --> %s\n%s%s#xywh=%d,%d,%d,%d\n",
+			formatWebVTTTime(tile.Start), formatWebVTTTime(tile.End),
+			urlPrefix, tile.Image, tile.X, tile.Y, tile.Width, tile.Height)
+	}
+	return vtt.String()
+}
+
+// formatWebVTTTime formats a duration as a WebVTT timestamp, e.g "00:01:02.500".
+func formatWebVTTTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,69 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreviewCommandLine(t *testing.T) {
+	video := (&Video{filepath: "in.mp4", width: 1280, height: 720, start: 5 * time.Second, end: 20 * time.Second, duration: 30 * time.Second}).GetEditableVideo()
+	options := PreviewOptions{Format: AnimatedGIF, Start: 2 * time.Second, Duration: 3 * time.Second, FPS: 10, Width: 320}
+
+	expected := []string{
+		"ffmpeg", "-y", "-ss", "7", "-t", "3", "-i", "in.mp4",
+		"-filter_complex", "[0:v]fps=10,scale=320:-2[vout];[vout]split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse[preview]",
+		"-map", "[preview]", "-an", "-f", "gif", "-loop", "0", "out.gif",
+	}
+	if got := video.previewCommandLine("out.gif", options); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+
+	options.Format = AnimatedWebP
+	line := strings.Join(video.previewCommandLine("out.webp", options), " ")
+	if !strings.Contains(line, "-map [vout] -an -c:v libwebp") || strings.Contains(line, "palettegen") {
+		t.Errorf("unexpected WebP command line:\n%s", line)
+	}
+}
+
+func TestSpriteTiles(t *testing.T) {
+	video := (&Video{filepath: "in.mp4", width: 1280, height: 720, end: 25 * time.Second, duration: 25 * time.Second}).GetEditableVideo()
+	options := SpriteOptions{Interval: 10 * time.Second, Width: 160, Columns: 2, Rows: 1}
+
+	expected := []SpriteTile{
+		{Start: 0, End: 10 * time.Second, Image: "sprite_001.jpg", X: 0, Y: 0, Width: 160, Height: 90},
+		{Start: 10 * time.Second, End: 20 * time.Second, Image: "sprite_001.jpg", X: 160, Y: 0, Width: 160, Height: 90},
+		{Start: 20 * time.Second, End: 25 * time.Second, Image: "sprite_002.jpg", X: 0, Y: 0, Width: 160, Height: 90},
+	}
+	tiles := video.spriteTiles(options)
+	if !reflect.DeepEqual(tiles, expected) {
+		t.Fatalf("got  %+v\nwant %+v", tiles, expected)
+	}
+
+	line := strings.Join(video.spriteCommandLine("out", options), " ")
+	if !strings.Contains(line, "[0:v]fps=1/10,scale=160:90,tile=2x1[vout]") {
+		t.Errorf("unexpected command line:\n%s", line)
+	}
+
+	expectedVTT := `WEBVTT
+
+00:00:00.000 --> 00:00:10.000
+https://cdn/sprite_001.jpg#xywh=0,0,160,90
+
+00:00:10.000 --> 00:00:20.000
+https://cdn/sprite_001.jpg#xywh=160,0,160,90
+
+00:00:20.000 --> 00:00:25.000
+https://cdn/sprite_002.jpg#xywh=0,0,160,90
+`
+	if vtt := spriteWebVTT(tiles, "https://cdn/"); vtt != expectedVTT {
+		t.Errorf("got\n%s\nwant\n%s", vtt, expectedVTT)
+	}
+}
+
+func TestFormatWebVTTTime(t *testing.T) {
+	if got := formatWebVTTTime(time.Hour + 2*time.Minute + 3500*time.Millisecond); got != "01:02:03.500" {
+		t.Errorf("got %s", got)
+	}
+}
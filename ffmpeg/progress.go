@@ -0,0 +1,120 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// withProgressArgs returns a copy of cmdline that makes ffmpeg write machine readable progress to stdout.
+func withProgressArgs(cmdline []string) []string {
+	var args = make([]string, 0, len(cmdline)+3)
+	args = append(args, cmdline[0], "-progress", "pipe:1", "-nostats")
+	return append(args, cmdline[1:]...)
+}
+
+// runCommand executes cmdline and waits for it to finish. The process is killed as soon as ctx is done.
+//
+// total: is the expected output duration, used to compute Progress.Percent (0 if unknown).
+//
+// progressFn: is optional, if set ffmpeg progress will be reported to it.
+func runCommand(ctx context.Context, cmdline []string, total time.Duration, progressFn ProgressFunc) error {
+	return runCommandWithLogs(ctx, cmdline, total, progressFn, nil)
+}
+
+// runCommandWithLogs is runCommand with an optional writer receiving ffmpeg logs (stderr).
+func runCommandWithLogs(ctx context.Context, cmdline []string, total time.Duration, progressFn ProgressFunc, logs io.Writer) error {
+	var stderr bytes.Buffer
+	var stderrWriter io.Writer = &stderr
+	if logs != nil {
+		stderrWriter = io.MultiWriter(&stderr, logs)
+	}
+
+	var stdout *io.PipeWriter
+	var progressDone chan struct{}
+	if progressFn != nil {
+		cmdline = withProgressArgs(cmdline)
+		var reader *io.PipeReader
+		reader, stdout = io.Pipe()
+		progressDone = make(chan struct{})
+		go func() {
+			defer close(progressDone)
+			readProgress(reader, total, progressFn)
+			// Never block the process if the progress could not be parsed until the end.
+			io.Copy(ioutil.Discard, reader)
+		}()
+	}
+
+	var err error
+	if stdout != nil {
+		err = GetRunner().Run(ctx, cmdline, stdout, stderrWriter)
+		stdout.Close()
+		<-progressDone
+	} else {
+		err = GetRunner().Run(ctx, cmdline, nil, stderrWriter)
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("execution interrupted: %w", ctxErr)
+	}
+	if err != nil {
+		if stderr.Len() > 0 {
+			return errors.New(stderr.String())
+		}
+		return err
+	}
+	return nil
+}
+
+// readProgress parses ffmpeg -progress key=value blocks from r until EOF and reports each block to progressFn.
+func readProgress(r io.Reader, total time.Duration, progressFn ProgressFunc) {
+	var progress Progress
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		sep := strings.Index(line, "=")
+		if sep < 0 {
+			continue
+		}
+		key, value := line[:sep], strings.TrimSpace(line[sep+1:])
+
+		switch key {
+		case "frame":
+			progress.Frame, _ = strconv.ParseInt(value, 10, 64)
+		case "fps":
+			progress.FPS, _ = strconv.ParseFloat(value, 64)
+		case "bitrate":
+			progress.Bitrate = value
+		case "total_size":
+			progress.TotalSize, _ = strconv.ParseInt(value, 10, 64)
+		case "out_time_us":
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil {
+				progress.OutTime = time.Duration(us) * time.Microsecond
+			}
+		case "dup_frames":
+			progress.DupFrames, _ = strconv.ParseInt(value, 10, 64)
+		case "drop_frames":
+			progress.DropFrames, _ = strconv.ParseInt(value, 10, 64)
+		case "speed":
+			progress.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+		case "progress":
+			// "progress" closes every block.
+			progress.Done = value == "end"
+			progress.Percent = 0
+			if total > 0 {
+				progress.Percent = float64(progress.OutTime) / float64(total) * 100
+				if progress.Percent > 100 || progress.Done {
+					progress.Percent = 100
+				}
+			}
+			progressFn(progress)
+		}
+	}
+}
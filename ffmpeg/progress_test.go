@@ -0,0 +1,70 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleProgressOutput = `frame=60
+fps=29.97
+stream_0_0_q=28.0
+bitrate=1200.5kbits/s
+total_size=262144
+out_time_us=2000000
+out_time_ms=2000000
+out_time=00:00:02.000000
+dup_frames=0
+drop_frames=1
+speed=2.05x
+progress=continue
+frame=120
+fps=29.97
+bitrate=1180.0kbits/s
+total_size=524288
+out_time_us=4000000
+out_time=00:00:04.000000
+dup_frames=0
+drop_frames=1
+speed=N/A
+progress=end
+`
+
+func TestReadProgress(t *testing.T) {
+	var reports []Progress
+	readProgress(strings.NewReader(sampleProgressOutput), 8*time.Second, func(p Progress) {
+		reports = append(reports, p)
+	})
+
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want 2", len(reports))
+	}
+	first := reports[0]
+	if first.Frame != 60 || first.OutTime != 2*time.Second || first.Speed != 2.05 || first.Percent != 25 || first.DropFrames != 1 || first.Done {
+		t.Errorf("unexpected first report: %+v", first)
+	}
+	last := reports[1]
+	if last.Frame != 120 || last.Speed != 0 || last.Percent != 100 || !last.Done {
+		t.Errorf("unexpected last report: %+v", last)
+	}
+}
+
+func TestRunCommandCancellation(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not found in PATH")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := runCommand(ctx, []string{"sleep", "10"}, 0, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("process was not killed when the context expired")
+	}
+}
@@ -0,0 +1,334 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// transcodeQueueState is the content of the TranscodeQueue state file.
+type transcodeQueueState struct {
+	LastID int64
+	Jobs   []TranscodeJob // in submission order
+}
+
+// TranscodeQueue renders EditableVideo jobs by priority with a limited number of concurrent ffmpeg processes,
+// it should be shared by every caller of a process instead of rendering in background.
+type TranscodeQueue struct {
+	options TranscodeQueueOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	state   transcodeQueueState
+	cancels map[string]context.CancelFunc // of the running jobs
+	done    map[string]chan struct{}      // closed when the job is finished
+	closed  bool
+	saveErr error // last error persisting the state from a worker
+}
+
+// NewTranscodeQueue creates a queue and starts its workers.
+// If options.StatePath exists, its unfinished jobs are queued again: jobs interrupted by a crash are rendered from the beginning.
+func NewTranscodeQueue(options TranscodeQueueOptions) (*TranscodeQueue, error) {
+	if options.MaxParallel <= 0 {
+		options.MaxParallel = runtime.NumCPU() / 4
+		if options.MaxParallel < 1 {
+			options.MaxParallel = 1
+		}
+	}
+
+	q := &TranscodeQueue{
+		options: options,
+		cancels: map[string]context.CancelFunc{},
+		done:    map[string]chan struct{}{},
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	if options.StatePath != "" {
+		data, err := ioutil.ReadFile(options.StatePath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("NewTranscodeQueue: %w", err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &q.state); err != nil {
+				return nil, fmt.Errorf("NewTranscodeQueue: invalid state file %s: %w", options.StatePath, err)
+			}
+		}
+	}
+	for i := range q.state.Jobs {
+		job := &q.state.Jobs[i]
+		q.done[job.ID] = make(chan struct{})
+		switch job.Status {
+		case JobRunning:
+			job.Status = JobQueued
+			job.Percent = 0
+			job.StartedAt = time.Time{}
+		case JobDone, JobFailed, JobCanceled:
+			close(q.done[job.ID])
+		}
+	}
+	if err := q.save(); err != nil {
+		return nil, fmt.Errorf("NewTranscodeQueue: %w", err)
+	}
+
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	for i := 0; i < options.MaxParallel; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+	return q, nil
+}
+
+// Add queues the render of video to output and returns the job ID.
+// The command line is computed immediately, later changes to video do not affect the job.
+func (q *TranscodeQueue) Add(video *EditableVideo, output string, priority int) (string, error) {
+	cmdline := video.commandLine(output)
+	duration := video.outputDuration()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return "", errors.New("TranscodeQueue.Add: the queue is closed")
+	}
+
+	q.state.LastID++
+	job := TranscodeJob{
+		ID:          strconv.FormatInt(q.state.LastID, 10),
+		Priority:    priority,
+		Output:      output,
+		CommandLine: cmdline,
+		Duration:    duration,
+		Status:      JobQueued,
+		CreatedAt:   time.Now(),
+	}
+	q.state.Jobs = append(q.state.Jobs, job)
+	if err := q.save(); err != nil {
+		q.state.Jobs = q.state.Jobs[:len(q.state.Jobs)-1]
+		return "", fmt.Errorf("TranscodeQueue.Add: %w", err)
+	}
+	q.done[job.ID] = make(chan struct{})
+	q.cond.Signal()
+	return job.ID, nil
+}
+
+// Status returns the current state of the job.
+func (q *TranscodeQueue) Status(id string) (TranscodeJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.job(id)
+	if job == nil {
+		return TranscodeJob{}, fmt.Errorf("TranscodeQueue.Status: unknown job %s", id)
+	}
+	return job.clone(), nil
+}
+
+// Jobs returns the state of every job, in submission order.
+func (q *TranscodeQueue) Jobs() []TranscodeJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var jobs = make([]TranscodeJob, len(q.state.Jobs))
+	for i := range q.state.Jobs {
+		jobs[i] = q.state.Jobs[i].clone()
+	}
+	return jobs
+}
+
+// Wait waits for the job to finish and returns its final state.
+func (q *TranscodeQueue) Wait(ctx context.Context, id string) (TranscodeJob, error) {
+	q.mu.Lock()
+	done, ok := q.done[id]
+	q.mu.Unlock()
+	if !ok {
+		return TranscodeJob{}, fmt.Errorf("TranscodeQueue.Wait: unknown job %s", id)
+	}
+
+	select {
+	case <-done:
+		return q.Status(id)
+	case <-q.ctx.Done():
+		return TranscodeJob{}, errors.New("TranscodeQueue.Wait: the queue is closed")
+	case <-ctx.Done():
+		return TranscodeJob{}, ctx.Err()
+	}
+}
+
+// Cancel cancels a queued job, or kills the ffmpeg process of a running job.
+func (q *TranscodeQueue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.job(id)
+	if job == nil {
+		return fmt.Errorf("TranscodeQueue.Cancel: unknown job %s", id)
+	}
+
+	switch job.Status {
+	case JobQueued:
+		job.Status = JobCanceled
+		job.FinishedAt = time.Now()
+		close(q.done[id])
+	case JobRunning:
+		// The worker keeps the status and finishes the job once the process is killed.
+		job.Status = JobCanceled
+		q.cancels[id]()
+	default:
+		return fmt.Errorf("TranscodeQueue.Cancel: job %s is already %s", id, job.Status)
+	}
+	return q.save()
+}
+
+// Remove forgets a finished job.
+func (q *TranscodeQueue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, job := range q.state.Jobs {
+		if job.ID != id {
+			continue
+		}
+		if job.Status == JobQueued || job.Status == JobRunning {
+			return fmt.Errorf("TranscodeQueue.Remove: job %s is %s", id, job.Status)
+		}
+		q.state.Jobs = append(q.state.Jobs[:i], q.state.Jobs[i+1:]...)
+		delete(q.done, id)
+		return q.save()
+	}
+	return fmt.Errorf("TranscodeQueue.Remove: unknown job %s", id)
+}
+
+// Close stops the workers and kills the running processes, their jobs stay queued and are resumed by the next queue
+// using the same state file. It returns the last error persisting the state, if any.
+func (q *TranscodeQueue) Close() error {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	q.cancel()
+	q.workers.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.saveErr
+}
+
+// work runs the queued jobs until the queue is closed.
+func (q *TranscodeQueue) work() {
+	defer q.workers.Done()
+	for {
+		q.mu.Lock()
+		index := q.next()
+		for index < 0 && !q.closed {
+			q.cond.Wait()
+			index = q.next()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+
+		job := &q.state.Jobs[index]
+		job.Status = JobRunning
+		job.StartedAt = time.Now()
+		ctx, cancel := context.WithCancel(q.ctx)
+		q.cancels[job.ID] = cancel
+		id, cmdline, duration := job.ID, job.CommandLine, job.Duration
+		q.saveFromWorker()
+		q.mu.Unlock()
+
+		err := runCommand(ctx, cmdline, duration, func(progress Progress) {
+			q.mu.Lock()
+			if job := q.job(id); job != nil && job.Status == JobRunning {
+				job.Percent = progress.Percent
+			}
+			q.mu.Unlock()
+		})
+		cancel()
+		q.finish(id, err)
+	}
+}
+
+// finish records the result of a job run by a worker.
+func (q *TranscodeQueue) finish(id string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.cancels, id)
+	job := q.job(id)
+
+	switch {
+	case job.Status == JobCanceled:
+	case err == nil:
+		job.Status = JobDone
+		job.Percent = 100
+	case q.closed:
+		// Interrupted by Close, the job is resumed by the next queue.
+		job.Status = JobQueued
+		job.Percent = 0
+		job.StartedAt = time.Time{}
+		q.saveFromWorker()
+		return
+	default:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+	job.FinishedAt = time.Now()
+	close(q.done[id])
+	q.saveFromWorker()
+}
+
+// next returns the index of the queued job to run next, -1 if there is none. q.mu must be held.
+func (q *TranscodeQueue) next() int {
+	var index = -1
+	for i, job := range q.state.Jobs {
+		if job.Status == JobQueued && (index < 0 || job.Priority > q.state.Jobs[index].Priority) {
+			index = i
+		}
+	}
+	return index
+}
+
+// job returns the job with the given ID, nil if there is none. q.mu must be held.
+func (q *TranscodeQueue) job(id string) *TranscodeJob {
+	for i := range q.state.Jobs {
+		if q.state.Jobs[i].ID == id {
+			return &q.state.Jobs[i]
+		}
+	}
+	return nil
+}
+
+// save writes the state file, the previous file is atomically replaced so a crash never corrupts it. q.mu must be held.
+func (q *TranscodeQueue) save() error {
+	if q.options.StatePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(q.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := q.options.StatePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, q.options.StatePath)
+}
+
+// saveFromWorker saves the state, the error is returned by Close as workers have no caller to report it to. q.mu must be held.
+func (q *TranscodeQueue) saveFromWorker() {
+	if err := q.save(); err != nil {
+		q.saveErr = err
+	}
+}
+
+// clone returns a copy of the job that does not share its command line.
+func (j TranscodeJob) clone() TranscodeJob {
+	j.CommandLine = append([]string(nil), j.CommandLine...)
+	return j
+}
@@ -0,0 +1,230 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateRunner blocks every ffmpeg command until it is released or its context is done.
+type gateRunner struct {
+	started chan string // receives the output path of every started command
+	release chan struct{}
+
+	mu      sync.Mutex
+	outputs []string
+}
+
+func newGateRunner() *gateRunner {
+	return &gateRunner{started: make(chan string, 16), release: make(chan struct{}, 16)}
+}
+
+func (r *gateRunner) Run(ctx context.Context, cmdline []string, stdout, stderr io.Writer) error {
+	output := cmdline[len(cmdline)-1]
+	r.mu.Lock()
+	r.outputs = append(r.outputs, output)
+	r.mu.Unlock()
+	r.started <- output
+	select {
+	case <-r.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *gateRunner) waitStarted(t *testing.T) string {
+	t.Helper()
+	select {
+	case output := <-r.started:
+		return output
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a job to start")
+		return ""
+	}
+}
+
+func newQueueTestVideo() *EditableVideo {
+	return newTrimTestVideo().GetEditableVideo()
+}
+
+func addJob(t *testing.T, queue *TranscodeQueue, output string, priority int) string {
+	t.Helper()
+	id, err := queue.Add(newQueueTestVideo(), output, priority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func waitJob(t *testing.T, queue *TranscodeQueue, id string) TranscodeJob {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err := queue.Wait(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestTranscodeQueuePriority(t *testing.T) {
+	runner := newGateRunner()
+	defer SetRunner(SetRunner(runner))
+
+	queue, err := NewTranscodeQueue(TranscodeQueueOptions{MaxParallel: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer queue.Close()
+
+	first := addJob(t, queue, "first.mp4", 0)
+	runner.waitStarted(t)
+	low := addJob(t, queue, "low.mp4", 0)
+	high := addJob(t, queue, "high.mp4", 5)
+
+	for range []string{first, high, low} {
+		runner.release <- struct{}{}
+	}
+	for _, id := range []string{first, low, high} {
+		if job := waitJob(t, queue, id); job.Status != JobDone || job.Percent != 100 {
+			t.Errorf("job %s is %s (%v%%), want done", id, job.Status, job.Percent)
+		}
+	}
+
+	expected := []string{"first.mp4", "high.mp4", "low.mp4"}
+	for i, output := range runner.outputs {
+		if output != expected[i] {
+			t.Errorf("render %d is %s, want %s", i, output, expected[i])
+		}
+	}
+}
+
+func TestTranscodeQueueMaxParallel(t *testing.T) {
+	runner := newGateRunner()
+	defer SetRunner(SetRunner(runner))
+
+	queue, err := NewTranscodeQueue(TranscodeQueueOptions{MaxParallel: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer queue.Close()
+
+	var ids []string
+	for _, output := range []string{"1.mp4", "2.mp4", "3.mp4"} {
+		ids = append(ids, addJob(t, queue, output, 0))
+	}
+	runner.waitStarted(t)
+	runner.waitStarted(t)
+
+	job, err := queue.Status(ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobQueued {
+		t.Errorf("third job is %s while 2 jobs are running, want queued", job.Status)
+	}
+
+	runner.release <- struct{}{}
+	runner.waitStarted(t)
+	runner.release <- struct{}{}
+	runner.release <- struct{}{}
+	for _, id := range ids {
+		waitJob(t, queue, id)
+	}
+}
+
+func TestTranscodeQueueCancel(t *testing.T) {
+	runner := newGateRunner()
+	defer SetRunner(SetRunner(runner))
+
+	queue, err := NewTranscodeQueue(TranscodeQueueOptions{MaxParallel: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer queue.Close()
+
+	running := addJob(t, queue, "running.mp4", 0)
+	runner.waitStarted(t)
+	queued := addJob(t, queue, "queued.mp4", 0)
+
+	if err := queue.Cancel(queued); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Cancel(running); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{running, queued} {
+		if job := waitJob(t, queue, id); job.Status != JobCanceled {
+			t.Errorf("job %s is %s, want canceled", id, job.Status)
+		}
+	}
+	if err := queue.Cancel(running); err == nil {
+		t.Errorf("expected an error canceling a finished job")
+	}
+	if len(runner.outputs) != 1 {
+		t.Errorf("got %d renders, want 1", len(runner.outputs))
+	}
+}
+
+func TestTranscodeQueueResume(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "queue.json")
+	runner := newGateRunner()
+	defer SetRunner(SetRunner(runner))
+
+	queue, err := NewTranscodeQueue(TranscodeQueueOptions{MaxParallel: 1, StatePath: statePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interrupted := addJob(t, queue, "interrupted.mp4", 0)
+	runner.waitStarted(t)
+	pending := addJob(t, queue, "pending.mp4", 0)
+	if err := queue.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &FakeRunner{}
+	SetRunner(fake)
+	queue, err = NewTranscodeQueue(TranscodeQueueOptions{MaxParallel: 1, StatePath: statePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer queue.Close()
+
+	for _, id := range []string{interrupted, pending} {
+		if job := waitJob(t, queue, id); job.Status != JobDone {
+			t.Errorf("job %s is %s, want done", id, job.Status)
+		}
+	}
+	if commands := fake.FFmpegCommands(); len(commands) != 2 || commands[0][len(commands[0])-1] != "interrupted.mp4" {
+		t.Errorf("unexpected resumed renders %v", commands)
+	}
+	if id := addJob(t, queue, "new.mp4", 0); id == interrupted || id == pending {
+		t.Errorf("job ID %s is reused", id)
+	}
+}
+
+func TestTranscodeQueueFailure(t *testing.T) {
+	fake := &FakeRunner{Err: io.ErrUnexpectedEOF, Logs: "Conversion failed!"}
+	defer SetRunner(SetRunner(fake))
+
+	queue, err := NewTranscodeQueue(TranscodeQueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer queue.Close()
+
+	id := addJob(t, queue, "out.mp4", 0)
+	if job := waitJob(t, queue, id); job.Status != JobFailed || job.Error != "Conversion failed!" {
+		t.Errorf("got %s %q, want failed with the ffmpeg logs", job.Status, job.Error)
+	}
+	if err := queue.Remove(id); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.Jobs()) != 0 {
+		t.Errorf("the removed job is still listed")
+	}
+}
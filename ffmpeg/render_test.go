@@ -0,0 +1,144 @@
+package ffmpeg
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTrimTestVideo() *Video {
+	return &Video{
+		filepath: "in.mp4",
+		width:    1280,
+		height:   720,
+		fps:      25,
+		bitrate:  2000000,
+		end:      10 * time.Second,
+		duration: 10 * time.Second,
+		info: &MediaInfo{
+			Format:  FormatInfo{BitRate: 2000000},
+			Streams: []StreamInfo{{Type: VideoStream, Width: 1280, Height: 720, AvgFrameRate: 25}},
+		},
+	}
+}
+
+func TestCommandLineAccurateTrim(t *testing.T) {
+	video := newTrimTestVideo()
+	video.Trim(2*time.Second, 5500*time.Millisecond)
+	video.SetFPS(30)
+	video.SetBitrate(1000000)
+
+	expected := []string{
+		"ffmpeg", "-y", "-ss", "2", "-i", "in.mp4",
+		"-vcodec", "libx264",
+		"-t", "3.5", "-r", "30", "-b:v", "1000000",
+		"out.mp4",
+	}
+	if got := video.GetEditableVideo().commandLine("out.mp4"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+}
+
+func TestCommandLineNTSCFrameRate(t *testing.T) {
+	video := newTrimTestVideo()
+	video.info.Streams[0].AvgFrameRate = 30000.0 / 1001
+	video.fps = 30 // rounded by LoadVideo
+	video.SetSeekMode(KeyframeSeek)
+	if !video.GetEditableVideo().canStreamCopy() {
+		t.Errorf("the rounded source framerate prevents a stream copy")
+	}
+
+	video.SetSeekMode(AccurateSeek)
+	video.SetFPS(30)
+
+	expected := []string{
+		"ffmpeg", "-y", "-i", "in.mp4",
+		"-vcodec", "libx264",
+		"-r", "30",
+		"out.mp4",
+	}
+	if got := video.GetEditableVideo().commandLine("out.mp4"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+}
+
+func TestCommandLineKeyframeTrimStreamCopy(t *testing.T) {
+	video := newTrimTestVideo()
+	video.SetSeekMode(KeyframeSeek)
+	video.SetEnd(4 * time.Second)
+	video.SetStart(time.Second)
+
+	expected := []string{
+		"ffmpeg", "-y", "-noaccurate_seek", "-ss", "1", "-i", "in.mp4",
+		"-t", "3",
+		"-c", "copy",
+		"out.mp4",
+	}
+	if got := video.GetEditableVideo().commandLine("out.mp4"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+
+	// Any other operation requires a re-encode.
+	video.SetFPS(15)
+	if video.GetEditableVideo().canStreamCopy() {
+		t.Errorf("stream copy used with a framerate change")
+	}
+	video.SetFPS(25)
+	editable := video.GetEditableVideo()
+	editable.Rotate(90)
+	if editable.canStreamCopy() {
+		t.Errorf("stream copy used with a filter")
+	}
+	editable = video.GetEditableVideo()
+	editable.SetConstantRateFactor(28)
+	if editable.canStreamCopy() {
+		t.Errorf("stream copy used with an encoding argument")
+	}
+}
+
+func TestCommandLineWithoutTrim(t *testing.T) {
+	video := newTrimTestVideo()
+
+	expected := []string{
+		"ffmpeg", "-y", "-i", "in.mp4",
+		"-vcodec", "libx264",
+		"out.mp4",
+	}
+	if got := video.GetEditableVideo().commandLine("out.mp4"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+}
+
+func TestRenderTrim(t *testing.T) {
+	skipIfUnavailable(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.mp4")
+	err := exec.Command("ffmpeg", "-y", "-f", "lavfi", "-i", "testsrc=duration=6:size=320x240:rate=25",
+		"-c:v", "libx264", "-g", "25", input).Run()
+	if err != nil {
+		t.Fatalf("unable to generate the test video: %v", err)
+	}
+
+	for _, mode := range []SeekMode{AccurateSeek, KeyframeSeek} {
+		video, err := LoadVideo(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		video.SetSeekMode(mode)
+		video.Trim(2*time.Second, 4*time.Second)
+
+		output := filepath.Join(dir, "out.mp4")
+		if err := video.GetEditableVideo().Render(output); err != nil {
+			t.Fatal(err)
+		}
+		trimmed, err := LoadVideo(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Duration(trimmed.GetDuration() * float64(time.Second)); d < 1900*time.Millisecond || d > 2100*time.Millisecond {
+			t.Errorf("seek mode %d: got a %v video, want 2s", mode, d)
+		}
+	}
+}
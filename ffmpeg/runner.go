@@ -0,0 +1,125 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Runner executes the ffmpeg and ffprobe command lines of the package.
+// cmdline[0] is the program name. stdout and stderr may be nil.
+// The process must be killed as soon as ctx is done.
+type Runner interface {
+	Run(ctx context.Context, cmdline []string, stdout, stderr io.Writer) error
+}
+
+// ExecRunner is the default Runner, it executes the programs found in the PATH.
+type ExecRunner struct{}
+
+// Run executes cmdline and waits for it to finish.
+func (ExecRunner) Run(ctx context.Context, cmdline []string, stdout, stderr io.Writer) error {
+	if _, err := exec.LookPath(cmdline[0]); err != nil {
+		return errors.New(cmdline[0] + " was not found in your PATH " +
+			"environment variable, make sure to install ffmpeg " +
+			"(https://ffmpeg.org/) and add ffmpeg, ffplay and ffprobe to your " +
+			"PATH")
+	}
+	cmd := exec.CommandContext(ctx, cmdline[0], cmdline[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+var (
+	runnerMu sync.RWMutex
+	runner   Runner = ExecRunner{}
+)
+
+// SetRunner replaces the Runner used by the package and returns the previous one, e.g to restore it at the end of a test.
+// Functions returning an *exec.Cmd (RenderInBackground..) always execute ffmpeg directly.
+func SetRunner(r Runner) Runner {
+	runnerMu.Lock()
+	defer runnerMu.Unlock()
+	previous := runner
+	runner = r
+	return previous
+}
+
+// GetRunner returns the Runner used by the package.
+func GetRunner() Runner {
+	runnerMu.RLock()
+	defer runnerMu.RUnlock()
+	return runner
+}
+
+// FakeRunner is a Runner recording the command lines instead of executing them, it is intended for tests.
+// ffprobe commands replay the JSON registered in Probes for their input.
+type FakeRunner struct {
+	Probes map[string]string // ffprobe JSON output returned for an input path
+	Logs   string            // written to stderr by every ffmpeg command, e.g showinfo or silencedetect logs
+	Err    error             // if set, returned by every ffmpeg command
+
+	mu       sync.Mutex
+	commands [][]string
+}
+
+// Run records cmdline and replays the registered output.
+func (r *FakeRunner) Run(ctx context.Context, cmdline []string, stdout, stderr io.Writer) error {
+	r.mu.Lock()
+	r.commands = append(r.commands, append([]string(nil), cmdline...))
+	r.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if cmdline[0] == "ffprobe" {
+		path := cmdline[len(cmdline)-1]
+		probe, ok := r.Probes[path]
+		if !ok {
+			return fmt.Errorf("FakeRunner: no probe registered for %s", path)
+		}
+		if stdout != nil {
+			_, err := io.WriteString(stdout, probe)
+			return err
+		}
+		return nil
+	}
+
+	if stderr != nil && r.Logs != "" {
+		if _, err := io.WriteString(stderr, r.Logs); err != nil {
+			return err
+		}
+	}
+	// Report the end of the execution to the progress reader.
+	if stdout != nil && strings.Contains(strings.Join(cmdline, " "), "-progress pipe:1") {
+		if _, err := io.WriteString(stdout, "progress=end\n"); err != nil {
+			return err
+		}
+	}
+	return r.Err
+}
+
+// Commands returns the recorded command lines, in execution order.
+func (r *FakeRunner) Commands() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var commands = make([][]string, len(r.commands))
+	copy(commands, r.commands)
+	return commands
+}
+
+// FFmpegCommands returns the recorded ffmpeg command lines, ffprobe commands excluded.
+func (r *FakeRunner) FFmpegCommands() [][]string {
+	var commands [][]string
+	for _, cmdline := range r.Commands() {
+		if cmdline[0] != "ffprobe" {
+			commands = append(commands, cmdline)
+		}
+	}
+	return commands
+}
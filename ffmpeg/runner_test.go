@@ -0,0 +1,147 @@
+package ffmpeg
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useFakeRunner replaces the package Runner by a FakeRunner replaying probes until the end of the test.
+// It returns the fake and the paths of empty files created for every probe key.
+func useFakeRunner(t *testing.T, probes map[string]string) (*FakeRunner, map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	var fake = &FakeRunner{Probes: map[string]string{}}
+	var paths = map[string]string{}
+	for name, probe := range probes {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		fake.Probes[path] = probe
+		paths[name] = path
+	}
+	previous := SetRunner(fake)
+	t.Cleanup(func() { SetRunner(previous) })
+	return fake, paths
+}
+
+func TestLoadVideoWithFakeRunner(t *testing.T) {
+	fake, paths := useFakeRunner(t, map[string]string{"sample.mov": sampleProbeOutput})
+
+	video, err := LoadVideo(paths["sample.mov"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if video.width != 1080 || video.height != 1920 || video.fps != 30 || video.GetDuration() != 10.01 {
+		t.Errorf("unexpected video %dx%d %dfps %vs", video.width, video.height, video.fps, video.GetDuration())
+	}
+
+	expected := [][]string{{"ffprobe", "-v", "quiet", "-print_format", "json", "-show_format", "-show_streams", paths["sample.mov"]}}
+	if got := fake.Commands(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+
+	if _, err := LoadVideo(filepath.Join(t.TempDir(), "missing.mp4")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestRenderWithFakeRunner(t *testing.T) {
+	fake, paths := useFakeRunner(t, map[string]string{"sample.mov": sampleProbeOutput})
+	video, err := LoadVideo(paths["sample.mov"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	editable := video.GetEditableVideo()
+	editable.SetSize(540, 960)
+
+	var done bool
+	if err := editable.RenderContext(ctx, "out.mp4", func(p Progress) { done = p.Done }); err != nil {
+		t.Fatal(err)
+	}
+	if !done {
+		t.Errorf("the end of the execution was not reported")
+	}
+
+	expected := []string{
+		"ffmpeg", "-progress", "pipe:1", "-nostats", "-y", "-i", paths["sample.mov"],
+		"-filter_complex", "[0:v]scale=540:960[vout]", "-map", "[vout]", "-map", "0:a?",
+		"-vcodec", "libx264",
+		"out.mp4",
+	}
+	commands := fake.FFmpegCommands()
+	if len(commands) != 1 || !reflect.DeepEqual(commands[0], expected) {
+		t.Errorf("got  %v\nwant %v", commands, [][]string{expected})
+	}
+
+	fake.Err = errors.New("exit status 1")
+	fake.Logs = "Invalid argument"
+	if err := editable.Render("out.mp4"); err == nil || !strings.Contains(err.Error(), "Invalid argument") {
+		t.Errorf("expected the ffmpeg logs in the error, got %v", err)
+	}
+}
+
+func TestGetThumbnailAtSecWithFakeRunner(t *testing.T) {
+	fake, paths := useFakeRunner(t, map[string]string{"sample.mov": sampleProbeOutput})
+	video, err := LoadVideo(paths["sample.mov"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := video.GetThumbnailAtSec("thumb.jpg", 2.5); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{{
+		"ffmpeg", "-y", "-i", paths["sample.mov"],
+		"-vframes", "1", "-an", "-s", "1080x1920", "-ss", "2.5",
+		"thumb.jpg",
+	}}
+	if got := fake.FFmpegCommands(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+}
+
+func TestAddWaterMarkWithFakeRunner(t *testing.T) {
+	fake, paths := useFakeRunner(t, map[string]string{"sample.mov": sampleProbeOutput})
+	video, err := LoadVideo(paths["sample.mov"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := video.GetEditableVideo().AddWaterMark("ignored.mp4", "logo.png", "out.mp4", 100, 50); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{{
+		"ffmpeg", "-y", "-i", paths["sample.mov"], "-i", "logo.png",
+		"-filter_complex", "[1:v]scale=100:50[ov1];[0:v][ov1]overlay=10:10[vout]", "-map", "[vout]", "-map", "0:a?",
+		"-vcodec", "libx264",
+		"out.mp4",
+	}}
+	if got := fake.FFmpegCommands(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+}
+
+func TestMergeFragmentsWithFakeRunner(t *testing.T) {
+	fake, paths := useFakeRunner(t, map[string]string{"a.mov": sampleProbeOutput, "b.mov": sampleProbeOutput})
+	if err := MergeFragmentsFragments("out.mp4", false, paths["a.mov"], paths["b.mov"]); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := fake.FFmpegCommands()
+	if len(commands) != 1 {
+		t.Fatalf("got %d ffmpeg commands, want 1", len(commands))
+	}
+	// Identical fragments are stream copied through a temporary concat list.
+	line := strings.Join(commands[0], " ")
+	if !strings.HasPrefix(line, "ffmpeg -y -f concat -safe 0 -i ") || !strings.HasSuffix(line, " -map 0:v:0 -map 0:a:0? -c copy out.mp4") {
+		t.Errorf("unexpected command line %s", line)
+	}
+	if probes := len(fake.Commands()) - len(commands); probes != 2 {
+		t.Errorf("got %d probes, want 2", probes)
+	}
+}
@@ -0,0 +1,258 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// showInfoTimeRegex matches the timestamps printed by the showinfo filter.
+var showInfoTimeRegex = regexp.MustCompile(`Parsed_showinfo.*\bpts_time:\s*(-?[0-9.]+(?:[eE][-+]?[0-9]+)?)`)
+
+// samplingCommandLine returns the command line extracting the sampled frames of the video to dir.
+func (v *Video) samplingCommandLine(dir string, options SamplingOptions) []string {
+	cmdline := []string{"ffmpeg", "-y"}
+
+	var filters []string
+	switch options.Mode {
+	case SampleKeyframes:
+		// Only decode keyframes.
+		cmdline = append(cmdline, "-skip_frame", "nokey")
+	case SampleSceneChange:
+		filters = append(filters, escapeFilterGraph(fmt.Sprintf("select=eq(n,0)+gt(scene,%s)", strconv.FormatFloat(options.SceneThreshold, 'f', -1, 64))))
+	default:
+		filters = append(filters, escapeFilterGraph(fmt.Sprintf("select=isnan(prev_selected_t)+gte(t-prev_selected_t,%s)", formatSeconds(options.Interval))))
+	}
+	// showinfo logs the timestamp of every frame that reached the output.
+	filters = append(filters, "showinfo")
+	if options.Width > 0 {
+		filters = append(filters, fmt.Sprintf("scale=%d:-2", options.Width))
+	}
+
+	cmdline = append(cmdline,
+		"-i", v.filepath,
+		"-an",
+		"-vf", strings.Join(filters, ","),
+		"-vsync", "vfr",
+	)
+	if options.MaxFrames > 0 {
+		cmdline = append(cmdline, "-frames:v", strconv.Itoa(options.MaxFrames))
+	}
+	return append(cmdline, filepath.Join(dir, "frame_%05d.png"))
+}
+
+// ExtractFrames extracts the sampled frames of the video in a single ffmpeg pass.
+// Frames are written to a new temporary directory which must be removed by the caller using os.RemoveAll.
+func (v *Video) ExtractFrames(ctx context.Context, options SamplingOptions) (string, []Frame, error) {
+	if options.Interval <= 0 {
+		options.Interval = time.Second
+	}
+	if options.SceneThreshold <= 0 {
+		options.SceneThreshold = 0.4
+	}
+
+	dir, err := ioutil.TempDir("", "frames")
+	if err != nil {
+		return "", nil, fmt.Errorf("err Creating TempDir %v", err)
+	}
+
+	var logs bytes.Buffer
+	if err := runCommandWithLogs(ctx, v.samplingCommandLine(dir, options), v.duration, nil, &logs); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("Video.ExtractFrames: ffmpeg failed: %w", err)
+	}
+
+	timestamps := parseShowInfoTimestamps(logs.Bytes())
+	var frames []Frame
+	for i := 0; ; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("frame_%05d.png", i+1))
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+		var frame = Frame{Path: path}
+		if i < len(timestamps) {
+			frame.Timestamp = timestamps[i]
+		}
+		frames = append(frames, frame)
+	}
+	return dir, frames, nil
+}
+
+// parseShowInfoTimestamps returns the frames timestamps logged by the showinfo filter, in output order.
+func parseShowInfoTimestamps(logs []byte) []time.Duration {
+	var timestamps []time.Duration
+	for _, match := range showInfoTimeRegex.FindAllSubmatch(logs, -1) {
+		secs, err := strconv.ParseFloat(string(match[1]), 64)
+		if err != nil {
+			continue
+		}
+		timestamps = append(timestamps, time.Duration(secs*float64(time.Second)+0.5))
+	}
+	return timestamps
+}
+
+// processFrames calls process for every frame using at most workers goroutines (defaults to the CPU count).
+func processFrames(frames []Frame, workers int, process func(index int, frame Frame)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers && w < len(frames); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				process(i, frames[i])
+			}
+		}()
+	}
+	for i := range frames {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// GetBestThumbnail creates a Thumbnail at path using the least blurry, non-black frame among the scenes of the video.
+func (v *Video) GetBestThumbnail(ctx context.Context, outputPath string) error {
+	var sampling = SamplingOptions{Mode: SampleSceneChange, MaxFrames: 30, Width: 320}
+	dir, frames, err := v.ExtractFrames(ctx, sampling)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// Static videos have few scene changes, sample them evenly instead.
+	if len(frames) < 3 {
+		sampling.Mode = SampleInterval
+		sampling.Interval = v.duration / 10
+		if sampling.Interval < time.Second {
+			sampling.Interval = time.Second
+		}
+		if dir, frames, err = v.ExtractFrames(ctx, sampling); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+	if len(frames) == 0 {
+		return fmt.Errorf("Video.GetBestThumbnail: no frame extracted")
+	}
+
+	best, err := pickBestFrame(frames, sampling.Workers)
+	if err != nil {
+		return err
+	}
+	return v.GetThumbnailAtSecContext(ctx, outputPath, best.Timestamp.Seconds())
+}
+
+// frameQuality describes how suitable a frame is as a thumbnail.
+type frameQuality struct {
+	sharpness float64 // variance of the Laplacian, higher is sharper
+	usable    bool    // false for black or blank frames
+}
+
+// pickBestFrame returns the sharpest usable frame, or the sharpest frame if none is usable.
+func pickBestFrame(frames []Frame, workers int) (Frame, error) {
+	var qualities = make([]frameQuality, len(frames))
+	var errs = make([]error, len(frames))
+	processFrames(frames, workers, func(i int, frame Frame) {
+		qualities[i], errs[i] = measureFrameQuality(frame.Path)
+	})
+
+	var best = -1
+	for i, q := range qualities {
+		if errs[i] != nil {
+			return Frame{}, errs[i]
+		}
+		switch {
+		case best < 0:
+			best = i
+		case q.usable && !qualities[best].usable:
+			best = i
+		case q.usable == qualities[best].usable && q.sharpness > qualities[best].sharpness:
+			best = i
+		}
+	}
+	return frames[best], nil
+}
+
+// measureFrameQuality computes the brightness, contrast and sharpness of an image.
+func measureFrameQuality(path string) (frameQuality, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return frameQuality{}, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return frameQuality{}, fmt.Errorf("image.Decode %s, Error: %v", path, err)
+	}
+
+	luma := toLuma(img)
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width < 3 || height < 3 {
+		return frameQuality{}, nil
+	}
+
+	mean, stdDev := meanStdDev(luma)
+
+	// Laplacian variance
+	var laplacian = make([]float64, 0, (width-2)*(height-2))
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			i := y*width + x
+			laplacian = append(laplacian, 4*luma[i]-luma[i-1]-luma[i+1]-luma[i-width]-luma[i+width])
+		}
+	}
+	_, lapStdDev := meanStdDev(laplacian)
+
+	return frameQuality{
+		sharpness: lapStdDev * lapStdDev,
+		usable:    mean > 24 && mean < 235 && stdDev > 8,
+	}, nil
+}
+
+// toLuma returns the luma [0-255] of every pixel of img, row by row.
+func toLuma(img image.Image) []float64 {
+	bounds := img.Bounds()
+	var luma = make([]float64, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			luma = append(luma, (0.299*float64(r)+0.587*float64(g)+0.114*float64(b))/257)
+		}
+	}
+	return luma
+}
+
+// meanStdDev returns the mean and the standard deviation of values.
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum, sumSquares float64
+	for _, v := range values {
+		sum += v
+		sumSquares += v * v
+	}
+	mean := sum / float64(len(values))
+	variance := sumSquares/float64(len(values)) - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+	return mean, math.Sqrt(variance)
+}
@@ -0,0 +1,115 @@
+package ffmpeg
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseShowInfoTimestamps(t *testing.T) {
+	logs := `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':
+[Parsed_showinfo_1 @ 0x55d0c] n:   0 pts:      0 pts_time:0       duration:   512 fmt:yuv420p
+[Parsed_showinfo_1 @ 0x55d0c] color_range:tv color_space:bt709
+[Parsed_showinfo_1 @ 0x55d0c] n:   1 pts:  63488 pts_time:4.13333 duration:   512 fmt:yuv420p
+[Parsed_showinfo_1 @ 0x55d0c] n:   2 pts: 153600 pts_time:10      duration:   512 fmt:yuv420p
+frame=    3 fps=0.0 q=-0.0 Lsize=N/A time=00:00:10.03`
+
+	expected := []time.Duration{0, 4133330 * time.Microsecond, 10 * time.Second}
+	if got := parseShowInfoTimestamps([]byte(logs)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
+
+func TestSamplingCommandLine(t *testing.T) {
+	video := &Video{filepath: "in.mp4"}
+
+	scene := strings.Join(video.samplingCommandLine("dir", SamplingOptions{Mode: SampleSceneChange, SceneThreshold: 0.3, MaxFrames: 5, Width: 320}), " ")
+	if !strings.Contains(scene, `-vf select=eq(n\,0)+gt(scene\,0.3),showinfo,scale=320:-2 -vsync vfr -frames:v 5`) {
+		t.Errorf("unexpected scene sampling command line: %s", scene)
+	}
+
+	keyframes := strings.Join(video.samplingCommandLine("dir", SamplingOptions{Mode: SampleKeyframes}), " ")
+	if !strings.HasPrefix(keyframes, "ffmpeg -y -skip_frame nokey -i in.mp4") {
+		t.Errorf("unexpected keyframes sampling command line: %s", keyframes)
+	}
+}
+
+func TestProcessFramesIsBounded(t *testing.T) {
+	frames := make([]Frame, 20)
+	var mu sync.Mutex
+	var running, maxRunning = 0, 0
+	var processed = make([]bool, len(frames))
+	processFrames(frames, 3, func(i int, frame Frame) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+		processed[i] = true
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	if maxRunning > 3 {
+		t.Errorf("got %d concurrent workers, want at most 3", maxRunning)
+	}
+	for i, ok := range processed {
+		if !ok {
+			t.Errorf("frame %d was not processed", i)
+		}
+	}
+}
+
+func TestPickBestFrame(t *testing.T) {
+	dir := t.TempDir()
+	writeFrame := func(name string, pixel func(x, y int) uint8) Frame {
+		img := image.NewGray(image.Rect(0, 0, 32, 32))
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				img.SetGray(x, y, color.Gray{Y: pixel(x, y)})
+			}
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		return Frame{Path: path}
+	}
+
+	black := writeFrame("black.png", func(x, y int) uint8 { return 2 })
+	blurry := writeFrame("blurry.png", func(x, y int) uint8 { return uint8(60 + 4*x) })
+	sharp := writeFrame("sharp.png", func(x, y int) uint8 {
+		if (x/2+y/2)%2 == 0 {
+			return 40
+		}
+		return 200
+	})
+
+	best, err := pickBestFrame([]Frame{black, blurry, sharp}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if best.Path != sharp.Path {
+		t.Errorf("got %s, want %s", best.Path, sharp.Path)
+	}
+
+	if best, _ := pickBestFrame([]Frame{black, blurry}, 1); best.Path != blurry.Path {
+		t.Errorf("got %s, want %s", best.Path, blurry.Path)
+	}
+}
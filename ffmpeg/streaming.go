@@ -0,0 +1,273 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ladderRenditions returns the renditions of the ladder that do not exceed the source resolution, sorted from lowest to highest.
+// If the source is smaller than every rendition, the lowest rendition is returned with the source resolution.
+func (v *EditableVideo) ladderRenditions(ladder []Rendition) []Rendition {
+	var sorted = make([]Rendition, len(ladder))
+	copy(sorted, ladder)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Resolution < sorted[j].Resolution })
+
+	sourceRes := v.GetEditableVideoResolution()
+	var renditions []Rendition
+	for _, r := range sorted {
+		if r.Resolution <= sourceRes {
+			renditions = append(renditions, r)
+		}
+	}
+	if len(renditions) == 0 && len(sorted) > 0 {
+		renditions = append(renditions, Rendition{Resolution: VideoResolution(toEvenNumber(int(sourceRes))), VideoBitrate: sorted[0].VideoBitrate})
+	}
+	return renditions
+}
+
+// hasAudioOutput returns true if the rendered video will contain an audio stream.
+func (v *EditableVideo) hasAudioOutput() bool {
+	for _, arg := range v.additionalArgs {
+		if arg == "-an" {
+			return false
+		}
+	}
+	return v.info == nil || v.info.HasAudio()
+}
+
+// streamingCommandLine returns the command line rendering all renditions to outputDir in a single ffmpeg pass.
+func (v *EditableVideo) streamingCommandLine(outputDir string, renditions []Rendition, options StreamingOptions, withAudio bool) []string {
+	cmdline := []string{"ffmpeg", "-y"}
+	cmdline = append(cmdline, v.inputSeekArgs()...)
+	cmdline = append(cmdline, "-i", v.filepath)
+
+	// Apply the video operations once, then split the result for each rendition.
+	// The renditions are scaled to the displayed size, non square pixels are converted first.
+	var filterComplex, videoPad = "", "0:v"
+	if !v.filters.IsEmpty() || !v.geometry.HasSquarePixels() {
+		var inputArgs []string
+		filters := v.displayFilters()
+		inputArgs, filterComplex, videoPad = filters.Build("0:v", 1)
+		cmdline = append(cmdline, inputArgs...)
+		filterComplex += ";"
+	}
+	filterComplex += fmt.Sprintf("[%s]split=%d", videoPad, len(renditions))
+	for i := range renditions {
+		filterComplex += fmt.Sprintf("[s%d]", i)
+	}
+	for i, r := range renditions {
+		width, height := v.GetResolutions(r.Resolution)
+		filterComplex += fmt.Sprintf(";[s%d]scale=%d:%d[r%d]", i, width, height, i)
+	}
+
+	var audioMap = "0:a:0"
+	if withAudio && !v.audio.IsEmpty() {
+		inputArgs, graph, outputPad := v.audio.Build(v.sourceAudioPad(), countInputs(cmdline), v.outputDuration())
+		if graph != "" {
+			cmdline = append(cmdline, inputArgs...)
+			filterComplex += ";" + graph
+			audioMap = "[" + outputPad + "]"
+		}
+	}
+	cmdline = append(cmdline, "-filter_complex", filterComplex)
+
+	for i := range renditions {
+		cmdline = append(cmdline, "-map", fmt.Sprintf("[r%d]", i))
+	}
+	if withAudio {
+		cmdline = append(cmdline, "-map", audioMap)
+	}
+	cmdline = append(cmdline, v.durationArgs()...)
+
+	// Segments must start on a keyframe, force a fixed GOP matching the segment duration.
+	fps := v.fps
+	if fps <= 0 {
+		fps = 30
+	}
+	gop := strconv.Itoa(int(float64(fps) * options.SegmentDuration.Seconds()))
+	cmdline = append(cmdline,
+		"-c:v", "libx264",
+		"-preset", string(options.Preset),
+		"-pix_fmt", "yuv420p",
+		"-g", gop,
+		"-keyint_min", gop,
+		"-sc_threshold", "0",
+	)
+	for i, r := range renditions {
+		cmdline = append(cmdline,
+			fmt.Sprintf("-b:v:%d", i), fmt.Sprintf("%dk", r.VideoBitrate),
+			fmt.Sprintf("-maxrate:v:%d", i), fmt.Sprintf("%dk", r.VideoBitrate*107/100),
+			fmt.Sprintf("-bufsize:v:%d", i), fmt.Sprintf("%dk", r.VideoBitrate*3/2),
+		)
+	}
+	if withAudio {
+		cmdline = append(cmdline, "-c:a", "aac", "-b:a", fmt.Sprintf("%dk", options.AudioBitrate), "-ac", "2")
+	}
+
+	segmentSeconds := formatSeconds(options.SegmentDuration)
+	if options.Format == DASH {
+		adaptationSets := "id=0,streams=v"
+		if withAudio {
+			adaptationSets += " id=1,streams=a"
+		}
+		return append(cmdline,
+			"-f", "dash",
+			"-seg_duration", segmentSeconds,
+			"-use_template", "1",
+			"-use_timeline", "1",
+			"-hls_playlist", "1",
+			"-adaptation_sets", adaptationSets,
+			"-init_seg_name", "init-$RepresentationID$.m4s",
+			"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+			filepath.Join(outputDir, dashManifestName),
+		)
+	}
+
+	var streamMap []string
+	for i, r := range renditions {
+		stream := fmt.Sprintf("v:%d,name:%s", i, renditionName(r))
+		if withAudio {
+			stream += ",agroup:audio"
+		}
+		streamMap = append(streamMap, stream)
+	}
+	if withAudio {
+		streamMap = append(streamMap, "a:0,agroup:audio,name:audio")
+	}
+	return append(cmdline,
+		"-f", "hls",
+		"-hls_time", segmentSeconds,
+		"-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+		"-hls_segment_filename", filepath.Join(outputDir, "%v", "segment_%05d.ts"),
+		"-master_pl_name", hlsMasterPlaylistName,
+		"-var_stream_map", strings.Join(streamMap, " "),
+		filepath.Join(outputDir, "%v", "index.m3u8"),
+	)
+}
+
+// RenderStreaming renders an adaptive bitrate ladder (HLS, and optionally DASH) of the video to outputDir
+// in a single ffmpeg pass. Renditions above the source resolution are skipped.
+//
+// progressFn: is optional, if set it will be called every time ffmpeg reports progress.
+func (v *EditableVideo) RenderStreaming(ctx context.Context, outputDir string, options StreamingOptions, progressFn ProgressFunc) (*StreamingManifest, error) {
+	if len(options.Renditions) == 0 {
+		options.Renditions = DefaultLadder
+	}
+	if options.SegmentDuration <= 0 {
+		options.SegmentDuration = 6 * time.Second
+	}
+	if options.AudioBitrate <= 0 {
+		options.AudioBitrate = 128
+	}
+	if options.Preset == "" {
+		options.Preset = Veryfast
+	}
+
+	renditions := v.ladderRenditions(options.Renditions)
+	withAudio := v.hasAudioOutput()
+
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if options.Format == HLS {
+		for _, r := range renditions {
+			if err := os.MkdirAll(filepath.Join(outputDir, renditionName(r)), os.ModePerm); err != nil {
+				return nil, err
+			}
+		}
+		if withAudio {
+			if err := os.MkdirAll(filepath.Join(outputDir, "audio"), os.ModePerm); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cmdline := v.streamingCommandLine(outputDir, renditions, options, withAudio)
+	if err := runCommand(ctx, cmdline, v.outputDuration(), progressFn); err != nil {
+		return nil, fmt.Errorf("Video.RenderStreaming: ffmpeg failed: %w", err)
+	}
+
+	return v.streamingManifest(outputDir, renditions, options, withAudio)
+}
+
+// streamingManifest lists the files generated by RenderStreaming.
+func (v *EditableVideo) streamingManifest(outputDir string, renditions []Rendition, options StreamingOptions, withAudio bool) (*StreamingManifest, error) {
+	var manifest = StreamingManifest{
+		MasterPlaylist: filepath.Join(outputDir, hlsMasterPlaylistName),
+	}
+	if options.Format == DASH {
+		manifest.DASHManifest = filepath.Join(outputDir, dashManifestName)
+	}
+
+	var audioBandwidth = 0
+	if withAudio {
+		audioBandwidth = options.AudioBitrate * 1000
+	}
+
+	for i, r := range renditions {
+		width, height := v.GetResolutions(r.Resolution)
+		output, err := renditionOutput(outputDir, options.Format, renditionName(r), i)
+		if err != nil {
+			return nil, err
+		}
+		output.Width = width
+		output.Height = height
+		output.Bandwidth = r.VideoBitrate*1000 + audioBandwidth
+		manifest.Renditions = append(manifest.Renditions, *output)
+	}
+	if withAudio {
+		output, err := renditionOutput(outputDir, options.Format, "audio", len(renditions))
+		if err != nil {
+			return nil, err
+		}
+		output.Bandwidth = audioBandwidth
+		manifest.Audio = output
+	}
+
+	err := filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			manifest.Files = append(manifest.Files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// renditionOutput returns the playlist and segments of a rendition.
+//
+// index: is the DASH representation id of the rendition.
+func renditionOutput(outputDir string, format StreamingFormat, name string, index int) (*RenditionOutput, error) {
+	var output = RenditionOutput{Name: name}
+	var err error
+	if format == DASH {
+		output.Playlist = filepath.Join(outputDir, fmt.Sprintf("media_%d.m3u8", index))
+		output.InitSegment = filepath.Join(outputDir, fmt.Sprintf("init-%d.m4s", index))
+		output.Segments, err = filepath.Glob(filepath.Join(outputDir, fmt.Sprintf("chunk-%d-*.m4s", index)))
+	} else {
+		output.Playlist = filepath.Join(outputDir, name, "index.m3u8")
+		output.Segments, err = filepath.Glob(filepath.Join(outputDir, name, "segment_*.ts"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(output.Segments)
+	return &output, nil
+}
+
+// renditionName returns the name of a rendition, e.g "720p".
+func renditionName(r Rendition) string {
+	return fmt.Sprintf("%dp", r.Resolution)
+}
@@ -0,0 +1,63 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLadderRenditionsCappedAtSource(t *testing.T) {
+	video := (&Video{filepath: "in.mp4", width: 720, height: 1280, fps: 30}).GetEditableVideo()
+
+	renditions := video.ladderRenditions(DefaultLadder)
+	var resolutions []VideoResolution
+	for _, r := range renditions {
+		resolutions = append(resolutions, r.Resolution)
+	}
+	if !reflect.DeepEqual(resolutions, []VideoResolution{240, 480, 720}) {
+		t.Errorf("unexpected renditions: %v", resolutions)
+	}
+
+	tiny := (&Video{filepath: "in.mp4", width: 200, height: 120, fps: 30}).GetEditableVideo()
+	if renditions := tiny.ladderRenditions(DefaultLadder); len(renditions) != 1 || renditions[0].Resolution != 120 {
+		t.Errorf("unexpected renditions for a tiny source: %v", renditions)
+	}
+}
+
+func TestStreamingCommandLine(t *testing.T) {
+	video := (&Video{filepath: "in.mp4", width: 1280, height: 720, fps: 25}).GetEditableVideo()
+	options := StreamingOptions{SegmentDuration: 4 * time.Second, AudioBitrate: 96, Preset: Veryfast}
+	renditions := video.ladderRenditions(DefaultLadder)
+
+	line := strings.Join(video.streamingCommandLine("out", renditions, options, true), " ")
+	for _, expected := range []string{
+		"-filter_complex [0:v]split=3[s0][s1][s2];[s0]scale=426:240[r0];[s1]scale=854:480[r1];[s2]scale=1280:720[r2]",
+		"-map [r0] -map [r1] -map [r2] -map 0:a:0",
+		"-g 100 -keyint_min 100",
+		"-b:v:2 2800k",
+		"-var_stream_map v:0,name:240p,agroup:audio v:1,name:480p,agroup:audio v:2,name:720p,agroup:audio a:0,agroup:audio,name:audio",
+		"-master_pl_name master.m3u8",
+	} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("command line does not contain %q:\n%s", expected, line)
+		}
+	}
+
+	options.Format = DASH
+	line = strings.Join(video.streamingCommandLine("out", renditions, options, false), " ")
+	if !strings.Contains(line, "-f dash") || !strings.Contains(line, "-adaptation_sets id=0,streams=v ") || strings.Contains(line, "0:a:0") {
+		t.Errorf("unexpected DASH command line:\n%s", line)
+	}
+}
+
+func TestStreamingCommandLineNonSquarePixels(t *testing.T) {
+	geometry := DisplayGeometry{CodedWidth: 720, CodedHeight: 576, SAR: 16.0 / 15, Width: 768, Height: 576}
+	video := (&Video{filepath: "in.mp4", width: 768, height: 576, fps: 25, geometry: geometry}).GetEditableVideo()
+	options := StreamingOptions{SegmentDuration: 4 * time.Second, AudioBitrate: 96, Preset: Veryfast}
+
+	line := strings.Join(video.streamingCommandLine("out", video.ladderRenditions(DefaultLadder), options, false), " ")
+	if !strings.Contains(line, "-filter_complex [0:v]scale=768:576,setsar=1[") || !strings.Contains(line, "]split=2[s0][s1];") {
+		t.Errorf("expected the pixels to be squared before the split:\n%s", line)
+	}
+}
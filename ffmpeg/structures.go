@@ -19,11 +19,432 @@ type Video struct {
 	width          int
 	height         int
 	fps            int
+	fpsSet         bool // fps was set by SetFPS, and not rounded from the source framerate
 	bitrate        int
 	rotate         *int
+	geometry       DisplayGeometry
+	seekMode       SeekMode
 	start          time.Duration
 	end            time.Duration
 	duration       time.Duration
-	filters        []string
+	filters        FilterGraph
+	audio          AudioGraph
 	additionalArgs []string
+	profile        *EncodingProfile
+	subtitles      []SubtitleTrack
+	info           *MediaInfo
+}
+
+// EncodingProfile defines how the output video is encoded.
+type EncodingProfile struct {
+	Name         string
+	VideoCodec   VideoCodec
+	Format       string           // ffmpeg muxer, e.g "mp4", "webm" or "matroska". If empty it is guessed from the output extension.
+	PixelFormat  string           // e.g "yuv420p", if empty the encoder picks one matching the input
+	Preset       ConversionPreset // encoding speed, mapped to the equivalent option of the codec
+	CRF          int              // constant quality, used if VideoBitrate is 0. 0 uses the encoder default.
+	VideoBitrate int              // average bitrate in kbit/s, 0 uses the constant quality mode
+	GOPSize      int              // maximum number of frames between keyframes, 0 uses the encoder default
+	AudioCodec   AudioCodec       // if empty the default encoder of the format is used
+	AudioBitrate int              // in kbit/s, 0 uses the encoder default
+	Streamable   bool             // moves the MP4 index to the beginning of the file
+}
+
+// MediaInfo is the typed description of a media file returned by ffprobe.
+type MediaInfo struct {
+	Format  FormatInfo
+	Streams []StreamInfo
+}
+
+// FormatInfo describes the container of a media file.
+type FormatInfo struct {
+	Filename       string
+	FormatName     string // comma separated list of short names, e.g "mov,mp4,m4a,3gp,3g2,mj2"
+	FormatLongName string
+	StreamsCount   int
+	StartTime      time.Duration
+	Duration       time.Duration
+	Size           int64 // in bytes
+	BitRate        int64 // in bits/s
+	ProbeScore     int
+	Tags           map[string]string
+}
+
+// StreamInfo describes a single video, audio, subtitle or data stream.
+// Fields not relevant to the stream Type are left to their zero value.
+type StreamInfo struct {
+	Index         int
+	Type          StreamType
+	CodecName     string
+	CodecLongName string
+	CodecTag      string
+	Profile       string
+	Level         int
+	BitRate       int64 // in bits/s
+	Duration      time.Duration
+	FramesCount   int64
+	TimeBase      string
+	Disposition   map[string]int
+	Tags          map[string]string
+
+	// Video
+	Width              int
+	Height             int
+	CodedWidth         int
+	CodedHeight        int
+	SampleAspectRatio  string
+	DisplayAspectRatio string
+	PixelFormat        string
+	FieldOrder         string
+	FrameRate          float64 // real base frame rate
+	AvgFrameRate       float64
+	Color              ColorInfo
+	DisplayMatrix      *DisplayMatrix
+	MasteringDisplay   *MasteringDisplayMetadata
+	ContentLightLevel  *ContentLightLevel
+
+	// Audio
+	SampleFormat  string
+	SampleRate    int // in Hz
+	Channels      int
+	ChannelLayout string
+	BitsPerSample int
+}
+
+// ColorInfo contains the color properties of a video stream.
+type ColorInfo struct {
+	Range     string // tv, pc
+	Space     string // bt709, bt2020nc..
+	Transfer  string // bt709, smpte2084 (PQ), arib-std-b67 (HLG)..
+	Primaries string // bt709, bt2020..
+}
+
+// DisplayMatrix is the Display Matrix side data of a video stream.
+type DisplayMatrix struct {
+	Matrix   string
+	Rotation float64 // in degrees, counter clockwise
+}
+
+// MasteringDisplayMetadata is the HDR mastering display side data (SMPTE ST 2086) of a video stream.
+type MasteringDisplayMetadata struct {
+	RedX, RedY               float64
+	GreenX, GreenY           float64
+	BlueX, BlueY             float64
+	WhitePointX, WhitePointY float64
+	MinLuminance             float64 // in cd/m2
+	MaxLuminance             float64 // in cd/m2
+}
+
+// ContentLightLevel is the HDR content light level side data (MaxCLL/MaxFALL) of a video stream.
+type ContentLightLevel struct {
+	MaxContent int // MaxCLL in cd/m2
+	MaxAverage int // MaxFALL in cd/m2
+}
+
+// Progress is a snapshot of an ffmpeg execution parsed from its -progress output.
+type Progress struct {
+	Frame      int64
+	FPS        float64
+	Bitrate    string // e.g "1234.5kbits/s", "N/A" when unknown
+	TotalSize  int64  // in bytes
+	OutTime    time.Duration
+	DupFrames  int64
+	DropFrames int64
+	Speed      float64 // processing speed relative to realtime, 0 when unknown
+	Percent    float64 // OutTime relative to the expected output duration [0-100], 0 when unknown
+	Done       bool    // true for the last report of the execution
+}
+
+// ProgressFunc is called every time ffmpeg reports progress.
+type ProgressFunc func(progress Progress)
+
+// DrawTextOptions defines a text drawn over a video.
+type DrawTextOptions struct {
+	Text      string
+	FontFile  string // path of a .ttf/.otf font, uses fontconfig default font if empty
+	FontSize  int    // defaults to 24
+	FontColor string // color name or 0xRRGGBB[AA], defaults to white
+	X         string // ffmpeg expression, e.g "(w-text_w)/2", defaults to 10
+	Y         string // ffmpeg expression, e.g "h-text_h-10", defaults to 10
+
+	Box            bool   // draws a box behind the text
+	BoxColor       string // defaults to black@0.5
+	BoxBorderWidth int    // space between the text and the box edges
+
+	BorderWidth int    // outline of the text, 0 disables it
+	BorderColor string // defaults to black
+
+	Start time.Duration // the text is shown from Start, relative to the output video
+	End   time.Duration // the text is hidden after End, 0 shows it until the end
+}
+
+// SubtitleOptions configures subtitles burnt into the picture by BurnSubtitles.
+type SubtitleOptions struct {
+	Charset    string // character encoding of the file, e.g "cp1252", defaults to UTF-8
+	ForceStyle string // ASS style overriding the SRT default style, e.g "FontName=Arial,FontSize=24,PrimaryColour=&H00FFFFFF"
+	FontsDir   string // directory of the fonts used by the subtitles
+}
+
+// SubtitleTrack is a soft subtitle track muxed in the output container.
+type SubtitleTrack struct {
+	Path     string // SRT, WebVTT or ASS file
+	Language string // ISO 639-2 code, e.g "eng"
+	Title    string // e.g "English (CC)"
+	Default  bool   // selected by players without user choice
+}
+
+// Rendition is a single quality of an adaptive bitrate ladder.
+type Rendition struct {
+	Resolution   VideoResolution // lowest value between width and height, see GetResolutions
+	VideoBitrate int             // in kbit/s
+}
+
+// StreamingOptions configures the generation of an adaptive bitrate ladder.
+type StreamingOptions struct {
+	Format          StreamingFormat
+	Renditions      []Rendition      // defaults to DefaultLadder
+	SegmentDuration time.Duration    // defaults to 6 seconds
+	AudioBitrate    int              // in kbit/s, defaults to 128
+	Preset          ConversionPreset // defaults to Veryfast
+}
+
+// StreamingManifest lists every file generated for an adaptive bitrate ladder.
+type StreamingManifest struct {
+	MasterPlaylist string // HLS master playlist
+	DASHManifest   string // DASH manifest, empty unless the DASH format was requested
+	Renditions     []RenditionOutput
+	Audio          *RenditionOutput // nil if the output has no audio
+	Files          []string         // every generated file, playlists and segments included
+}
+
+// RenditionOutput describes the files of a single rendition.
+type RenditionOutput struct {
+	Name        string // e.g "720p" or "audio"
+	Width       int
+	Height      int
+	Bandwidth   int // in bit/s
+	Playlist    string
+	InitSegment string // fMP4 initialization segment, empty for MPEG-TS segments
+	Segments    []string
+}
+
+// Frame is a still image extracted from a video.
+type Frame struct {
+	Path      string
+	Timestamp time.Duration
+}
+
+// FrameScores contains the Likelihood of each ModerationCategory detected in a frame.
+type FrameScores map[ModerationCategory]Likelihood
+
+// ModerationThresholds defines the highest Likelihood tolerated for each ModerationCategory.
+// A frame is rejected when a category scores above its threshold, categories absent from the map are not checked.
+type ModerationThresholds map[ModerationCategory]Likelihood
+
+// ModerationReport is the result of a video moderation.
+type ModerationReport struct {
+	Frames   []FrameModeration // sorted by Timestamp
+	Rejected bool              // true if at least one frame was rejected
+}
+
+// FrameModeration is the moderation result of a single frame.
+type FrameModeration struct {
+	Timestamp  time.Duration
+	Scores     FrameScores
+	Violations []ModerationCategory // categories above their threshold
+	Err        error                // set if the frame could not be moderated
+}
+
+// SamplingOptions defines how frames are sampled from a video.
+type SamplingOptions struct {
+	Mode           SamplingMode
+	Interval       time.Duration // used by SampleInterval, defaults to 1 second
+	SceneThreshold float64       // used by SampleSceneChange [0-1], defaults to 0.4
+	MaxFrames      int           // maximum number of frames to extract, 0 for no limit
+	Width          int           // width of the extracted frames keeping the aspect ratio, 0 keeps the video size
+	Workers        int           // maximum number of frames processed concurrently, defaults to the CPU count
+}
+
+// LoudnessTarget defines the EBU R128 loudness normalization targets.
+type LoudnessTarget struct {
+	IntegratedLoudness float64 // in LUFS, defaults to -16
+	TruePeak           float64 // in dBTP, defaults to -1.5
+	LoudnessRange      float64 // in LU, defaults to 11
+}
+
+// LoudnessMeasurement is the result of the loudnorm analysis pass.
+type LoudnessMeasurement struct {
+	IntegratedLoudness float64 // in LUFS
+	TruePeak           float64 // in dBTP
+	LoudnessRange      float64 // in LU
+	Threshold          float64 // in LUFS
+	TargetOffset       float64 // in LU
+}
+
+// BackgroundMusicOptions defines how a music track is added to a video.
+type BackgroundMusicOptions struct {
+	Volume  float64 // volume multiplier of the music, defaults to 1
+	Replace bool    // replaces the original audio instead of mixing the music in
+	Duck    bool    // lowers the music while the original audio is loud (ignored when Replace is set)
+	Loop    bool    // loops the music until the end of the video
+}
+
+// SilenceOptions defines what is considered silence.
+type SilenceOptions struct {
+	NoiseLevel  float64       // in dB, defaults to -50
+	MinDuration time.Duration // defaults to 500ms
+}
+
+// SilenceInterval is a silent section of a media.
+type SilenceInterval struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// PreviewOptions configures the animated preview generated by GetAnimatedPreview.
+type PreviewOptions struct {
+	Format   PreviewFormat
+	Start    time.Duration // relative to the start of the output video
+	Duration time.Duration // defaults to 3 seconds
+	FPS      int           // defaults to 10
+	Width    int           // defaults to 320, the height keeps the aspect ratio
+}
+
+// SpriteOptions configures the sprite sheets generated by GetSpriteSheet.
+type SpriteOptions struct {
+	Interval  time.Duration // time between two tiles, defaults to 10 seconds
+	Width     int           // width of a tile, defaults to 160. The height keeps the aspect ratio.
+	Columns   int           // defaults to 10
+	Rows      int           // maximum rows of a sheet, 0 puts every tile in a single sheet
+	URLPrefix string        // prepended to the sheets file names in the WebVTT file, e.g "https://cdn.example.com/video/"
+}
+
+// SpriteSheet lists the files generated by GetSpriteSheet.
+type SpriteSheet struct {
+	Images []string // JPEG sheets, in order
+	WebVTT string   // path of the WebVTT thumbnails track
+	Tiles  []SpriteTile
+}
+
+// SpriteTile locates the thumbnail of a time range in a sheet.
+type SpriteTile struct {
+	Start  time.Duration
+	End    time.Duration
+	Image  string // file name of the sheet
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// WatermarkOptions defines an image or text watermark, see AddWatermark.
+// Sizes are relative to the output video width so the watermark looks the same on portrait and landscape videos.
+type WatermarkOptions struct {
+	Image    string // path of a PNG, GIF, APNG or WebP image, exclusive with Text
+	Animated bool   // loops an animated image for the whole video
+
+	Text      string  // e.g a username, exclusive with Image
+	FontFile  string  // path of a .ttf/.otf font, uses fontconfig default font if empty
+	FontColor string  // defaults to white
+	TextSize  float64 // font size relative to the video width, defaults to 0.04
+
+	Anchor  WatermarkAnchor
+	Margin  float64 // distance to the anchored edges relative to the video width, defaults to 0.02
+	Scale   float64 // image width relative to the video width, defaults to 0.15
+	Opacity float64 // [0-1], defaults to 1
+
+	Start time.Duration // the watermark is shown from Start, relative to the output video
+	End   time.Duration // the watermark is hidden after End, 0 shows it until the end
+}
+
+// MergeOptions configures MergeFragments.
+type MergeOptions struct {
+	Profile         *EncodingProfile // encoding used if the fragments must be re-encoded, defaults to H.264/AAC
+	ForceReEncode   bool             // re-encodes the fragments even if they could be stream copied
+	DeleteFragments bool             // deletes the fragments once they are merged
+}
+
+// MergeTarget is the common format incompatible fragments are re-encoded to.
+type MergeTarget struct {
+	Width      int    // display width
+	Height     int    // display height
+	FPS        int    // highest frame rate of the fragments, at most 60
+	SAR        string // sample aspect ratio, always "1:1"
+	Rotation   int    // always 0, fragments are rotated while decoding
+	SampleRate int    // in Hz, 0 if no fragment has audio
+}
+
+// FragmentDiagnostic describes a fragment and why it can not be stream copied.
+type FragmentDiagnostic struct {
+	Path   string
+	Info   *MediaInfo
+	Issues []string // differences with the first fragment preventing a stream copy
+	Err    error    // set if the fragment can not be merged at all
+}
+
+// MergeReport describes how fragments were merged by MergeFragments.
+type MergeReport struct {
+	Fragments  []FragmentDiagnostic
+	StreamCopy bool         // true if the fragments were concatenated without re-encoding
+	Target     *MergeTarget // nil if the fragments were stream copied
+}
+
+// TranscodeQueueOptions configures a TranscodeQueue.
+type TranscodeQueueOptions struct {
+	MaxParallel int    // maximum number of concurrent ffmpeg processes, defaults to one per 4 CPUs as ffmpeg encoders are multi-threaded
+	StatePath   string // JSON file the jobs are persisted to, unfinished jobs are resumed when the queue is created again. Empty keeps the jobs in memory.
+}
+
+// TranscodeJob is a render job of a TranscodeQueue. Jobs are persisted as JSON.
+type TranscodeJob struct {
+	ID          string
+	Priority    int      // jobs with the highest priority run first, equal priorities run in submission order
+	Output      string   // path of the rendered video
+	CommandLine []string // ffmpeg command line rendering the video, computed when the job is added
+	Duration    time.Duration
+	Status      JobStatus
+	Percent     float64 // progress of a running job, [0-100]
+	Error       string  // set if the job failed
+	CreatedAt   time.Time
+	StartedAt   time.Time
+	FinishedAt  time.Time
+}
+
+// FingerprintOptions configures Video.Fingerprint.
+type FingerprintOptions struct {
+	Sampling      SamplingOptions // frames to hash, defaults to the keyframes resized to 128 pixels wide, at most 300
+	SkipAudio     bool            // skips the audio signature
+	AudioDuration time.Duration   // maximum duration of the audio signature from the beginning of the video, defaults to 2 minutes
+}
+
+// FrameHash contains the perceptual hashes of a video frame.
+type FrameHash struct {
+	Timestamp time.Duration
+	DHash     uint64 // difference hash, robust to re-encoding and resizing
+	PHash     uint64 // DCT hash, also robust to small color and contrast changes
+}
+
+// Fingerprint identifies the content of a video, it can be stored as JSON and compared with CompareFingerprints.
+type Fingerprint struct {
+	Duration time.Duration
+	Frames   []FrameHash
+	Audio    []uint32 // audio signature, a 32 bit sub-fingerprint every 512 samples at 11025 Hz. Empty if the video has no audio.
+}
+
+// Similarity describes how close two fingerprints are, 1 for identical content and around 0 for unrelated videos.
+type Similarity struct {
+	Score  float64 // combined score, the mean of Visual and Audio if both videos have audio, Visual otherwise
+	Visual float64
+	Audio  float64 // -1 if a video has no audio
+}
+
+// DisplayGeometry describes how players display a video stream, from the coded frames size, the rotation metadata
+// (Display Matrix side data or legacy rotate tag) and the sample aspect ratio.
+type DisplayGeometry struct {
+	CodedWidth  int     // width of the decoded frames
+	CodedHeight int     // height of the decoded frames
+	Rotation    int     // clockwise rotation applied by players, in [0-360)
+	SAR         float64 // sample (pixel) aspect ratio, 1 for square pixels
+	Width       int     // displayed width, the SAR stretched width is rounded to an even number
+	Height      int     // displayed height
 }
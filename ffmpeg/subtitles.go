@@ -0,0 +1,90 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BurnSubtitles draws the subtitles file at path (SRT, WebVTT or ASS) into the picture of the output video.
+// Subtitles timings are relative to the input video, the video must be trimmed before calling BurnSubtitles.
+func (v *EditableVideo) BurnSubtitles(path string, options SubtitleOptions) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("Video.BurnSubtitles: %w", err)
+	}
+	if v.start > 0 {
+		// The input is seeked before decoding, shift the subtitles back to the input timeline.
+		v.filters.Filter("setpts=PTS+"+formatSeconds(v.start)+"/TB").
+			Subtitles(path, options).
+			Filter("setpts=PTS-STARTPTS")
+		return nil
+	}
+	v.filters.Subtitles(path, options)
+	return nil
+}
+
+// AddSubtitleTrack muxes the subtitles file at track.Path as a soft subtitle track of the output video.
+// Subtitles are converted to mov_text for MP4/MOV outputs and to WebVTT for WebM outputs, other containers keep the file format.
+func (v *EditableVideo) AddSubtitleTrack(track SubtitleTrack) error {
+	if _, err := os.Stat(track.Path); err != nil {
+		return fmt.Errorf("Video.AddSubtitleTrack: %w", err)
+	}
+	v.subtitles = append(v.subtitles, track)
+	return nil
+}
+
+// GetSubtitleTracks returns the soft subtitle tracks muxed in the output video.
+func (v *EditableVideo) GetSubtitleTracks() []SubtitleTrack {
+	var tracks = make([]SubtitleTrack, len(v.subtitles))
+	copy(tracks, v.subtitles)
+	return tracks
+}
+
+// subtitleCodec returns the subtitle encoder supported by the container of output.
+func (v *EditableVideo) subtitleCodec(output string) string {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(output)), ".")
+	if v.profile != nil && v.profile.Format != "" {
+		format = v.profile.Format
+	}
+	switch format {
+	case "mp4", "m4v", "mov":
+		return "mov_text"
+	case "webm":
+		return "webvtt"
+	}
+	return "copy"
+}
+
+// subtitleArgs returns the inputs and the output options muxing the subtitle tracks in output.
+//
+// firstInputIndex: is the index ffmpeg will assign to the first subtitle input.
+func (v *EditableVideo) subtitleArgs(firstInputIndex int, output string) (inputArgs []string, outputArgs []string) {
+	if len(v.subtitles) == 0 {
+		return nil, nil
+	}
+	for i, track := range v.subtitles {
+		// Subtitles are seeked like the video to stay in sync.
+		if v.start > 0 {
+			inputArgs = append(inputArgs, "-ss", formatSeconds(v.start))
+		}
+		inputArgs = append(inputArgs, "-i", track.Path)
+		outputArgs = append(outputArgs, "-map", fmt.Sprintf("%d:s:0", firstInputIndex+i))
+	}
+
+	outputArgs = append(outputArgs, "-c:s", v.subtitleCodec(output))
+	for i, track := range v.subtitles {
+		if track.Language != "" {
+			outputArgs = append(outputArgs, fmt.Sprintf("-metadata:s:s:%d", i), "language="+track.Language)
+		}
+		if track.Title != "" {
+			outputArgs = append(outputArgs, fmt.Sprintf("-metadata:s:s:%d", i), "title="+track.Title)
+		}
+		disposition := "0"
+		if track.Default {
+			disposition = "default"
+		}
+		outputArgs = append(outputArgs, fmt.Sprintf("-disposition:s:%d", i), disposition)
+	}
+	return inputArgs, outputArgs
+}
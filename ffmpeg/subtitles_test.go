@@ -0,0 +1,87 @@
+package ffmpeg
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrawTextBoxAndTiming(t *testing.T) {
+	options := DrawTextOptions{
+		Text:           "Hello",
+		X:              "(w-text_w)/2",
+		Y:              "h-text_h-20",
+		Box:            true,
+		BoxBorderWidth: 8,
+		BorderWidth:    2,
+		Start:          1500 * time.Millisecond,
+		End:            4 * time.Second,
+	}
+	expected := `drawtext=text=Hello:fontsize=24:fontcolor=white:x=(w-text_w)/2:y=h-text_h-20:box=1:boxcolor=black@0.5:boxborderw=8:borderw=2:bordercolor=black:enable=between(t,1.5,4)`
+	if got := options.filter(); got != expected {
+		t.Errorf("got  %s\nwant %s", got, expected)
+	}
+
+	var graph FilterGraph
+	_, built, _ := graph.DrawText(DrawTextOptions{Text: "Hi", Start: 2 * time.Second}).Build("0:v", 1)
+	if !strings.HasSuffix(built, `:enable=gte(t\,2)[vout]`) {
+		t.Errorf("unexpected graph %s", built)
+	}
+}
+
+func TestSubtitlesFilter(t *testing.T) {
+	var graph FilterGraph
+	graph.Subtitles("C:/subs/en.srt", SubtitleOptions{ForceStyle: "FontName=Arial,FontSize=24"})
+	graph.Subtitles("styled.ASS", SubtitleOptions{ForceStyle: "ignored"})
+
+	_, built, _ := graph.Build("0:v", 1)
+	expected := `[0:v]subtitles=filename=C\\:/subs/en.srt:force_style=FontName=Arial\,FontSize=24,ass=filename=styled.ASS[vout]`
+	if built != expected {
+		t.Errorf("got  %s\nwant %s", built, expected)
+	}
+}
+
+func TestCommandLineWithSubtitleTracks(t *testing.T) {
+	dir := t.TempDir()
+	english, french := filepath.Join(dir, "en.srt"), filepath.Join(dir, "fr.vtt")
+	for _, path := range []string{english, french} {
+		if err := ioutil.WriteFile(path, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	video := (&Video{filepath: "in.mp4", width: 1280, height: 720, start: 2 * time.Second, end: 10 * time.Second, duration: 10 * time.Second}).GetEditableVideo()
+	if err := video.AddSubtitleTrack(SubtitleTrack{Path: english, Language: "eng", Title: "English", Default: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := video.AddSubtitleTrack(SubtitleTrack{Path: french, Language: "fra"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := video.AddSubtitleTrack(SubtitleTrack{Path: filepath.Join(dir, "missing.srt")}); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+
+	expected := []string{
+		"ffmpeg", "-y", "-ss", "2", "-i", "in.mp4",
+		"-ss", "2", "-i", english, "-ss", "2", "-i", french,
+		"-map", "0:v?", "-map", "0:a?",
+		"-map", "1:s:0", "-map", "2:s:0", "-c:s", "mov_text",
+		"-metadata:s:s:0", "language=eng", "-metadata:s:s:0", "title=English", "-disposition:s:0", "default",
+		"-metadata:s:s:1", "language=fra", "-disposition:s:1", "0",
+		"-vcodec", "libx264",
+		"out.mp4",
+	}
+	if got := video.commandLine("out.mp4"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got  %v\nwant %v", got, expected)
+	}
+
+	if codec := video.subtitleCodec("out.webm"); codec != "webvtt" {
+		t.Errorf("got %s for a WebM output", codec)
+	}
+	if codec := video.subtitleCodec("out.mkv"); codec != "copy" {
+		t.Errorf("got %s for a Matroska output", codec)
+	}
+}
@@ -1,6 +1,9 @@
 package ffmpeg
 
 import (
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -34,13 +37,180 @@ func (v *EditableVideo) commandLine(output string) []string {
 	cmdline := []string{
 		"ffmpeg",
 		"-y",
-		"-i", v.filepath,
-		"-vcodec", "libx264",
-		//	"-ss", strconv.FormatFloat(v.start.Seconds(), 'f', -1, 64),
-		//	"-t", strconv.FormatFloat((v.end - v.start).Seconds(), 'f', -1, 64),
-		//	"-vb", strconv.Itoa(v.bitrate),
 	}
+	cmdline = append(cmdline, v.inputSeekArgs()...)
+	cmdline = append(cmdline, "-i", v.filepath)
+
+	// Trimming on keyframes without any other operation does not require a re-encode.
+	if v.canStreamCopy() {
+		subtitleInputs, subtitleArgs := v.subtitleArgs(1, output)
+		cmdline = append(cmdline, subtitleInputs...)
+		if len(subtitleArgs) > 0 {
+			cmdline = append(cmdline, "-map", "0:v?", "-map", "0:a?")
+		}
+		cmdline = append(cmdline, v.durationArgs()...)
+		cmdline = append(cmdline, "-c", "copy")
+		cmdline = append(cmdline, subtitleArgs...)
+		cmdline = append(cmdline, additionalArgs...)
+		return append(cmdline, output)
+	}
+
+	// All video and audio operations are chained in a single filter graph.
+	inputArgs, filterArgs := v.filterArgs(1)
+	subtitleInputs, subtitleArgs := v.subtitleArgs(1+countInputs(inputArgs), output)
+	cmdline = append(cmdline, inputArgs...)
+	cmdline = append(cmdline, subtitleInputs...)
+	if len(filterArgs) == 0 && len(subtitleArgs) > 0 {
+		// Mapping the subtitles disables the default streams selection.
+		cmdline = append(cmdline, "-map", "0:v?", "-map", "0:a?")
+	}
+	cmdline = append(cmdline, filterArgs...)
+	cmdline = append(cmdline, subtitleArgs...)
+
+	cmdline = append(cmdline, v.encodingArgs()...)
+	cmdline = append(cmdline, v.outputArgs()...)
 	cmdline = append(cmdline, additionalArgs...)
 	cmdline = append(cmdline, output)
 	return cmdline
 }
+
+// encodingArgs returns the options of the encoding profile, H.264 with the encoder defaults if none is set.
+func (v *EditableVideo) encodingArgs() []string {
+	if v.profile == nil {
+		return []string{"-vcodec", "libx264"}
+	}
+	return v.profile.args()
+}
+
+// inputSeekArgs returns the options placed before the input to seek to the start of the output video.
+func (v *EditableVideo) inputSeekArgs() []string {
+	if v.start <= 0 {
+		return nil
+	}
+	var args []string
+	if v.seekMode == KeyframeSeek {
+		args = append(args, "-noaccurate_seek")
+	}
+	return append(args, "-ss", formatSeconds(v.start))
+}
+
+// durationArgs returns the options limiting the output to the trimmed duration.
+func (v *EditableVideo) durationArgs() []string {
+	if v.end <= v.start || v.end >= v.duration {
+		return nil
+	}
+	return []string{"-t", formatSeconds(v.end - v.start)}
+}
+
+// outputArgs returns the output options applying the trim, framerate and bitrate of the video.
+// Framerate and bitrate are only set if they differ from the input video.
+func (v *EditableVideo) outputArgs() []string {
+	args := v.durationArgs()
+	// The source framerate is compared exactly, SetFPS(30) is a change for a 29.97 fps (30000/1001) source.
+	if v.fpsSet && v.fps > 0 && math.Abs(float64(v.fps)-v.sourceFPS()) > 0.01 {
+		args = append(args, "-r", strconv.Itoa(v.fps))
+	}
+	if v.bitrate > 0 && v.bitrate != v.sourceBitrate() {
+		args = append(args, "-b:v", strconv.Itoa(v.bitrate))
+	}
+	return args
+}
+
+// sourceFPS returns the framerate of the input video as set by LoadVideo.
+func (v *EditableVideo) sourceFPS() float64 {
+	if v.info != nil {
+		if stream := v.info.PrimaryVideoStream(); stream != nil && stream.AvgFrameRate > 0 {
+			return stream.AvgFrameRate
+		}
+	}
+	return 30
+}
+
+// sourceBitrate returns the bitrate of the input video as set by LoadVideo.
+func (v *EditableVideo) sourceBitrate() int {
+	if v.info != nil {
+		return int(v.info.Format.BitRate)
+	}
+	return 0
+}
+
+// reEncodingArgs are the additional arguments that can not be applied on a stream copy.
+var reEncodingArgs = map[string]bool{
+	"-preset": true, "-crf": true, "-s": true, "-r": true, "-vf": true, "-af": true, "-filter_complex": true,
+	"-b:v": true, "-vb": true, "-vcodec": true, "-c:v": true, "-acodec": true, "-c:a": true, "-pix_fmt": true,
+}
+
+// canStreamCopy returns true if the output can be rendered without re-encoding the input.
+func (v *EditableVideo) canStreamCopy() bool {
+	if v.seekMode != KeyframeSeek || v.profile != nil || !v.filters.IsEmpty() || !v.audio.IsEmpty() {
+		return false
+	}
+	if len(v.outputArgs()) != len(v.durationArgs()) {
+		return false
+	}
+	for _, arg := range v.additionalArgs {
+		if reEncodingArgs[arg] {
+			return false
+		}
+	}
+	return true
+}
+
+// outputDuration returns the expected duration of the rendered video.
+func (v *EditableVideo) outputDuration() time.Duration {
+	if v.end > v.start {
+		return v.end - v.start
+	}
+	return v.duration
+}
+
+// filterArgs returns the extra inputs arguments and the -filter_complex / -map arguments applying the video and audio graphs.
+// Nothing is returned when there is no operation to apply.
+//
+// firstInputIndex: is the index ffmpeg will assign to the first extra input.
+func (v *EditableVideo) filterArgs(firstInputIndex int) (inputArgs []string, outputArgs []string) {
+	if v.filters.IsEmpty() && v.audio.IsEmpty() {
+		return nil, nil
+	}
+
+	var graphs []string
+	var videoMap, audioMap = "0:v?", "0:a?"
+
+	if !v.filters.IsEmpty() {
+		filters := v.displayFilters()
+		videoInputs, graph, outputPad := filters.Build("0:v", firstInputIndex)
+		inputArgs = append(inputArgs, videoInputs...)
+		graphs = append(graphs, graph)
+		videoMap = "[" + outputPad + "]"
+		firstInputIndex += countInputs(videoInputs)
+	}
+
+	if !v.audio.IsEmpty() {
+		audioInputs, graph, outputPad := v.audio.Build(v.sourceAudioPad(), firstInputIndex, v.outputDuration())
+		inputArgs = append(inputArgs, audioInputs...)
+		if graph != "" {
+			graphs = append(graphs, graph)
+			audioMap = "[" + outputPad + "]"
+		}
+	}
+
+	if len(graphs) == 0 {
+		return inputArgs, nil
+	}
+	return inputArgs, []string{
+		"-filter_complex", strings.Join(graphs, ";"),
+		"-map", videoMap,
+		"-map", audioMap,
+	}
+}
+
+// countInputs returns the number of inputs (-i) of a command line.
+func countInputs(args []string) int {
+	var count = 0
+	for _, arg := range args {
+		if arg == "-i" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,102 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+// withDefaults returns the options with the default value of every unset field.
+func (o WatermarkOptions) withDefaults() WatermarkOptions {
+	if o.FontColor == "" {
+		o.FontColor = "white"
+	}
+	if o.TextSize <= 0 {
+		o.TextSize = 0.04
+	}
+	if o.Margin <= 0 {
+		o.Margin = 0.02
+	}
+	if o.Scale <= 0 {
+		o.Scale = 0.15
+	}
+	if o.Opacity <= 0 || o.Opacity > 1 {
+		o.Opacity = 1
+	}
+	return o
+}
+
+// position returns the x and y expressions placing the watermark at its anchor.
+//
+// width, height: are the names of the watermark size variables of the filter, e.g "w" and "h" for overlay.
+// videoWidth, videoHeight: are the names of the video size variables of the filter.
+func (o WatermarkOptions) position(margin int, width, height, videoWidth, videoHeight string) (string, string) {
+	m := strconv.Itoa(margin)
+	switch o.Anchor {
+	case TopRight:
+		return videoWidth + "-" + width + "-" + m, m
+	case BottomLeft:
+		return m, videoHeight + "-" + height + "-" + m
+	case BottomRight:
+		return videoWidth + "-" + width + "-" + m, videoHeight + "-" + height + "-" + m
+	case Center:
+		return "(" + videoWidth + "-" + width + ")/2", "(" + videoHeight + "-" + height + ")/2"
+	}
+	return m, m
+}
+
+// AddWatermark overlays an image or a text watermark on the output video, see WatermarkOptions.
+// Sizes are computed from the current output width, the watermark must be added after resizing the video.
+func (v *EditableVideo) AddWatermark(options WatermarkOptions) error {
+	if (options.Image == "") == (options.Text == "") {
+		return errors.New("Video.AddWatermark: exactly one of Image and Text must be set")
+	}
+	if options.Image != "" {
+		if _, err := os.Stat(options.Image); err != nil {
+			return fmt.Errorf("Video.AddWatermark: %w", err)
+		}
+	}
+	options = options.withDefaults()
+	margin := int(math.Round(float64(v.width) * options.Margin))
+
+	if options.Text != "" {
+		x, y := options.position(margin, "text_w", "text_h", "w", "h")
+		text := DrawTextOptions{
+			Text:        options.Text,
+			FontFile:    options.FontFile,
+			FontSize:    int(math.Round(float64(v.width) * options.TextSize)),
+			FontColor:   options.FontColor + "@" + formatFloat(options.Opacity),
+			X:           x,
+			Y:           y,
+			BorderWidth: 1,
+			BorderColor: "black@" + formatFloat(options.Opacity/2),
+			Start:       options.Start,
+			End:         options.End,
+		}
+		v.filters.DrawText(text)
+		return nil
+	}
+
+	// The watermark keeps its aspect ratio, its width is relative to the video width.
+	width := toEvenNumber(int(math.Round(float64(v.width) * options.Scale)))
+	inputFilters := []string{fmt.Sprintf("scale=%d:-1", width), "format=rgba"}
+	if options.Opacity < 1 {
+		inputFilters = append(inputFilters, "colorchannelmixer=aa="+formatFloat(options.Opacity))
+	}
+
+	x, y := options.position(margin, "w", "h", "W", "H")
+	filter := fmt.Sprintf("overlay=x=%s:y=%s", x, y)
+	var inputArgs []string
+	if options.Animated {
+		// Loop the animation forever, the overlay ends with the video.
+		inputArgs = []string{"-stream_loop", "-1"}
+		filter += ":shortest=1"
+	}
+	if enable := timelineExpression(options.Start, options.End); enable != "" {
+		filter += ":enable=" + escapeFilterOption(enable)
+	}
+	v.filters.overlay(options.Image, filter, inputArgs, inputFilters)
+	return nil
+}
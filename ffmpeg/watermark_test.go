@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddImageWatermark(t *testing.T) {
+	logo := filepath.Join(t.TempDir(), "logo.gif")
+	if err := ioutil.WriteFile(logo, []byte("GIF89a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		width, height int
+		expected      string
+	}{
+		{1920, 1080, `[1:v]scale=384:-1,format=rgba,colorchannelmixer=aa=0.5[ov1];[0:v][ov1]overlay=x=W-w-38:y=H-h-38:shortest=1:enable=between(t\,1\,5)[vout]`},
+		{720, 1280, `[1:v]scale=144:-1,format=rgba,colorchannelmixer=aa=0.5[ov1];[0:v][ov1]overlay=x=W-w-14:y=H-h-14:shortest=1:enable=between(t\,1\,5)[vout]`},
+	}
+	for _, test := range tests {
+		video := (&Video{filepath: "in.mp4", width: test.width, height: test.height}).GetEditableVideo()
+		err := video.AddWatermark(WatermarkOptions{
+			Image:    logo,
+			Animated: true,
+			Anchor:   BottomRight,
+			Scale:    0.2,
+			Opacity:  0.5,
+			Start:    time.Second,
+			End:      5 * time.Second,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputArgs, graph, _ := video.filters.Build("0:v", 1)
+		if graph != test.expected {
+			t.Errorf("%dx%d:\ngot  %s\nwant %s", test.width, test.height, graph, test.expected)
+		}
+		if len(inputArgs) != 4 || inputArgs[0] != "-stream_loop" || inputArgs[3] != logo {
+			t.Errorf("unexpected watermark input %v", inputArgs)
+		}
+	}
+}
+
+func TestAddTextWatermark(t *testing.T) {
+	video := (&Video{filepath: "in.mp4", width: 1000, height: 1000}).GetEditableVideo()
+	if err := video.AddWatermark(WatermarkOptions{Text: "@username", Anchor: Center, Opacity: 0.8}); err != nil {
+		t.Fatal(err)
+	}
+	_, graph, _ := video.filters.Build("0:v", 1)
+	expected := `[0:v]drawtext=text=@username:fontsize=40:fontcolor=white@0.8:x=(w-text_w)/2:y=(h-text_h)/2:borderw=1:bordercolor=black@0.4[vout]`
+	if graph != expected {
+		t.Errorf("got  %s\nwant %s", graph, expected)
+	}
+
+	if err := video.AddWatermark(WatermarkOptions{}); err == nil {
+		t.Errorf("expected an error without image and text")
+	}
+	if err := video.AddWatermark(WatermarkOptions{Text: "a", Image: "b.png"}); err == nil {
+		t.Errorf("expected an error with both image and text")
+	}
+}
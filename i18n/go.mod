@@ -0,0 +1,3 @@
+module github.com/sabriboughanmi/go_utils/i18n
+
+go 1.16
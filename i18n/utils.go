@@ -35,8 +35,8 @@ func loadJsonFiles(directory string) error {
 		}
 	}
 
-	languages_json_script := "package languages " +
-		"\n var languagesJson string = `%s`"
+	languages_json_script := "package i18n\n" +
+		"\nvar languagesJson string = `%s`\n"
 
 	mapB, _ := json.Marshal(jsonFiles)
 
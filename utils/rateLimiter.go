@@ -7,6 +7,7 @@ import (
 type RateLimiter struct {
 	chanBlocker chan interface{}
 	ticker      *time.Ticker
+	done        chan struct{}
 	running     bool
 }
 
@@ -14,9 +15,16 @@ type RateLimiter struct {
 func (r *RateLimiter) Start() {
 	r.running = true
 	go func() {
-		for range r.ticker.C {
-			//r.chanBlocker <- nil
-			if r.running == false {
+		for {
+			select {
+			case <-r.ticker.C:
+			case <-r.done:
+				return
+			}
+			//allow one more operation, unless the limiter is stopped while waiting for it.
+			select {
+			case r.chanBlocker <- nil:
+			case <-r.done:
 				return
 			}
 		}
@@ -26,7 +34,8 @@ func (r *RateLimiter) Start() {
 //Stop must be called after all api calls has finished
 func (r *RateLimiter) Stop() {
 	r.running = false
-	close(r.chanBlocker)
+	r.ticker.Stop()
+	close(r.done)
 }
 
 //Wait must be called before any rate limited API Call
@@ -34,7 +43,10 @@ func (r *RateLimiter) Wait() {
 	if !r.running {
 		panic("RateLimiter need to be running in order to wait for it")
 	}
-	<-r.chanBlocker
+	select {
+	case <-r.chanBlocker:
+	case <-r.done:
+	}
 }
 
 //CreateLimiter returns a RateLimiter which can be used to rate limit any process operations.
@@ -51,12 +63,17 @@ func CreateLimiter(requestInterval time.Duration, burstCount int, requestsCount
 			return 0
 		}()),
 		ticker: ticker,
+		done:   make(chan struct{}),
 	}
 	//fill chanBlocker with free operations to prevent waiting for burst calls.
 	if burstCount > 0 {
 		go func() {
 			for i := 0; i < burstCount; i++ {
-				rateLimiter.chanBlocker <- nil
+				select {
+				case rateLimiter.chanBlocker <- nil:
+				case <-rateLimiter.done:
+					return
+				}
 			}
 		}()
 	}